@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Bucket width in virtual seconds when none is given
+const defaultActivityBucket = 60
+
+// Most buckets one response may hold, so a tiny width can't balloon it
+const maxActivityBuckets = 10000
+
+// Event counts for one window of the incident
+type activityBucket struct {
+	Start    int            `json:"start"` // first virtual second of the window
+	End      int            `json:"end"`   // first second after it
+	Total    int            `json:"total"`
+	Channels map[string]int `json:"channels"` // every channel, zero included, so charts can stack
+}
+
+// Activity endpoint response
+type activityResponse struct {
+	BucketSeconds int              `json:"bucket_seconds"`
+	Channels      []string         `json:"channels"` // in order of first appearance
+	Buckets       []activityBucket `json:"buckets"`
+}
+
+// Count events per fixed-width window of virtual time and per channel,
+// optionally on a single channel. Windows run from T+0 to the end of the
+// incident or its last event, whichever is later, quiet ones included.
+func buildActivity(t *IncidentTranscript, bucket int, channel string) (activityResponse, error) {
+	activity := activityResponse{BucketSeconds: bucket, Channels: []string{}}
+	seen := make(map[string]bool)
+	span := t.Incident.DurationSeconds
+	for _, event := range t.Events {
+		if channel != "" && event.Channel != channel {
+			continue
+		}
+		if !seen[event.Channel] {
+			seen[event.Channel] = true
+			activity.Channels = append(activity.Channels, event.Channel)
+		}
+		span = max(span, event.TimeOffset+1)
+	}
+
+	count := max((span+bucket-1)/bucket, 1)
+	if count > maxActivityBuckets {
+		return activity, fmt.Errorf("bucket of %ds gives %d buckets, more than the %d allowed", bucket, count, maxActivityBuckets)
+	}
+	activity.Buckets = make([]activityBucket, count)
+	for i := range activity.Buckets {
+		activity.Buckets[i] = activityBucket{Start: i * bucket, End: (i + 1) * bucket, Channels: make(map[string]int)}
+		for _, name := range activity.Channels {
+			activity.Buckets[i].Channels[name] = 0
+		}
+	}
+
+	for _, event := range t.Events {
+		if channel != "" && event.Channel != channel {
+			continue
+		}
+		b := &activity.Buckets[event.TimeOffset/bucket]
+		b.Total++
+		b.Channels[event.Channel]++
+	}
+	return activity, nil
+}
+
+// Handler for the transcript's event rate over time, e.g. for a heatmap of
+// the busiest phases
+func activityHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	bucket := defaultActivityBucket
+	if bucketStr := query.Get("bucket"); bucketStr != "" {
+		parsed, err := strconv.Atoi(bucketStr)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Invalid bucket value, expected a positive number of seconds", http.StatusBadRequest)
+			return
+		}
+		bucket = parsed
+	}
+
+	activity, err := buildActivity(primaryIncident().Transcript, bucket, query.Get("channel"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(activity)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestActivity(t *testing.T) {
+	// Events at 0, 5, 30, 45, 90 and 120 over a 120s incident
+	tr, err := loadFixture(t, "checkout_outage.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startServer(t, tr)
+
+	tests := []struct {
+		name     string
+		method   string
+		query    string
+		status   int
+		bucket   int
+		starts   []int
+		totals   []int
+		channels map[string][]int // per-channel counts, bucket by bucket
+	}{
+		{"default bucket", http.MethodGet, "", http.StatusOK, 60, []int{0, 60, 120}, []int{4, 1, 1},
+			map[string][]int{"team": {3, 0, 1}, "metrics": {1, 1, 0}}},
+		// The last event sits on the incident's end, so it opens a window of its own
+		{"30s buckets", http.MethodGet, "?bucket=30", http.StatusOK, 30, []int{0, 30, 60, 90, 120}, []int{2, 2, 0, 1, 1},
+			map[string][]int{"team": {1, 2, 0, 0, 1}, "metrics": {1, 0, 0, 1, 0}}},
+		// Only the filtered channel's events stretch the span past the incident's end
+		{"one channel", http.MethodGet, "?bucket=60&channel=metrics", http.StatusOK, 60, []int{0, 60}, []int{1, 1},
+			map[string][]int{"metrics": {1, 1}}},
+		{"bucket wider than the incident", http.MethodGet, "?bucket=600", http.StatusOK, 600, []int{0}, []int{6},
+			map[string][]int{"team": {4}, "metrics": {2}}},
+		{"zero bucket", http.MethodGet, "?bucket=0", http.StatusBadRequest, 0, nil, nil, nil},
+		{"non-numeric bucket", http.MethodGet, "?bucket=abc", http.StatusBadRequest, 0, nil, nil, nil},
+		{"wrong method", http.MethodPost, "", http.StatusMethodNotAllowed, 0, nil, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := control(t, tt.method, srv.URL+"/activity"+tt.query, "")
+			if status != tt.status {
+				t.Fatalf("%s /activity%s: status %d, want %d: %s", tt.method, tt.query, status, tt.status, body)
+			}
+			if status != http.StatusOK {
+				return
+			}
+
+			var got activityResponse
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatalf("decode %q: %v", body, err)
+			}
+			if got.BucketSeconds != tt.bucket {
+				t.Errorf("bucket_seconds %d, want %d", got.BucketSeconds, tt.bucket)
+			}
+			starts, totals := []int{}, []int{}
+			channels := make(map[string][]int)
+			for _, b := range got.Buckets {
+				if b.End != b.Start+tt.bucket {
+					t.Errorf("window %d-%d, want %ds wide", b.Start, b.End, tt.bucket)
+				}
+				starts = append(starts, b.Start)
+				totals = append(totals, b.Total)
+				// Every listed channel has a count in every window, zeros included
+				for _, name := range got.Channels {
+					count, ok := b.Channels[name]
+					if !ok {
+						t.Errorf("window at %d has no count for %q", b.Start, name)
+					}
+					channels[name] = append(channels[name], count)
+				}
+			}
+			if !reflect.DeepEqual(starts, tt.starts) || !reflect.DeepEqual(totals, tt.totals) {
+				t.Errorf("windows at %v with totals %v, want %v with %v", starts, totals, tt.starts, tt.totals)
+			}
+			if !reflect.DeepEqual(channels, tt.channels) {
+				t.Errorf("channel counts %v, want %v", channels, tt.channels)
+			}
+		})
+	}
+}
+
+func TestActivityBucketLimit(t *testing.T) {
+	tr := &IncidentTranscript{Incident: IncidentInfo{DurationSeconds: maxActivityBuckets + 1}}
+	if _, err := buildActivity(tr, 1, ""); err == nil {
+		t.Errorf("%d one-second buckets accepted, want an error", maxActivityBuckets+1)
+	}
+	if _, err := buildActivity(tr, 2, ""); err != nil {
+		t.Errorf("two-second buckets: %v", err)
+	}
+}
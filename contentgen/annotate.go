@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A facilitator's bookmark on the timeline, such as "decision point" or
+// "root cause identified", kept for the after-action export
+type annotation struct {
+	Time   time.Time
+	Offset int // virtual incident offset in seconds when it was dropped
+	Label  string
+	Note   string
+}
+
+// Record an annotation at the current incident offset and show it on every
+// stream attached to the replay, whatever its channel
+func (rp *replay) annotate(label, note string) annotation {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	now := wallClock.Now()
+	a := annotation{Time: now, Offset: int(math.Floor(rp.offsetLocked(now))), Label: label, Note: note}
+	rp.annotations = append(rp.annotations, a)
+	for sub := range rp.subscribers {
+		rp.sendLocked(sub, replayMessage{Kind: messageAnnotated, Mark: a})
+	}
+	slog.Info("📌 Annotated replay", "label", label, "offset", a.Offset, "streams", len(rp.subscribers))
+	return a
+}
+
+// Copy of the annotations dropped on this run, oldest first
+func (rp *replay) annotationLog() []annotation {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return slices.Clone(rp.annotations)
+}
+
+// Body accepted by the annotate endpoint
+type annotateRequest struct {
+	Label string `json:"label"`
+	Note  string `json:"note,omitempty"`
+}
+
+// Handler for facilitators marking a moment in the live replay
+func annotateHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req annotateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid annotation body")
+		return
+	}
+	label := strings.TrimSpace(req.Label)
+	if label == "" {
+		writeJSONError(w, http.StatusBadRequest, "Annotation needs a label")
+		return
+	}
+	if strings.ContainsAny(label, "\r\n") {
+		writeJSONError(w, http.StatusBadRequest, "Annotation label must be a single line")
+		return
+	}
+
+	a := primaryIncident().Replay.annotate(label, strings.TrimSpace(req.Note))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok", "message": fmt.Sprintf("Annotated T+%ds: %s", a.Offset, a.Label)})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestAnnotateMidStream(t *testing.T) {
+	tests := []struct {
+		name  string
+		after string // last event played before annotating
+		want  []string
+	}{
+		{"before the first metric", "Paging on-call", []string{
+			"Paging on-call", "📌 Decision point", "CPU 92%", "CPU 99%", "Rolling back", "Resolved",
+		}},
+		{"mid-replay", "CPU 99%", []string{
+			"Paging on-call", "CPU 92%", "CPU 99%", "📌 Decision point", "Rolling back", "Resolved",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			useFakeSlack(t)
+			srv := startServer(t, fixtureTranscript())
+			all := openSSE(t, srv.URL+"/stream?channels=team,metrics")
+			team := openSSE(t, srv.URL+"/stream?channels=team")
+			metrics := openSSE(t, srv.URL+"/stream?channels=metrics")
+
+			played := []string{"Paging on-call", "CPU 92%", "CPU 99%", "Rolling back", "Resolved"}
+			step := 0
+			all.until(t, played[0])
+			for played[step] != tt.after {
+				step++
+				clock.step(t)
+				all.until(t, played[step])
+			}
+
+			status, body := control(t, http.MethodPost, srv.URL+"/annotate", `{"label":" Decision point ","note":"roll back the deploy"}`)
+			if status != http.StatusOK {
+				t.Fatalf("POST /annotate: status %d: %s", status, body)
+			}
+
+			// Every stream shows the marker, whatever its channel
+			for _, stream := range []*sseStream{all, team, metrics} {
+				stream.until(t, "📌 Decision point")
+			}
+
+			for step++; step < len(played); step++ {
+				clock.step(t)
+				all.until(t, played[step])
+			}
+			eventOutbox.wait()
+
+			// The annotation sits in the export between the events around it
+			status, body = control(t, http.MethodGet, srv.URL+"/export?format=json", "")
+			if status != http.StatusOK {
+				t.Fatalf("GET /export: status %d: %s", status, body)
+			}
+			var entries []emittedEvent
+			if err := json.Unmarshal([]byte(body), &entries); err != nil {
+				t.Fatalf("decode export: %v", err)
+			}
+			var got []string
+			for _, entry := range entries {
+				if entry.Kind == "annotation" {
+					got = append(got, "📌 "+entry.Message)
+					if entry.Note != "roll back the deploy" {
+						t.Errorf("annotation note %q, want %q", entry.Note, "roll back the deploy")
+					}
+					continue
+				}
+				got = append(got, entry.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("export %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
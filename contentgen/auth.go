@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Check the request carries the expected bearer token
+func hasBearerToken(r *http.Request, token string) bool {
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+// Check the request carries the viewer token: as a bearer token, as the
+// password of basic auth under any username, or in the token query
+// parameter for EventSource, which can't set headers. The admin token is
+// accepted too, so facilitators can watch with the one token.
+func hasViewerToken(r *http.Request) bool {
+	if hasBearerToken(r, viewerToken) || (adminToken != "" && hasBearerToken(r, adminToken)) {
+		return true
+	}
+	if _, password, ok := r.BasicAuth(); ok && subtle.ConstantTimeCompare([]byte(password), []byte(viewerToken)) == 1 {
+		return true
+	}
+	provided := r.URL.Query().Get("token")
+	return provided != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(viewerToken)) == 1
+}
+
+// Require the viewer token on stream endpoints when -viewer-token is set,
+// answering 401 before any stream headers are written. Without a token
+// streams are open to anyone as before.
+func requireViewer(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if viewerToken != "" && !hasViewerToken(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="viewer"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Require the admin token for mutating requests when ADMIN_TOKEN is set.
+// Read-only GET requests stay open, and without a token everything is open
+// as before.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminToken != "" && r.Method != http.MethodGet && !hasBearerToken(r, adminToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
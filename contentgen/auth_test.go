@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Send a request with an optional bearer token and return the status
+func requestWithToken(t *testing.T, method, url, token string) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestAdminToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string // ADMIN_TOKEN, empty when unset
+		method     string
+		path       string
+		token      string
+		status     int
+	}{
+		{"unset: control open", "", http.MethodPost, "/pause", "", http.StatusOK},
+		{"unset: any token ignored", "", http.MethodPost, "/pause", "wrong", http.StatusOK},
+		{"set: missing token", "s3cret", http.MethodPost, "/pause", "", http.StatusUnauthorized},
+		{"set: wrong token", "s3cret", http.MethodPost, "/seek?offset=5", "wrong", http.StatusUnauthorized},
+		{"set: right token", "s3cret", http.MethodPost, "/pause", "s3cret", http.StatusOK},
+		{"set: speed read stays open", "s3cret", http.MethodGet, "/speed", "", http.StatusOK},
+		{"set: status stays open", "s3cret", http.MethodGet, "/status", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := adminToken
+			adminToken = tt.configured
+			t.Cleanup(func() { adminToken = previous })
+			srv := startServer(t, fixtureTranscript())
+
+			if status := requestWithToken(t, tt.method, srv.URL+tt.path, tt.token); status != tt.status {
+				t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, status, tt.status)
+			}
+		})
+	}
+}
+
+func TestViewerToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string // -viewer-token, empty when unset
+		admin      string // ADMIN_TOKEN, empty when unset
+		path       string
+		auth       string // how the token is sent: bearer, basic or query
+		token      string
+		status     int
+	}{
+		{"unset: streams open", "", "", "/stream/team", "", "", http.StatusOK},
+		{"set: missing token", "v1ew", "", "/stream/team", "", "", http.StatusUnauthorized},
+		{"set: bearer", "v1ew", "", "/stream/team", "bearer", "v1ew", http.StatusOK},
+		{"set: wrong bearer", "v1ew", "", "/stream/team", "bearer", "wrong", http.StatusUnauthorized},
+		{"set: basic auth password", "v1ew", "", "/stream/team", "basic", "v1ew", http.StatusOK},
+		{"set: wrong basic auth password", "v1ew", "", "/stream/team", "basic", "wrong", http.StatusUnauthorized},
+		{"set: query parameter", "v1ew", "", "/stream/team", "query", "v1ew", http.StatusOK},
+		{"set: wrong query parameter", "v1ew", "", "/stream/team", "query", "wrong", http.StatusUnauthorized},
+		{"set: admin token accepted", "v1ew", "s3cret", "/stream/team", "bearer", "s3cret", http.StatusOK},
+		{"set: combined stream", "v1ew", "", "/stream?channels=team,metrics", "", "", http.StatusUnauthorized},
+		{"set: incident stream", "v1ew", "", "/incidents/default/stream/team", "", "", http.StatusUnauthorized},
+		{"set: WebSocket upgrade", "v1ew", "", "/ws/team", "", "", http.StatusUnauthorized},
+		{"set: status stays open", "v1ew", "", "/status", "", "", http.StatusOK},
+		{"set: page stays open", "v1ew", "", "/", "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousViewer, previousAdmin := viewerToken, adminToken
+			viewerToken, adminToken = tt.configured, tt.admin
+			t.Cleanup(func() { viewerToken, adminToken = previousViewer, previousAdmin })
+			srv := startServer(t, fixtureTranscript())
+
+			// Streams let in end once the replay completes
+			url := srv.URL + tt.path
+			switch {
+			case strings.Contains(tt.path, "?"):
+				url += "&oncomplete=close"
+			case strings.HasPrefix(tt.path, "/stream") || strings.HasPrefix(tt.path, "/incidents/"):
+				url += "?oncomplete=close"
+			}
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch tt.auth {
+			case "bearer":
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			case "basic":
+				req.SetBasicAuth("facilitator", tt.token)
+			case "query":
+				q := req.URL.Query()
+				q.Set("token", tt.token)
+				req.URL.RawQuery = q.Encode()
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET %s: %v", tt.path, err)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("GET %s: status %d, want %d", tt.path, resp.StatusCode, tt.status)
+			}
+			if resp.StatusCode == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Lossy, laggy feed simulation for training drills; nil unless -chaos is set
+var chaos *chaosConfig
+
+// How chaos mode degrades the replay
+type chaosConfig struct {
+	DropRate float64       // probability that an event is dropped
+	Jitter   time.Duration // maximum extra delay before an event fires
+	Seed     int64         // seeds every replay's dice, so a scenario can be replayed identically
+}
+
+// Check the chaos parameters are usable
+func (c *chaosConfig) validate() error {
+	if c.DropRate < 0 || c.DropRate > 1 {
+		return fmt.Errorf("drop rate %g must be between 0 and 1", c.DropRate)
+	}
+	if c.Jitter < 0 {
+		return fmt.Errorf("jitter %s must not be negative", c.Jitter)
+	}
+	return nil
+}
+
+// Human-readable summary for stream banners
+func (c *chaosConfig) describe() string {
+	return fmt.Sprintf("🎲 Chaos mode: dropping %.0f%% of events, up to %s extra delay (seed %d)", c.DropRate*100, c.Jitter, c.Seed)
+}
+
+// Dice for one replay. Every replay rolls its own sequence from the same
+// seed, so the same timeline always loses and delays the same events.
+type chaosDice struct {
+	config *chaosConfig
+	rng    *rand.Rand
+}
+
+// Create dice for a replay, or nil when chaos mode is off
+func newChaosDice(config *chaosConfig) *chaosDice {
+	if config == nil {
+		return nil
+	}
+	return &chaosDice{config: config, rng: rand.New(rand.NewSource(config.Seed))}
+}
+
+// Decide the fate of the next event: dropped, or fired after an extra delay.
+// Both values are always drawn so one decision never shifts the next.
+func (d *chaosDice) roll() (drop bool, delay time.Duration) {
+	if d == nil {
+		return false, 0
+	}
+	drop = d.rng.Float64() < d.config.DropRate
+	delay = time.Duration(d.rng.Float64() * float64(d.config.Jitter))
+	return drop, delay
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestChaosFixedSeed(t *testing.T) {
+	previous := chaos
+	chaos = &chaosConfig{DropRate: 0.4, Jitter: 2 * time.Second, Seed: 7}
+	t.Cleanup(func() { chaos = previous })
+
+	var events []Event
+	for i := range 6 {
+		events = append(events, Event{TimeOffset: i * 10, Channel: "team", Message: fmt.Sprintf("event %d", i)})
+	}
+
+	// Seed 7 drops events 1, 3 and 4, and holds the rest back by its jitter
+	want := []timedEvent{
+		{"event 0", 463014348 * time.Nanosecond},
+		{"event 2", 20*time.Second + 292311896*time.Nanosecond},
+		{"event 5", 50*time.Second + 777918175*time.Nanosecond},
+	}
+
+	// Every run of the same timeline rolls the same dice
+	for run := range 2 {
+		clock := useFakeClock(t)
+		rp := newReplay(events, newSpeedControl(1), false)
+		t.Cleanup(rp.wait)
+		sub, _ := rp.subscribeWithBacklog([]string{"team"})
+		got := driveTimed(t, clock, sub)
+
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("run %d: events %v, want %v", run, got, want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Package client consumes the replay server's SSE streams from Go, so tests
+// and tooling can script a replay without hand-rolling an EventSource parser.
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Stream path of each channel served on the top-level routes
+var streamPaths = map[string]string{
+	"metrics": "/stream/incidents",
+	"team":    "/stream/team",
+	"zoom":    "/stream/zoom",
+}
+
+// One replayed event. Streams in the plain text format carry only Time and
+// Message; the JSON format fills in the rest.
+type Event struct {
+	Time    string            `json:"time"`
+	Offset  int               `json:"offset"`
+	Channel string            `json:"channel"`
+	Message string            `json:"message"`
+	Type    string            `json:"type,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+	Level   string            `json:"level"`
+}
+
+// Connect to one channel's stream and deliver its events until the replay
+// completes, the server goes away or ctx ends; the returned channel is then
+// closed. Banners and markers are consumed, not delivered.
+func Subscribe(ctx context.Context, baseURL, channel string) (<-chan Event, error) {
+	path, ok := streamPaths[channel]
+	if !ok {
+		return nil, fmt.Errorf("unknown channel %q", channel)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+path+"?format=json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stream request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("stream returned status %d", resp.StatusCode)
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		readStream(ctx, resp.Body, events)
+	}()
+	return events, nil
+}
+
+// Parse SSE events into events until completion or the end of the body. A
+// multi-line message arrives as several data lines, joined back with newlines
+// at the blank line ending the SSE event. Typed SSE events such as lifecycle
+// signals aren't replayed events, so they're skipped.
+func readStream(ctx context.Context, body io.Reader, events chan<- Event) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	named := false // the SSE event has a type, e.g. a lifecycle signal
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			if data, ok := strings.CutPrefix(line, "data:"); ok {
+				lines = append(lines, strings.TrimPrefix(data, " "))
+			} else if strings.HasPrefix(line, "event:") {
+				named = true
+			}
+			continue
+		}
+		skip := lines == nil || named
+		data := strings.Join(lines, "\n")
+		lines, named = nil, false
+		if skip {
+			continue
+		}
+
+		event, complete, ok := parseData(data)
+		if complete {
+			return
+		}
+		if !ok {
+			continue
+		}
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Interpret one SSE event's data in either stream format, reporting whether it
+// holds an event and whether it marks the replay complete
+func parseData(data string) (event Event, complete, ok bool) {
+	// JSON frames: markers carry an "event" name, events a message
+	if strings.HasPrefix(data, "{") {
+		var frame struct {
+			Event
+			Marker string `json:"event"`
+		}
+		if err := json.Unmarshal([]byte(data), &frame); err != nil {
+			return Event{}, false, false
+		}
+		if frame.Marker != "" {
+			return Event{}, frame.Marker == "complete", false
+		}
+		return frame.Event, false, true
+	}
+
+	// Plain text events are "[time] message"; anything else is a banner
+	if strings.HasPrefix(data, "✅ Incident replay completed") {
+		return Event{}, true, false
+	}
+	rest, found := strings.CutPrefix(data, "[")
+	if !found {
+		return Event{}, false, false
+	}
+	at, message, found := strings.Cut(rest, "] ")
+	if !found {
+		return Event{}, false, false
+	}
+	return Event{Time: at, Message: message}, false, true
+}
+
+// Set the default playback speed through the /speed endpoint. Pass the
+// server's admin token, or "" when it has none.
+func SetSpeed(ctx context.Context, baseURL, adminToken string, speed float64) error {
+	query := url.Values{"speed": {strconv.FormatFloat(speed, 'f', -1, 64)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/speed?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create speed request: %w", err)
+	}
+	if adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to set speed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to set speed: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
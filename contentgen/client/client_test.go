@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseData(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		event    Event
+		complete bool
+		ok       bool
+	}{
+		{"text event", "[09:00:05] Paging on-call", Event{Time: "09:00:05", Message: "Paging on-call"}, false, true},
+		{"text banner", "🔗 Connected to Team Communication", Event{}, false, false},
+		{"text completion", "✅ Incident replay completed", Event{}, true, false},
+		{"json event", `{"time":"09:00:05","offset":5,"channel":"team","message":"Paging on-call","level":"info"}`,
+			Event{Time: "09:00:05", Offset: 5, Channel: "team", Message: "Paging on-call", Level: "info"}, false, true},
+		{"json marker", `{"event":"connected","channel":"team"}`, Event{}, false, false},
+		{"json completion", `{"event":"complete","channel":"team"}`, Event{}, true, false},
+		{"malformed json", `{"time":`, Event{}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, complete, ok := parseData(tt.data)
+			if !reflect.DeepEqual(event, tt.event) || complete != tt.complete || ok != tt.ok {
+				t.Errorf("parseData(%q) = %+v, %v, %v, want %+v, %v, %v", tt.data, event, complete, ok, tt.event, tt.complete, tt.ok)
+			}
+		})
+	}
+}
+
+func TestReadStream(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"stops at completion", "data: [09:00:00] one\n\ndata: ✅ Incident replay completed\n\ndata: [09:00:09] after\n\n", []string{"one"}},
+		{"multi-line message", "data: [09:00:00] line one\ndata: line two\n\n", []string{"line one\nline two"}},
+		{"ends with the body", "event: system\ndata: {\"type\":\"start\"}\n\ndata: [09:00:00] one\n\n", []string{"one"}},
+		{"ignores comments and blank runs", ": ping\n\n\n\ndata: [09:00:00] one\n\n", []string{"one"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := make(chan Event, 10)
+			readStream(context.Background(), strings.NewReader(tt.body), events)
+			close(events)
+			var got []string
+			for event := range events {
+				got = append(got, event.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("messages %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "transcript not loaded", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		channel string
+		err     string
+	}{
+		{"zoom", "status 503"},
+		{"pager", `unknown channel "pager"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.channel, func(t *testing.T) {
+			if _, err := Subscribe(context.Background(), srv.URL, tt.channel); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Subscribe error %v, want it to mention %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSetSpeed(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{"with the admin token", "s3cret", ""},
+		{"without it", "", "status 401: Unauthorized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SetSpeed(context.Background(), srv.URL+"/", tt.token, 2.5)
+			if tt.err == "" && err != nil {
+				t.Fatalf("SetSpeed: %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("SetSpeed error %v, want it to mention %q", err, tt.err)
+			}
+			if got.Method != http.MethodPost || got.URL.Path != "/speed" || got.URL.Query().Get("speed") != "2.5" {
+				t.Errorf("request %s %s, want POST /speed?speed=2.5", got.Method, got.URL)
+			}
+		})
+	}
+}
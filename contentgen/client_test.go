@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"contentgen/client"
+)
+
+// The client library against the real handlers
+func TestClientSubscribe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		channel string
+		want    []client.Event
+	}{
+		{"team", []client.Event{
+			{Offset: 0, Channel: "team", Message: "Paging on-call", Level: "info"},
+			{Offset: 10, Channel: "team", Message: "Rolling back", Level: "info"},
+			{Offset: 20, Channel: "team", Message: "Resolved", Level: "info"},
+		}},
+		{"metrics", []client.Event{
+			{Offset: 1, Channel: "metrics", Message: "CPU 92%", Level: "info"},
+			{Offset: 3, Channel: "metrics", Message: "CPU 99%", Level: "info"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.channel, func(t *testing.T) {
+			srv := startServer(t, fixtureTranscript())
+			events, err := client.Subscribe(ctx, srv.URL, tt.channel)
+			if err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+			// The channel closes at completion, though the stream stays open
+			var got []client.Event
+			for event := range events {
+				if event.Time == "" {
+					t.Errorf("event %q has no time", event.Message)
+				}
+				event.Time = ""
+				got = append(got, event)
+			}
+			if ctx.Err() != nil {
+				t.Fatal("stream never completed")
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("events %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i].Offset != tt.want[i].Offset || got[i].Channel != tt.want[i].Channel || got[i].Message != tt.want[i].Message || got[i].Level != tt.want[i].Level {
+					t.Errorf("event %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestClientSetSpeed(t *testing.T) {
+	srv := startServer(t, fixtureTranscript())
+	if err := client.SetSpeed(context.Background(), srv.URL, "", 5); err != nil {
+		t.Fatalf("SetSpeed: %v", err)
+	}
+	if got := getPlaybackSpeed(""); got != 5 {
+		t.Errorf("speed %v after SetSpeed, want 5", got)
+	}
+}
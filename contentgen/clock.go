@@ -0,0 +1,48 @@
+package main
+
+import "time"
+
+// Source of wall time for the replay and the stream handlers, so timing can
+// be driven by something other than the real clock
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Single pending wake-up from a Clock that can be called off early
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// The real wall clock
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+// Timer backed by the runtime's own
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+// Clock every replay and stream reads; swapped out to control time
+var wallClock Clock = realClock{}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Clock a test moves by hand: time stands still and timers fire only when
+// Advance carries the clock past them
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	set    chan struct{} // signalled whenever a goroutine starts waiting on the clock
+}
+
+// Timer on a fakeClock, due at a fixed fake time
+type fakeTimer struct {
+	clock *fakeClock
+	at    time.Time
+	ch    chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 3, 14, 9, 0, 0, 0, time.UTC), set: make(chan struct{}, 1)}
+}
+
+// Swap the package clock for a fake one for the rest of the test
+func useFakeClock(t *testing.T) *fakeClock {
+	t.Helper()
+	clock := newFakeClock()
+	previous := wallClock
+	wallClock = clock
+	t.Cleanup(func() { wallClock = previous })
+	return clock
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, at: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.ch <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	select {
+	case c.set <- struct{}{}:
+	default:
+	}
+	return t
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Move the clock forward, firing every timer that comes due on the way
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advanceLocked(c.now.Add(d))
+}
+
+// Move the clock to the earliest pending timer and fire it, reporting false
+// when nothing is waiting on the clock
+func (c *fakeClock) AdvanceToNext() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.timers) == 0 {
+		return false
+	}
+	next := c.timers[0].at
+	for _, t := range c.timers[1:] {
+		if t.at.Before(next) {
+			next = t.at
+		}
+	}
+	c.advanceLocked(next)
+	return true
+}
+
+func (c *fakeClock) advanceLocked(to time.Time) {
+	if to.After(c.now) {
+		c.now = to
+	}
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].at.Before(c.timers[j].at) })
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.timers = pending
+}
+
+// Wait for the replay to start waiting on the clock, then move the clock to
+// its timer, failing the test if nothing waits within a few seconds
+func (c *fakeClock) step(t *testing.T) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for !c.AdvanceToNext() {
+		select {
+		case <-c.set:
+		case <-deadline:
+			t.Fatal("nothing is waiting on the fake clock")
+		}
+	}
+}
+
+// Like step, but first wait until n timers are pending, so several
+// timelines due at the same moment move together
+func (c *fakeClock) stepTogether(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for c.Pending() < n {
+		select {
+		case <-c.set:
+		case <-deadline:
+			t.Fatalf("%d timers waiting on the fake clock, want %d", c.Pending(), n)
+		}
+	}
+	c.AdvanceToNext()
+}
+
+// Number of timers still waiting to fire
+func (c *fakeClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// Receive a subscriber's messages until its stream completes, moving the
+// clock on to the next timer whenever the replay goes back to waiting
+func drive(t *testing.T, clock *fakeClock, sub *subscriber) []replayMessage {
+	t.Helper()
+	var received []replayMessage
+	for {
+		select {
+		case msg, ok := <-sub.ch:
+			if !ok {
+				t.Fatalf("subscriber dropped after %d messages", len(received))
+			}
+			received = append(received, msg)
+			if msg.Kind == messageComplete {
+				return received
+			}
+		case <-clock.set:
+			clock.AdvanceToNext()
+		}
+	}
+}
+
+// Event as a subscriber received it, with how far the fake clock had moved
+type timedEvent struct {
+	Message string
+	At      time.Duration
+}
+
+// Like drive, but note the fake time each event arrived at. Messages already
+// sent are taken before the clock moves, so each is stamped with the time it
+// fired at.
+func driveTimed(t *testing.T, clock *fakeClock, sub *subscriber) []timedEvent {
+	t.Helper()
+	start := clock.Now()
+	var received []timedEvent
+	take := func(msg replayMessage, ok bool) bool {
+		if !ok {
+			t.Fatalf("subscriber dropped after %d events", len(received))
+		}
+		if msg.Kind == messageEvent {
+			received = append(received, timedEvent{msg.Event.Message, clock.Now().Sub(start)})
+		}
+		return msg.Kind == messageComplete
+	}
+	for {
+		select {
+		case msg, ok := <-sub.ch:
+			if take(msg, ok) {
+				return received
+			}
+		case <-clock.set:
+			for drained := false; !drained; {
+				select {
+				case msg, ok := <-sub.ch:
+					if take(msg, ok) {
+						return received
+					}
+				default:
+					drained = true
+				}
+			}
+			clock.AdvanceToNext()
+		}
+	}
+}
+
+func TestFakeClockTimers(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+
+	late := clock.NewTimer(3 * time.Second)
+	early := clock.NewTimer(time.Second)
+	stopped := clock.NewTimer(2 * time.Second)
+	if !stopped.Stop() {
+		t.Fatal("Stop on a pending timer reported false")
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-early.C():
+		t.Fatal("timer fired before its deadline")
+	default:
+	}
+
+	if !clock.AdvanceToNext() {
+		t.Fatal("AdvanceToNext found no pending timer")
+	}
+	if got := <-early.C(); !got.Equal(start.Add(time.Second)) {
+		t.Errorf("early timer fired at %v, want %v", got.Sub(start), time.Second)
+	}
+
+	clock.Advance(5 * time.Second)
+	if got := <-late.C(); !got.Equal(start.Add(6 * time.Second)) {
+		t.Errorf("late timer fired at %v, want the time Advance reached", got.Sub(start))
+	}
+	select {
+	case <-stopped.C():
+		t.Error("stopped timer fired")
+	default:
+	}
+	if clock.Pending() != 0 || clock.AdvanceToNext() {
+		t.Error("timers still pending after all fired")
+	}
+}
+
+func TestReplayOnFakeClock(t *testing.T) {
+	events := []Event{
+		{TimeOffset: 0, Channel: "team", Message: "Paging on-call"},
+		{TimeOffset: 1, Channel: "metrics", Message: "CPU 92%"},
+		{TimeOffset: 3, Channel: "metrics", Message: "CPU 99%"},
+		{TimeOffset: 10, Channel: "team", Message: "Rolling back"},
+		{TimeOffset: 20, Channel: "team", Message: "Resolved"},
+	}
+
+	tests := []struct {
+		name  string
+		speed float64
+	}{
+		{"real time", 1},
+		{"double speed", 2},
+		{"fast forward", 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			start := clock.Now()
+
+			rp := newReplay(events, newSpeedControl(tt.speed), false)
+			t.Cleanup(rp.wait)
+			sub, _ := rp.subscribeWithBacklog([]string{"team", "metrics"})
+			received := drive(t, clock, sub)
+
+			var got []string
+			for _, msg := range received {
+				if msg.Kind == messageEvent {
+					got = append(got, msg.Event.Message)
+				}
+			}
+			want := []string{"Paging on-call", "CPU 92%", "CPU 99%", "Rolling back", "Resolved"}
+			if len(got) != len(want) {
+				t.Fatalf("received %q, want %q", got, want)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("event %d = %q, want %q", i, got[i], want[i])
+				}
+			}
+
+			// Each event fired exactly when its offset came due at this speed
+			for _, entry := range rp.emittedLog() {
+				due := time.Duration(float64(entry.Offset) / tt.speed * float64(time.Second))
+				if fired := entry.Time.Sub(start); fired != due {
+					t.Errorf("%q fired at %v, want %v", entry.Message, fired, due)
+				}
+			}
+		})
+	}
+}
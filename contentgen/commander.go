@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Labels AI commander responses so they never trigger responses of their own
+const commanderPrefix = "🤖 Commander:"
+
+// Virtual seconds between a team message and the commander's response
+const commanderDelaySeconds = 2
+
+// Time allowed for the model to produce a response
+const commanderTimeout = 20 * time.Second
+
+// Instructions for the commander model
+const commanderPrompt = `You are the incident commander for a live production incident.
+Reply to the latest team message in one or two short sentences, as you would in the incident Slack channel.
+Be decisive: assign owners, ask for specific data, or call the next step. Do not use a name prefix.`
+
+// AI commander configuration
+var (
+	aiCommander         bool          // respond to team messages with LLM-generated commander replies
+	aiCommanderInterval time.Duration // minimum time between LLM calls
+)
+
+// Rate limiting and de-duplication for commander responses
+var (
+	commanderMutex    sync.Mutex
+	commanderLastCall time.Time
+	commanderAnswered = make(map[string]bool) // team messages already responded to
+)
+
+// Report whether an event is a team message the commander should answer
+func wantsCommanderResponse(event Event) bool {
+	return aiCommander && llmClient.Enabled() && event.Channel == "team" && !strings.HasPrefix(event.Message, commanderPrefix)
+}
+
+// Claim a team message for a response, skipping repeats (after a seek back)
+// and calls that would exceed the rate limit
+func claimCommanderResponse(event Event) bool {
+	commanderMutex.Lock()
+	defer commanderMutex.Unlock()
+
+	key := fmt.Sprintf("%d|%s", event.TimeOffset, event.Message)
+	if commanderAnswered[key] {
+		return false
+	}
+	if wallClock.Now().Sub(commanderLastCall) < aiCommanderInterval {
+		slog.Debug("🤖 Skipping commander response, rate limited", "offset", event.TimeOffset)
+		return false
+	}
+	commanderAnswered[key] = true
+	commanderLastCall = wallClock.Now()
+	return true
+}
+
+// Ask the model for a commander reply to a team message and inject it into
+// the replay a couple of virtual seconds later, so it follows the playback speed
+func respondAsCommander(rp *replay, event Event) {
+	if !claimCommanderResponse(event) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commanderTimeout)
+	defer cancel()
+
+	info := primaryIncident().Transcript.Incident
+	input := fmt.Sprintf("Incident: %s\n%s\n\nLatest team message at %s:\n%s",
+		info.Title, info.Description, formatOffset(event.TimeOffset), event.displayText())
+	reply, err := llmClient.Complete(ctx, commanderPrompt, input, false)
+	if err != nil {
+		slog.Warn("⚠️  Failed to generate commander response", "offset", event.TimeOffset, "err", err)
+		return
+	}
+	reply = strings.TrimSpace(reply)
+	if reply == "" {
+		return
+	}
+
+	rp.inject(Event{Channel: "team", Message: commanderPrefix + " " + reply}, event.TimeOffset+commanderDelaySeconds)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Methods and headers browsers may use on cross-origin control requests
+const (
+	corsAllowMethods = "GET, POST, OPTIONS"
+	corsAllowHeaders = "Authorization, Content-Type"
+)
+
+// Parse a comma-separated origin allowlist; an empty list allows any origin
+func parseCORSOrigins(value string) map[string]bool {
+	origins := make(map[string]bool)
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin != "" {
+			origins[origin] = true
+		}
+	}
+	return origins
+}
+
+// Report whether a request origin may read responses
+func corsAllowed(origin string) bool {
+	return len(corsOrigins) == 0 || corsOrigins[origin]
+}
+
+// Set CORS headers on every route and answer preflight requests. Without an
+// allowlist any origin is allowed, as before; with one the request Origin is
+// echoed back only when listed, and omitted otherwise.
+func withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if len(corsOrigins) == 0 {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Add("Vary", "Origin")
+			if origin != "" && corsOrigins[origin] {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+		}
+
+		// Preflight for the mutating endpoints; never reaches the admin check
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if corsAllowed(origin) {
+				w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+				w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+				w.Header().Set("Access-Control-Max-Age", "600")
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
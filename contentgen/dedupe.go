@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Longest stretch of incident time, in seconds from its first line, that one
+// run of repeated metric lines may cover before it is rolled up
+const dedupeWindow = 30
+
+// How a stream with ?dedupe collapses repeated metric lines
+const (
+	dedupeExact  = "exact"  // identical messages, from ?dedupe=true
+	dedupePrefix = "prefix" // messages that agree up to their first number, e.g. "CPU 98%" and "CPU 99%"
+)
+
+// Consecutive similar metric lines held back on one channel
+type dedupeRun struct {
+	key   string
+	start int           // offset of the run's first line, which was shown
+	count int           // lines in the run, the first included
+	last  replayMessage // latest line held back
+}
+
+// Per-connection collapsing of repeated metric lines. The first line of a
+// run goes out on time; the rest are held back until a different line, a
+// marker or the end of the window, then sent as one rollup of the latest
+// with the run's length, e.g. "CPU 99% (x4)".
+type lineDeduper struct {
+	mode string
+	runs map[string]*dedupeRun // per channel
+}
+
+// Create a deduper for a ?dedupe mode, or nil when the stream doesn't want one
+func newLineDeduper(mode string) *lineDeduper {
+	if mode == "" {
+		return nil
+	}
+	return &lineDeduper{mode: mode, runs: make(map[string]*dedupeRun)}
+}
+
+// What two metric lines must share to count as repeats
+func (d *lineDeduper) key(message string) string {
+	if d.mode == dedupePrefix {
+		if i := strings.IndexFunc(message, unicode.IsDigit); i >= 0 {
+			return strings.TrimSpace(message[:i])
+		}
+	}
+	return message
+}
+
+// Take the next message of the feed and return any rollups due before it,
+// and whether the message itself is to be sent. Markers first release
+// every held run, so rollups never land after a completion or a seek.
+func (d *lineDeduper) offer(msg replayMessage) ([]replayMessage, bool) {
+	if msg.Kind != messageEvent {
+		return d.flush(), true
+	}
+	if msg.Event.Channel != "metrics" {
+		return nil, true
+	}
+
+	channel, key := msg.Event.Channel, d.key(msg.Event.Message)
+	run := d.runs[channel]
+	if run != nil && run.key == key && abs(msg.Event.TimeOffset-run.start) <= dedupeWindow {
+		run.count++
+		run.last = msg
+		return nil, false
+	}
+
+	var rollups []replayMessage
+	if rollup, ok := run.rollup(); ok {
+		rollups = append(rollups, rollup)
+	}
+	d.runs[channel] = &dedupeRun{key: key, start: msg.Event.TimeOffset, count: 1}
+	return rollups, true
+}
+
+// Release every held run as a rollup, in channel order
+func (d *lineDeduper) flush() []replayMessage {
+	channels := make([]string, 0, len(d.runs))
+	for channel := range d.runs {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+
+	var rollups []replayMessage
+	for _, channel := range channels {
+		if rollup, ok := d.runs[channel].rollup(); ok {
+			rollups = append(rollups, rollup)
+		}
+	}
+	clear(d.runs)
+	return rollups
+}
+
+// The latest held line standing for the whole run, if any were held
+func (r *dedupeRun) rollup() (replayMessage, bool) {
+	if r == nil || r.count < 2 {
+		return replayMessage{}, false
+	}
+	rollup := r.last
+	rollup.Repeats = r.count
+	return rollup, true
+}
+
+// Text appended to a rolled-up line, e.g. " (x4)"
+func describeRepeats(count int) string {
+	if count < 2 {
+		return ""
+	}
+	return fmt.Sprintf(" (x%d)", count)
+}
+
+// Absolute value of an offset difference; reverse playback counts down
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
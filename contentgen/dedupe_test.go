@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineDeduper(t *testing.T) {
+	metric := func(offset int, message string) Event {
+		return Event{TimeOffset: offset, Channel: "metrics", Message: message}
+	}
+	// An event without a channel stands for a marker, e.g. completion
+	marker := Event{}
+
+	tests := []struct {
+		name   string
+		mode   string
+		events []Event
+		want   []string // messages sent, in order, with any rollup count
+	}{
+		{"identical lines roll up", dedupeExact, []Event{
+			metric(0, "CPU 99%"), metric(1, "CPU 99%"), metric(2, "CPU 99%"), metric(3, "CPU 99%"), metric(4, "Memory 80%"),
+		}, []string{"CPU 99%", "CPU 99% (x4)", "Memory 80%"}},
+		{"distinct lines pass through", dedupeExact, []Event{
+			metric(0, "CPU 98%"), metric(1, "CPU 99%"), metric(2, "Memory 80%"),
+		}, []string{"CPU 98%", "CPU 99%", "Memory 80%"}},
+		{"exact keeps changed values apart", dedupeExact, []Event{
+			metric(0, "CPU 98%"), metric(1, "CPU 99%"), metric(2, "CPU 99%"), metric(3, "CPU 99%"), metric(4, "Memory 80%"),
+		}, []string{"CPU 98%", "CPU 99%", "CPU 99% (x3)", "Memory 80%"}},
+		{"prefix rolls up changed values", dedupePrefix, []Event{
+			metric(0, "CPU 98%"), metric(1, "CPU 99%"), metric(2, "CPU 97%"), metric(3, "Memory 80%"),
+		}, []string{"CPU 98%", "CPU 97% (x3)", "Memory 80%"}},
+		{"a single repeat is a rollup of two", dedupeExact, []Event{
+			metric(0, "CPU 99%"), metric(5, "CPU 99%"), metric(6, "Memory 80%"),
+		}, []string{"CPU 99%", "CPU 99% (x2)", "Memory 80%"}},
+		{"past the window a new run starts", dedupeExact, []Event{
+			metric(0, "CPU 99%"), metric(10, "CPU 99%"), metric(31, "CPU 99%"), metric(32, "CPU 99%"), metric(33, "Memory 80%"),
+		}, []string{"CPU 99%", "CPU 99% (x2)", "CPU 99%", "CPU 99% (x2)", "Memory 80%"}},
+		{"markers release held lines first", dedupeExact, []Event{
+			metric(0, "CPU 99%"), metric(1, "CPU 99%"), marker,
+		}, []string{"CPU 99%", "CPU 99% (x2)", "marker"}},
+		{"team lines untouched", dedupeExact, []Event{
+			{TimeOffset: 0, Channel: "team", Message: "Rolling back"}, {TimeOffset: 1, Channel: "team", Message: "Rolling back"},
+		}, []string{"Rolling back", "Rolling back"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newLineDeduper(tt.mode)
+			var got []string
+			send := func(msg replayMessage) {
+				if msg.Kind != messageEvent {
+					got = append(got, "marker")
+					return
+				}
+				got = append(got, msg.Event.Message+describeRepeats(msg.Repeats))
+			}
+			for _, event := range tt.events {
+				msg := replayMessage{Kind: messageEvent, Event: event}
+				if event.Channel == "" {
+					msg = replayMessage{Kind: messageComplete}
+				}
+				rollups, show := d.offer(msg)
+				for _, rollup := range rollups {
+					send(rollup)
+				}
+				if show {
+					send(msg)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sent %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if newLineDeduper("") != nil {
+		t.Error("deduper created without ?dedupe")
+	}
+}
+
+func TestDedupeStream(t *testing.T) {
+	tr := &IncidentTranscript{
+		Incident: IncidentInfo{Title: "Checkout outage", DurationSeconds: 6},
+		Events: []Event{
+			{TimeOffset: 0, Channel: "metrics", Message: "CPU 98%"},
+			{TimeOffset: 1, Channel: "metrics", Message: "CPU 99%"},
+			{TimeOffset: 2, Channel: "metrics", Message: "CPU 99%"},
+			{TimeOffset: 3, Channel: "metrics", Message: "CPU 99%"},
+			{TimeOffset: 4, Channel: "metrics", Message: "CPU 99%"},
+			{TimeOffset: 5, Channel: "metrics", Message: "Memory 80%"},
+			{TimeOffset: 6, Channel: "metrics", Message: "Memory 80%"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"off by default", "", []string{"CPU 98%", "CPU 99%", "CPU 99%", "CPU 99%", "CPU 99%", "Memory 80%", "Memory 80%"}},
+		{"identical lines", "&dedupe=true", []string{"CPU 98%", "CPU 99%", "CPU 99% (x4)", "Memory 80%", "Memory 80% (x2)"}},
+		{"similar by prefix", "&dedupe=prefix", []string{"CPU 98%", "CPU 99% (x5)", "Memory 80%", "Memory 80% (x2)"}},
+		{"json carries the count", "&dedupe=true&format=json", []string{`"CPU 98%"`, `"CPU 99%"`, `"CPU 99%","level":"info","repeats":4`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startServer(t, tr)
+			var got []string
+			for _, line := range sseData(readSSE(t, srv.URL+"/stream?channels=metrics&oncomplete=close"+tt.query)) {
+				if strings.Contains(line, "%") {
+					// Text lines open with the wall time, e.g. "[09:00:00] "
+					if _, text, ok := strings.Cut(line, "] "); ok && !strings.HasPrefix(line, "{") {
+						line = text
+					}
+					got = append(got, line)
+				}
+			}
+			if strings.Contains(tt.query, "json") {
+				if !containsInOrder(got, tt.want...) {
+					t.Errorf("frames %q, want %q in order", got, tt.want)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lines %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	srv := startServer(t, tr)
+	if status, body := control(t, http.MethodGet, srv.URL+"/stream?channels=metrics&dedupe=maybe", ""); status != http.StatusBadRequest {
+		t.Errorf("?dedupe=maybe: status %d, want 400: %s", status, body)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Embed side colors for each event level
+var discordLevelColors = map[string]int{
+	levelInfo:  0x3498DB,
+	levelWarn:  0xF1C40F,
+	levelError: 0xE74C3C,
+}
+
+// Discord webhook publisher posting each event as an embed
+type DiscordNotifier struct {
+	WebhookURL    string
+	Channels      map[string]bool // transcript channels published to Discord
+	IncidentTitle string          // embed title
+	HTTPClient    *http.Client
+
+	titleMu sync.RWMutex // guards IncidentTitle once publishing has started
+}
+
+// Create a Discord notifier for the given transcript channels
+func NewDiscordNotifier(webhookURL string, channels []string) *DiscordNotifier {
+	routes := make(map[string]bool)
+	for _, channel := range channels {
+		if channel = strings.TrimSpace(channel); channel != "" {
+			routes[channel] = true
+		}
+	}
+	return &DiscordNotifier{WebhookURL: webhookURL, Channels: routes, HTTPClient: outboundHTTPClient}
+}
+
+// Report whether the notifier has a webhook to post to
+func (d *DiscordNotifier) Enabled() bool {
+	return d != nil && d.WebhookURL != ""
+}
+
+// Name of the Discord backend
+func (d *DiscordNotifier) Name() string {
+	return "discord"
+}
+
+// Report whether a transcript channel is published to Discord
+func (d *DiscordNotifier) Routes(channel string) bool {
+	return d != nil && d.Channels[channel]
+}
+
+// Post a replayed event to the Discord channel, retrying transient failures
+// and waiting out rate limits for as long as Discord asks
+func (d *DiscordNotifier) Publish(event Event) error {
+	if !d.Enabled() {
+		return fmt.Errorf("Discord webhook URL not configured")
+	}
+
+	body, err := json.Marshal(d.buildEmbed(event))
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	return withRetry("Discord webhook", func(ctx context.Context) error {
+		return postDiscordWebhook(ctx, d.HTTPClient, d.WebhookURL, body)
+	})
+}
+
+// Embed title for the incident being published
+func (d *DiscordNotifier) title() string {
+	d.titleMu.RLock()
+	defer d.titleMu.RUnlock()
+	return d.IncidentTitle
+}
+
+// Title embeds with a different incident from now on
+func (d *DiscordNotifier) setIncident(info IncidentInfo) {
+	d.titleMu.Lock()
+	defer d.titleMu.Unlock()
+	d.IncidentTitle = info.Title
+}
+
+// Build the webhook message: one embed titled with the incident, the event
+// as its description and its level as the side color
+func (d *DiscordNotifier) buildEmbed(event Event) map[string]interface{} {
+	embed := map[string]interface{}{
+		"description": event.Message,
+		"color":       discordLevelColors[event.level()],
+		"footer":      map[string]interface{}{"text": fmt.Sprintf("🕒 %s · #%s", formatEventTime(event, wallClock.Now()), event.Channel)},
+	}
+	if title := d.title(); title != "" {
+		embed["title"] = title
+	}
+	if event.Speaker != "" {
+		embed["author"] = map[string]interface{}{"name": event.Speaker}
+	}
+
+	switch event.kind() {
+	case eventLink:
+		embed["description"] = fmt.Sprintf("🔗 [%s](%s)", event.Message, event.Meta["url"])
+	case eventImage:
+		embed["image"] = map[string]interface{}{"url": event.Meta["url"]}
+	}
+
+	return map[string]interface{}{
+		"embeds": []map[string]interface{}{embed},
+	}
+}
+
+// Make a single Discord webhook POST. Discord reports how long to back off
+// in the JSON body of a 429, in seconds with a fractional part.
+func postDiscordWebhook(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return &WebhookError{Err: fmt.Errorf("failed to send request: %w", err), transient: true}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		retryAfter := time.Second
+		var limited struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		if data, err := io.ReadAll(resp.Body); err == nil && json.Unmarshal(data, &limited) == nil && limited.RetryAfter > 0 {
+			retryAfter = time.Duration(math.Ceil(limited.RetryAfter*1000)) * time.Millisecond
+		}
+		return &WebhookError{RetryAfter: retryAfter, Err: fmt.Errorf("Discord rate limited (retry after %s)", retryAfter), transient: true}
+	case resp.StatusCode >= 500:
+		return &WebhookError{Err: fmt.Errorf("Discord server error: HTTP %d", resp.StatusCode), transient: true}
+	case resp.StatusCode >= 300:
+		return &WebhookError{Err: fmt.Errorf("Discord rejected event: HTTP %d", resp.StatusCode)}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Post a Discord webhook received, with the fake time it arrived at
+type discordPost struct {
+	At     time.Time
+	Embeds []map[string]interface{}
+}
+
+// Discord webhook stand-in: answer each post with the next of responses,
+// then 204, returning the notifier and a view of the posts so far
+func fakeDiscord(t *testing.T, clock *fakeClock, responses ...func(w http.ResponseWriter)) (*DiscordNotifier, func() []discordPost) {
+	t.Helper()
+	var mu sync.Mutex
+	var posts []discordPost
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		post := discordPost{At: clock.Now()}
+		var body struct {
+			Embeds []map[string]interface{} `json:"embeds"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Discord body: %v", err)
+		}
+		post.Embeds = body.Embeds
+		mu.Lock()
+		posts = append(posts, post)
+		call := len(posts)
+		mu.Unlock()
+		if call <= len(responses) {
+			responses[call-1](w)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	discord := NewDiscordNotifier(srv.URL, []string{"team"})
+	discord.HTTPClient = srv.Client()
+	return discord, func() []discordPost {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]discordPost(nil), posts...)
+	}
+}
+
+func TestDiscordEmbed(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  map[string]interface{} // fields of the embed besides title and footer
+	}{
+		{"info from a speaker", Event{Channel: "team", Speaker: "Priya", Message: "Rolling back"}, map[string]interface{}{
+			"description": "Rolling back", "color": float64(0x3498DB), "author": map[string]interface{}{"name": "Priya"},
+		}},
+		{"warning", Event{Channel: "team", Message: "disk 85% full", Level: levelWarn}, map[string]interface{}{
+			"description": "disk 85% full", "color": float64(0xF1C40F),
+		}},
+		{"error", Event{Channel: "team", Message: "db primary down", Level: levelError}, map[string]interface{}{
+			"description": "db primary down", "color": float64(0xE74C3C),
+		}},
+		{"link", Event{Channel: "team", Type: "link", Message: "Rollback pipeline", Meta: map[string]string{"url": "https://ci.example.com/812"}}, map[string]interface{}{
+			"description": "🔗 [Rollback pipeline](https://ci.example.com/812)", "color": float64(0x3498DB),
+		}},
+		{"image", Event{Channel: "team", Type: "image", Message: "Error rate graph", Meta: map[string]string{"url": "https://grafana.example.com/graph.png"}}, map[string]interface{}{
+			"description": "Error rate graph", "color": float64(0x3498DB), "image": map[string]interface{}{"url": "https://grafana.example.com/graph.png"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			discord, posts := fakeDiscord(t, clock)
+			discord.IncidentTitle = "Checkout outage"
+
+			if err := discord.Publish(tt.event); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+			got := posts()
+			if len(got) != 1 || len(got[0].Embeds) != 1 {
+				t.Fatalf("posted %+v, want one post with one embed", got)
+			}
+			want := map[string]interface{}{
+				"title":  "Checkout outage",
+				"footer": map[string]interface{}{"text": fmt.Sprintf("🕒 %s · #team", formatEventTime(tt.event, clock.Now()))},
+			}
+			for key, value := range tt.want {
+				want[key] = value
+			}
+			if embed := got[0].Embeds[0]; !reflect.DeepEqual(embed, want) {
+				t.Errorf("embed %v, want %v", embed, want)
+			}
+		})
+	}
+}
+
+func TestDiscordRateLimit(t *testing.T) {
+	answer := func(status int, body string) func(w http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			io.WriteString(w, body)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		response func(w http.ResponseWriter) // answer to the first post
+		retry    time.Duration               // wait before the second post, zero when not retried
+		err      string                      // substring of the Publish error, empty when it succeeds
+	}{
+		{"retry_after from the body", answer(http.StatusTooManyRequests, `{"message":"You are being rate limited.","retry_after":1.5,"global":false}`), 1500 * time.Millisecond, ""},
+		{"fractional milliseconds round up", answer(http.StatusTooManyRequests, `{"retry_after":0.2501}`), 251 * time.Millisecond, ""},
+		{"no retry_after", answer(http.StatusTooManyRequests, ""), time.Second, ""},
+		{"rejected", answer(http.StatusBadRequest, `{"message":"Invalid Form Body"}`), 0, "Discord rejected event: HTTP 400"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			discord, posts := fakeDiscord(t, clock, tt.response)
+
+			published := make(chan error, 1)
+			go func() { published <- discord.Publish(Event{Channel: "team", Message: "Paging on-call"}) }()
+			if tt.retry > 0 {
+				clock.step(t)
+			}
+			err := <-published
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to mention %q", err, tt.err)
+				}
+				if got := posts(); len(got) != 1 {
+					t.Errorf("posted %d times, want once without retrying", len(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+			got := posts()
+			if len(got) != 2 {
+				t.Fatalf("posted %d times, want the 429 and one retry", len(got))
+			}
+			if wait := got[1].At.Sub(got[0].At); wait != tt.retry {
+				t.Errorf("retried after %v, want %v", wait, tt.retry)
+			}
+		})
+	}
+}
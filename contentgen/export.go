@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Turn an incident title into a safe download filename stem
+func exportFilename(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	name := strings.TrimRight(b.String(), "-")
+	if name == "" {
+		name = "incident"
+	}
+	return name + "-replay"
+}
+
+// Interleave the run's annotations with the emitted events by wall time.
+// Annotations come after events emitted at the same instant.
+func exportEntries(emitted []emittedEvent, annotations []annotation) []emittedEvent {
+	entries := make([]emittedEvent, 0, len(emitted)+len(annotations))
+	for _, a := range annotations {
+		for len(emitted) > 0 && !emitted[0].Time.After(a.Time) {
+			entries = append(entries, emitted[0])
+			emitted = emitted[1:]
+		}
+		entries = append(entries, emittedEvent{Time: a.Time, Offset: a.Offset, Message: a.Label, Kind: "annotation", Note: a.Note})
+	}
+	return append(entries, emitted...)
+}
+
+// Handler for downloading the events emitted so far, with any annotations,
+// as JSON or CSV
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		http.Error(w, "Invalid format, expected json or csv", http.StatusBadRequest)
+		return
+	}
+
+	inc := primaryIncident()
+	emitted := exportEntries(inc.Replay.emittedLog(), inc.Replay.annotationLog())
+	filename := exportFilename(inc.Transcript.Incident.Title) + "." + format
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(emitted)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	out := csv.NewWriter(w)
+	out.Write([]string{"time", "offset_seconds", "offset", "channel", "message", "published", "kind", "note"})
+	for _, event := range emitted {
+		kind := event.Kind
+		if kind == "" {
+			kind = "event"
+		}
+		out.Write([]string{
+			event.Time.Format(time.RFC3339Nano),
+			strconv.Itoa(event.Offset),
+			formatOffset(event.Offset),
+			event.Channel,
+			event.Message,
+			strconv.FormatBool(event.Published),
+			kind,
+			event.Note,
+		})
+	}
+	out.Flush()
+}
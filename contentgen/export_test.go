@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Play the fixture to completion on the fake clock, then fetch an export
+func exportAfterReplay(t *testing.T, clock *fakeClock, srv string, format string) (*http.Response, time.Time) {
+	t.Helper()
+	start := clock.Now()
+	stream := openSSE(t, srv+"/stream?channels=team,metrics")
+	stream.until(t, "Paging on-call")
+	for _, next := range []string{"CPU 92%", "CPU 99%", "Rolling back", "Resolved"} {
+		clock.step(t)
+		stream.until(t, next)
+	}
+	eventOutbox.wait()
+
+	resp, err := http.Get(srv + "/export?format=" + format)
+	if err != nil {
+		t.Fatalf("GET /export: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /export?format=%s: %s", format, resp.Status)
+	}
+	if got, want := resp.Header.Get("Content-Disposition"), `attachment; filename="checkout-outage-replay.`+format+`"`; got != want {
+		t.Errorf("Content-Disposition %q, want %q", got, want)
+	}
+	return resp, start
+}
+
+// What the export should hold once the fixture has played at testSpeed:
+// only team is mapped to Slack, so only its events were published
+func expectedExport(start time.Time) []emittedEvent {
+	var want []emittedEvent
+	for _, event := range fixtureTranscript().Events {
+		want = append(want, emittedEvent{
+			Time:      start.Add(time.Duration(event.TimeOffset) * time.Second / testSpeed),
+			Offset:    event.TimeOffset,
+			Channel:   event.Channel,
+			Message:   event.Message,
+			Published: event.Channel == "team",
+		})
+	}
+	return want
+}
+
+func TestExportJSON(t *testing.T) {
+	clock := useFakeClock(t)
+	useFakeSlack(t)
+	srv := startServer(t, fixtureTranscript())
+	resp, start := exportAfterReplay(t, clock, srv.URL, "json")
+
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type %q, want application/json", got)
+	}
+	var got []emittedEvent
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	want := expectedExport(start)
+	if len(got) != len(want) {
+		t.Fatalf("exported %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) {
+			t.Errorf("event %d at %v, want %v", i, got[i].Time, want[i].Time)
+		}
+		got[i].Time = want[i].Time
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	clock := useFakeClock(t)
+	useFakeSlack(t)
+	srv := startServer(t, fixtureTranscript())
+	resp, start := exportAfterReplay(t, clock, srv.URL, "csv")
+
+	if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/csv") {
+		t.Errorf("Content-Type %q, want text/csv", got)
+	}
+	rows, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parse export: %v", err)
+	}
+	header := []string{"time", "offset_seconds", "offset", "channel", "message", "published", "kind", "note"}
+	if len(rows) == 0 || !reflect.DeepEqual(rows[0], header) {
+		t.Fatalf("header row %q, want %q", rows[0], header)
+	}
+
+	want := expectedExport(start)
+	if len(rows)-1 != len(want) {
+		t.Fatalf("exported %d rows, want %d", len(rows)-1, len(want))
+	}
+	for i, event := range want {
+		row := []string{
+			event.Time.Format(time.RFC3339Nano),
+			strconv.Itoa(event.Offset),
+			formatOffset(event.Offset),
+			event.Channel,
+			event.Message,
+			strconv.FormatBool(event.Published),
+			"event",
+			"",
+		}
+		if !reflect.DeepEqual(rows[i+1], row) {
+			t.Errorf("row %d = %q, want %q", i+1, rows[i+1], row)
+		}
+	}
+}
+
+func TestExportRejectsUnknownFormat(t *testing.T) {
+	srv := startServer(t, fixtureTranscript())
+	if status, _ := control(t, http.MethodGet, srv.URL+"/export?format=xml", ""); status != http.StatusBadRequest {
+		t.Errorf("format=xml: status %d, want 400", status)
+	}
+}
+
+func TestExportFilename(t *testing.T) {
+	tests := map[string]string{
+		"Checkout outage":                  "checkout-outage-replay",
+		"API Gateway Outage - Nov 6, 2025": "api-gateway-outage-nov-6-2025-replay",
+		"  ¡Pánico!  ":                     "p-nico-replay",
+		"":                                 "incident-replay",
+	}
+	for title, want := range tests {
+		if got := exportFilename(title); got != want {
+			t.Errorf("exportFilename(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Tidy a transcript file the way gofmt tidies Go: events in timeline order,
+// whitespace normalized, consistent indentation. The file is rewritten in
+// place, or printed to w on a dry run. Nothing is written if the transcript
+// has problems tidying can't fix.
+func runFmt(w io.Writer, path string, dryRun bool) error {
+	if path == "" {
+		return fmt.Errorf("no transcript given, use -transcript")
+	}
+	if isTranscriptURL(path) {
+		return fmt.Errorf("fmt rewrites a file in place, not a URL")
+	}
+
+	data, err := readTranscriptSource(path)
+	if err != nil {
+		return err
+	}
+	t, err := decodeTranscript(data, path)
+	if err != nil {
+		return err
+	}
+	// Out-of-order offsets are the one problem tidying fixes, so sort first
+	moved, err := sortTranscriptEvents(t)
+	if err != nil {
+		return err
+	}
+	if err := validateTranscript(t); err != nil {
+		return err
+	}
+	tidied := 0
+	for i, event := range t.Events {
+		if message := tidyWhitespace(event.Message); message != event.Message {
+			t.Events[i].Message = message
+			tidied++
+		}
+	}
+
+	formatted, err := encodeTranscript(t, path)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		_, err := w.Write(formatted)
+		return err
+	}
+	if bytes.Equal(formatted, data) {
+		slog.Info("✅ Transcript already formatted", "path", path)
+		return nil
+	}
+	if err := os.WriteFile(path, formatted, 0o644); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	slog.Info("✅ Formatted transcript", "path", path, "events", len(t.Events), "moved", moved, "messages_tidied", tidied)
+	return nil
+}
+
+// Stably sort events by the offset they fire at, keeping same-offset events
+// in file order, and return how many changed place. Events authored with
+// delay_after are ordered by their resolved offset but keep delay_after as
+// written, so the sort is refused if moving them would change their timing.
+func sortTranscriptEvents(t *IncidentTranscript) (int, error) {
+	resolved := &IncidentTranscript{Events: slices.Clone(t.Events)}
+	resolveEventDelays(resolved)
+	resolveEventTimestamps(resolved)
+
+	order := make([]int, len(t.Events))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return resolved.Events[order[a]].TimeOffset < resolved.Events[order[b]].TimeOffset
+	})
+
+	moved := 0
+	sorted := make([]Event, len(order))
+	for i, from := range order {
+		sorted[i] = t.Events[from]
+		if from != i {
+			moved++
+		}
+	}
+
+	check := &IncidentTranscript{Events: slices.Clone(sorted)}
+	resolveEventDelays(check)
+	resolveEventTimestamps(check)
+	for i, from := range order {
+		if check.Events[i].TimeOffset != resolved.Events[from].TimeOffset {
+			return 0, fmt.Errorf("event %d: sorting would change the offset delay_after gives it, reorder the file by hand", from)
+		}
+	}
+	warnDuplicateOffsets(check.Events)
+
+	t.Events = sorted
+	return moved, nil
+}
+
+// Collapse runs of spaces and tabs, trim every line and drop blank lines at
+// either end, keeping the line breaks of multi-line messages
+func tidyWhitespace(message string) string {
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// Encode a transcript in its source format with consistent indentation
+func encodeTranscript(t *IncidentTranscript, source string) ([]byte, error) {
+	if isYAMLTranscript(source) {
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(t); err != nil {
+			return nil, fmt.Errorf("failed to marshal transcript: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return nil, fmt.Errorf("failed to marshal transcript: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	// Authors write & and <, so keep them rather than escaping them for HTML
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(t); err != nil {
+		return nil, fmt.Errorf("failed to marshal transcript: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFmt(t *testing.T) {
+	unsortedJSON := `{"incident": {"title": "Unsorted", "duration_seconds": 60},
+"events": [
+{"time_offset": 30, "channel": "team", "message": "  Rolling   back\t now "},
+{"time_offset": 0, "channel": "team", "message": "Paging on-call"},
+{"time_offset": 10, "channel": "metrics", "message": "error_rate=12%  &  rising"}
+]}`
+
+	tests := []struct {
+		name  string
+		file  string // written to a temp file of this name
+		data  string // read from testdata when empty
+		load  string // substring of the error loading the file as written, empty when it loads
+		err   string // substring of the fmt error, empty when it formats
+		order []string
+	}{
+		// Loading rejects what fmt sorts, and says so
+		{"unsorted YAML fixture", "decreasing_offsets.yaml", "", "-mode fmt sorts them", "", []string{
+			"Paging on-call", "error_rate=12%", "Found the bad deploy", "Rolling back",
+		}},
+		{"unsorted JSON with untidy whitespace", "unsorted.json", unsortedJSON, "-mode fmt sorts them", "", []string{
+			"Paging on-call", "error_rate=12% & rising", "Rolling back now",
+		}},
+		{"already formatted", "interleaved_channels.yaml", "", "", "", []string{
+			"Paging on-call", "error_rate=12%", "Rolling back", "error_rate=0%",
+		}},
+		{"invalid beyond sorting", "negative_offset.json", "", "time_offset -5 is negative", "time_offset -5 is negative", nil},
+		{"delay_after would move", "delays.json", `{"events": [
+			{"time_offset": 10, "channel": "team", "message": "b"},
+			{"delay_after": 5, "channel": "team", "message": "c"},
+			{"time_offset": 12, "channel": "team", "message": "a"}
+		]}`, "-mode fmt can't sort them", "sorting would change the offset delay_after gives it", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(tt.data)
+			if tt.data == "" {
+				var err error
+				if data, err = os.ReadFile(filepath.Join("testdata", tt.file)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := parseTranscript(data, path)
+			if tt.load == "" && err != nil {
+				t.Fatalf("load as written: %v", err)
+			}
+			if tt.load != "" && (err == nil || !strings.Contains(err.Error(), tt.load)) {
+				t.Fatalf("load as written: error %v, want it to mention %q", err, tt.load)
+			}
+
+			// A dry run prints what a real run writes, leaving the file alone
+			var printed bytes.Buffer
+			err = runFmt(&printed, path, true)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to mention %q", err, tt.err)
+				}
+				if err := runFmt(&printed, path, false); err == nil {
+					t.Fatal("fmt rewrote a transcript it can't fix")
+				}
+				if after, _ := os.ReadFile(path); !bytes.Equal(after, data) {
+					t.Error("failed fmt changed the file")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fmt dry run: %v", err)
+			}
+			if after, _ := os.ReadFile(path); !bytes.Equal(after, data) {
+				t.Error("dry run changed the file")
+			}
+
+			if err := runFmt(&bytes.Buffer{}, path, false); err != nil {
+				t.Fatalf("fmt: %v", err)
+			}
+			formatted, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(formatted, printed.Bytes()) {
+				t.Errorf("wrote\n%s\nbut the dry run printed\n%s", formatted, printed.Bytes())
+			}
+
+			// The result loads as it is, and formatting it again changes nothing
+			tr, err := parseTranscript(formatted, path)
+			if err != nil {
+				t.Fatalf("formatted transcript doesn't load: %v\n%s", err, formatted)
+			}
+			var got []string
+			for _, event := range tr.Events {
+				got = append(got, event.Message)
+			}
+			if !reflect.DeepEqual(got, tt.order) {
+				t.Errorf("events %q, want %q", got, tt.order)
+			}
+			if err := runFmt(&bytes.Buffer{}, path, false); err != nil {
+				t.Fatalf("second fmt: %v", err)
+			}
+			if again, _ := os.ReadFile(path); !bytes.Equal(again, formatted) {
+				t.Errorf("second fmt changed the file to\n%s", again)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// Response writer that gzip-compresses a stream while keeping it flushable:
+// Flush pushes buffered bytes out of the compressor and then the response,
+// so streaming latency isn't lost to compression buffering.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz      *gzip.Writer
+	flusher http.Flusher
+}
+
+// Wrap w in a gzip writer and mark the response as gzip-encoded
+func newGzipResponseWriter(w http.ResponseWriter, flusher http.Flusher) *gzipResponseWriter {
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.Header().Del("Content-Length")
+	return &gzipResponseWriter{ResponseWriter: w, gz: gzip.NewWriter(w), flusher: flusher}
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	return g.gz.Write(p)
+}
+
+func (g *gzipResponseWriter) Flush() {
+	g.gz.Flush()
+	g.flusher.Flush()
+}
+
+// Write the gzip footer; call once the stream is finished
+func (g *gzipResponseWriter) Close() error {
+	return g.gz.Close()
+}
+
+// Report whether the client accepts a gzip-encoded response
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.TrimSpace(coding) != "gzip" {
+			continue
+		}
+		return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+	}
+	return false
+}
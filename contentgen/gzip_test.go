@@ -0,0 +1,91 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"testing"
+)
+
+// Open a stream asking for gzip, as browsers do
+func getGzipStream(t *testing.T, url string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	// Set by hand, so the transport leaves the body compressed
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestGzipStream(t *testing.T) {
+	clock := useFakeClock(t)
+	srv := startServer(t, fixtureChannels("team"))
+	resp := getGzipStream(t, srv.URL+"/stream/team?oncomplete=close")
+	if got := resp.Header.Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding %q, want gzip", got)
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("gzip header: %v", err)
+	}
+	stream := &sseStream{url: srv.URL, frames: make(chan sseFrame, 256)}
+	go func() {
+		defer close(stream.frames)
+		scanSSE(gz, func(frame sseFrame) { stream.frames <- frame })
+	}()
+
+	// The first event decodes while the clock still holds the next one, so
+	// each event was flushed through the compressor as it was written
+	stream.until(t, "Paging on-call")
+	clock.step(t)
+	stream.until(t, "Rolling back")
+	clock.step(t)
+	stream.until(t, "✅ Incident replay completed")
+	for range stream.frames {
+	}
+}
+
+func TestStreamWithoutGzip(t *testing.T) {
+	srv := startServer(t, fixtureChannels("team"))
+	// The default transport would ask for gzip and decode it out of sight
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	resp, err := client.Get(srv.URL + "/stream/team?oncomplete=close")
+	if err != nil {
+		t.Fatalf("GET stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding %q without Accept-Encoding, want none", got)
+	}
+	if lines := sseData(parseSSE(resp.Body)); !containsInOrder(lines, "Paging on-call", "Resolved") {
+		t.Errorf("uncompressed stream %q, want the events", lines)
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.8, br", true},
+		{"gzip;q=0", false},
+		{"gzip; q=0", false},
+		{"br", false},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest(http.MethodGet, "/stream/team", nil)
+		r.Header.Set("Accept-Encoding", tt.header)
+		if got := acceptsGzip(r); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
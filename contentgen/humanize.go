@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Subtle timing variance so replayed messages don't arrive like clockwork;
+// nil unless -humanize is set
+var humanize *humanizeConfig
+
+// How humanize mode varies event timing
+type humanizeConfig struct {
+	Jitter float64 // largest delay, as a fraction of the gap to the channel's next event
+	Seed   int64   // seeds every replay's jitter, so a run can be reproduced
+}
+
+// Check the humanize parameters are usable. A delay of a whole gap or more
+// could push an event past the one after it.
+func (c *humanizeConfig) validate() error {
+	if c.Jitter < 0 || c.Jitter >= 1 {
+		return fmt.Errorf("jitter %g must be at least 0 and less than 1", c.Jitter)
+	}
+	return nil
+}
+
+// Jitter source for one replay, reseeded every run like the chaos dice
+type humanizer struct {
+	config *humanizeConfig
+	rng    *rand.Rand
+}
+
+// Create a jitter source for a replay, or nil when humanize mode is off
+func newHumanizer(config *humanizeConfig) *humanizer {
+	if config == nil {
+		return nil
+	}
+	return &humanizer{config: config, rng: rand.New(rand.NewSource(config.Seed))}
+}
+
+// Random delay, in virtual seconds, for an event followed by the given gap.
+// It stays below the gap, so events on a channel never swap places.
+func (h *humanizer) delay(gap float64) float64 {
+	if h == nil || gap <= 0 {
+		return 0
+	}
+	return h.rng.Float64() * h.config.Jitter * gap
+}
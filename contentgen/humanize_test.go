@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHumanizeJitterBounds(t *testing.T) {
+	// Uneven gaps on two channels, so each event's bound differs
+	offsets := map[string][]int{
+		"team":    {0, 3, 4, 20, 50},
+		"metrics": {1, 2, 12, 13, 40},
+	}
+	var events []Event
+	due := make(map[string]int)   // message -> offset it is due at
+	bound := make(map[string]int) // message -> gap to the next event on its channel
+	for channel, times := range offsets {
+		for i, offset := range times {
+			message := fmt.Sprintf("%s %d", channel, i)
+			events = append(events, Event{TimeOffset: offset, Channel: channel, Message: message})
+			due[message] = offset
+			if i+1 < len(times) {
+				bound[message] = times[i+1] - offset
+			}
+		}
+	}
+	normalizeTranscript(&IncidentTranscript{Events: events})
+
+	tests := []struct {
+		name   string
+		jitter float64
+		seed   int64
+	}{
+		{"default jitter", 0.2, 1},
+		{"other seed", 0.2, 99},
+		{"largest jitter", 0.95, 7},
+		{"no jitter", 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := humanize
+			humanize = &humanizeConfig{Jitter: tt.jitter, Seed: tt.seed}
+			t.Cleanup(func() { humanize = previous })
+
+			var runs [2]string
+			for run := range runs {
+				clock := useFakeClock(t)
+				rp := newReplay(events, newSpeedControl(1), false)
+				t.Cleanup(rp.wait)
+				sub, _ := rp.subscribeWithBacklog([]string{"team", "metrics"})
+				got := driveTimed(t, clock, sub)
+				runs[run] = fmt.Sprint(got)
+
+				jittered := false
+				next := map[string]int{} // channel -> index of the event expected next
+				for _, event := range got {
+					channel, index, _ := strings.Cut(event.Message, " ")
+					if want := fmt.Sprint(next[channel]); index != want {
+						t.Errorf("%q fired when %s %s was next", event.Message, channel, want)
+					}
+					next[channel]++
+
+					// Late by less than jitter times the gap, and never early
+					late := event.At - time.Duration(due[event.Message])*time.Second
+					limit := time.Duration(tt.jitter * float64(bound[event.Message]) * float64(time.Second))
+					if late < 0 || (late > 0 && late >= limit) {
+						t.Errorf("%q fired %v late, want under %v", event.Message, late, limit)
+					}
+					jittered = jittered || late > 0
+				}
+				if len(got) != len(events) {
+					t.Errorf("%d events fired, want all %d", len(got), len(events))
+				}
+				if jittered != (tt.jitter > 0) {
+					t.Errorf("events fired late: %v, want %v", jittered, tt.jitter > 0)
+				}
+			}
+			// The same seed gives the same timing every run
+			if runs[0] != runs[1] {
+				t.Errorf("runs differ with seed %d:\n%s\n%s", tt.seed, runs[0], runs[1])
+			}
+		})
+	}
+}
+
+func TestHumanizeConfigValidate(t *testing.T) {
+	tests := []struct {
+		jitter float64
+		valid  bool
+	}{
+		{0, true},
+		{0.2, true},
+		{0.99, true},
+		{1, false},
+		{-0.1, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.jitter), func(t *testing.T) {
+			err := (&humanizeConfig{Jitter: tt.jitter}).validate()
+			if (err == nil) != tt.valid {
+				t.Errorf("validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Default log line format: an RFC3339 timestamp, an optional level, then the message
+const defaultImportPattern = `^(?P<ts>\d{4}-\d{2}-\d{2}T\S+)\s+(?:\[?(?P<level>[A-Za-z]+)\]?\s+)?(?P<msg>.*)$`
+
+// Settings for converting a log file into a transcript
+type logImportOptions struct {
+	In            string // log file to read
+	Out           string // transcript file to write
+	Pattern       string // regexp with named groups ts and msg, and optionally level
+	TimeLayout    string // Go time layout of the ts group
+	Channel       string // channel for lines without a level mapping
+	LevelChannels string // level-to-channel map such as "error=metrics,warn=metrics"
+}
+
+// Parse a level-to-channel map like "error=metrics,warn=metrics"; levels are case-insensitive
+func parseLevelChannels(value string) (map[string]string, error) {
+	channels := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		level, channel, ok := strings.Cut(pair, "=")
+		level, channel = strings.ToLower(strings.TrimSpace(level)), strings.TrimSpace(channel)
+		if !ok || level == "" || channel == "" {
+			return nil, fmt.Errorf("invalid level mapping %q, expected level=channel", pair)
+		}
+		channels[level] = channel
+	}
+	return channels, nil
+}
+
+// Convert log lines into a transcript with offsets relative to the first line.
+// Lines that don't match the pattern are skipped and counted.
+func buildImportedTranscript(lines []string, source string, opts logImportOptions) (*IncidentTranscript, int, error) {
+	pattern, err := regexp.Compile(opts.Pattern)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid import pattern: %w", err)
+	}
+	tsGroup, msgGroup, levelGroup := pattern.SubexpIndex("ts"), pattern.SubexpIndex("msg"), pattern.SubexpIndex("level")
+	if tsGroup < 0 || msgGroup < 0 {
+		return nil, 0, fmt.Errorf("import pattern needs named groups (?P<ts>...) and (?P<msg>...)")
+	}
+	levelChannels, err := parseLevelChannels(opts.LevelChannels)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	t := &IncidentTranscript{
+		Incident: IncidentInfo{
+			Title:       fmt.Sprintf("Imported incident from %s", filepath.Base(source)),
+			Description: fmt.Sprintf("Converted from application logs in %s", source),
+		},
+	}
+
+	var first time.Time
+	skipped := 0
+	for _, line := range lines {
+		match := pattern.FindStringSubmatch(line)
+		if match == nil {
+			skipped++
+			continue
+		}
+		logged, err := time.Parse(opts.TimeLayout, match[tsGroup])
+		message := strings.TrimSpace(match[msgGroup])
+		if err != nil || message == "" {
+			skipped++
+			continue
+		}
+		if first.IsZero() {
+			first = logged
+		}
+
+		channel := opts.Channel
+		if levelGroup >= 0 {
+			if mapped, ok := levelChannels[strings.ToLower(match[levelGroup])]; ok {
+				channel = mapped
+			}
+		}
+
+		offset := int(logged.Sub(first).Seconds())
+		if offset < 0 {
+			// Out-of-order lines keep their place at the start rather than going negative
+			offset = 0
+		}
+		t.Events = append(t.Events, Event{TimeOffset: offset, Channel: channel, Message: message})
+		if offset > t.Incident.DurationSeconds {
+			t.Incident.DurationSeconds = offset
+		}
+	}
+
+	// Lines logged out of order take their place in the timeline
+	sort.SliceStable(t.Events, func(i, j int) bool {
+		return t.Events[i].TimeOffset < t.Events[j].TimeOffset
+	})
+	if err := validateTranscript(t); err != nil {
+		return nil, skipped, err
+	}
+	return t, skipped, nil
+}
+
+// Convert an application log file into a replayable transcript file
+func runImport(opts logImportOptions) error {
+	if opts.In == "" {
+		return fmt.Errorf("no log file given, use -import-in")
+	}
+
+	file, err := os.Open(opts.In)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read log file: %w", err)
+	}
+
+	slog.Info("📥 Importing log file", "path", opts.In, "lines", len(lines))
+	t, skipped, err := buildImportedTranscript(lines, opts.In, opts)
+	if err != nil {
+		return err
+	}
+	if skipped > 0 {
+		slog.Warn("⚠️  Skipped log lines that didn't match the import pattern", "skipped", skipped)
+	}
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal transcript: %w", err)
+	}
+	if err := os.WriteFile(opts.Out, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+
+	slog.Info("✅ Imported transcript", "events", len(t.Events), "path", opts.Out)
+	return nil
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// An independently replayed incident: its transcript, shared timeline and
+// playback speed
+type incident struct {
+	ID         string
+	Transcript *IncidentTranscript
+	Replay     *replay
+}
+
+// Incidents loaded from -transcripts-dir or created with POST /transcripts,
+// keyed by file name without extension
+var incidents = make(map[string]*incident)
+
+// Guards incidents once the server is up, since POST /transcripts adds to it
+var incidentsMu sync.RWMutex
+
+// Directory of -transcripts-dir, where POST /transcripts saves new transcripts
+var incidentsDir string
+
+// Look up a loaded incident by id
+func findIncident(id string) (*incident, bool) {
+	incidentsMu.RLock()
+	defer incidentsMu.RUnlock()
+	inc, ok := incidents[id]
+	return inc, ok
+}
+
+// Snapshot of every loaded incident, in no particular order
+func listIncidents() []*incident {
+	incidentsMu.RLock()
+	defer incidentsMu.RUnlock()
+	list := make([]*incident, 0, len(incidents))
+	for _, inc := range incidents {
+		list = append(list, inc)
+	}
+	return list
+}
+
+// ID under which the transcript loaded at startup can be switched back to
+const defaultTranscriptID = "default"
+
+var (
+	activeMu          sync.RWMutex          // guards the primary incident, which POST /transcript swaps
+	activeID          = defaultTranscriptID // transcript served on the top-level routes
+	defaultTranscript *IncidentTranscript   // transcript loaded at startup
+)
+
+// The incident served on the top-level routes
+func primaryIncident() *incident {
+	activeMu.RLock()
+	defer activeMu.RUnlock()
+	return &incident{Transcript: transcript, Replay: incidentReplay}
+}
+
+// Answer 503 instead of running a handler that needs the primary incident
+// while no transcript is loaded, so a missing transcript never panics a
+// request goroutine
+func requireTranscript(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if inc := primaryIncident(); inc.Transcript == nil || inc.Replay == nil {
+			writeJSONError(w, http.StatusServiceUnavailable, "transcript not loaded")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Look up a transcript that can be made the primary one
+func libraryTranscript(id string) (*IncidentTranscript, bool) {
+	if id == defaultTranscriptID {
+		return defaultTranscript, true
+	}
+	if inc, ok := findIncident(id); ok {
+		return inc.Transcript, true
+	}
+	return nil, false
+}
+
+// Make a library transcript the primary incident for new connections, on a
+// fresh replay. Streams already connected keep the old replay, which stops
+// publishing externally so the two runs never both reach Slack.
+func switchTranscript(id string) (*IncidentTranscript, error) {
+	t, ok := libraryTranscript(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown transcript %q", id)
+	}
+	activateTranscript(id, t)
+	slog.Info("🔀 Switched active transcript", "id", id, "title", t.Incident.Title, "events", len(t.Events))
+	return t, nil
+}
+
+// Serve t on the top-level routes under id, on a fresh replay
+func activateTranscript(id string, t *IncidentTranscript) {
+	rp := newReplay(t.Events, playback, true)
+	rp.startAt = time.Time{}
+	rp.title = t.Incident.Title
+
+	activeMu.Lock()
+	previous := incidentReplay
+	transcript, incidentReplay, activeID = t, rp, id
+	activeMu.Unlock()
+
+	previous.retire()
+	slackClient.setIncident(t.Incident)
+	if n, ok := chatNotifier.(incidentNamer); ok {
+		n.setIncident(t.Incident)
+	}
+}
+
+// Load every JSON or YAML transcript in a directory as its own incident.
+// These replays are for parallel rooms watching the streams, so they never
+// publish to Slack or the other integrations.
+func loadIncidentsDir(dir string) (map[string]*incident, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcripts directory: %w", err)
+	}
+
+	loaded := make(map[string]*incident)
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".json" && ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		id := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if _, exists := loaded[id]; exists {
+			return nil, fmt.Errorf("duplicate incident id %q in %s", id, dir)
+		}
+		inc, err := loadIncidentFile(id, filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		loaded[id] = inc
+	}
+
+	if len(loaded) == 0 {
+		return nil, fmt.Errorf("no transcripts found in %s", dir)
+	}
+	return loaded, nil
+}
+
+// Load one transcript file as an incident with its own replay, which never
+// publishes to Slack or the other integrations
+func loadIncidentFile(id, path string) (*incident, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript %s: %w", path, err)
+	}
+	t, err := parseTranscript(data, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := renderTranscript(t, templateVars, strictVars); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	rp := newReplay(t.Events, newSpeedControl(getPlaybackSpeed("")), false)
+	rp.title = t.Incident.Title
+	slog.Info("✅ Loaded incident", "id", id, "title", t.Incident.Title, "events", len(t.Events))
+	return &incident{ID: id, Transcript: t, Replay: rp}, nil
+}
+
+// Find the incident named in the request path, answering 404 if there is none
+func lookupIncident(w http.ResponseWriter, r *http.Request) *incident {
+	inc, ok := findIncident(r.PathValue("id"))
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "Unknown incident")
+		return nil
+	}
+	return inc
+}
+
+// Entry in the incident listing
+type incidentListing struct {
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+	Events          int    `json:"events"`
+	DurationSeconds int    `json:"duration_seconds"`
+	Active          bool   `json:"active,omitempty"` // served on the top-level routes
+}
+
+// Handler listing the incidents loaded from -transcripts-dir
+func incidentsHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	loaded := listIncidents()
+	list := make([]incidentListing, 0, len(loaded))
+	for _, inc := range loaded {
+		list = append(list, incidentListing{
+			ID:              inc.ID,
+			Title:           inc.Transcript.Incident.Title,
+			Events:          len(inc.Transcript.Events),
+			DurationSeconds: inc.Transcript.Incident.DurationSeconds,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// Incident metadata for clients that want it before opening a stream
+type incidentMetadata struct {
+	IncidentInfo
+	Channels map[string]int `json:"channels"` // event count per transcript channel
+}
+
+// Describe an incident's transcript and the events on each channel
+func describeIncident(t *IncidentTranscript) incidentMetadata {
+	metadata := incidentMetadata{IncidentInfo: t.Incident, Channels: make(map[string]int)}
+	for _, event := range t.Events {
+		metadata.Channels[event.Channel]++
+	}
+	return metadata
+}
+
+// Handler for the primary incident's metadata
+func incidentInfoHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(describeIncident(primaryIncident().Transcript))
+}
+
+// Handler listing the transcripts the primary incident can be switched to,
+// and creating new ones with POST
+func transcriptsHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method == http.MethodPost {
+		createTranscriptHandler(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	activeMu.RLock()
+	active := activeID
+	activeMu.RUnlock()
+
+	ids := []string{defaultTranscriptID}
+	for _, inc := range listIncidents() {
+		if inc.ID != defaultTranscriptID {
+			ids = append(ids, inc.ID)
+		}
+	}
+	sort.Strings(ids[1:])
+
+	list := make([]incidentListing, 0, len(ids))
+	for _, id := range ids {
+		t, _ := libraryTranscript(id)
+		list = append(list, incidentListing{
+			ID:              id,
+			Title:           t.Incident.Title,
+			Events:          len(t.Events),
+			DurationSeconds: t.Incident.DurationSeconds,
+			Active:          id == active,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// Largest transcript body POST /transcripts accepts
+const maxTranscriptUpload = 10 << 20
+
+// Characters that don't belong in an id derived from an incident title
+var transcriptIDUnsafe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Response to a transcript created with POST /transcripts
+type createdTranscript struct {
+	Status  string            `json:"status"`
+	ID      string            `json:"id"`
+	Title   string            `json:"title"`
+	Streams map[string]string `json:"streams"` // stream path per channel
+}
+
+// Generate an unused incident id from a title, e.g. db-failover-3fa91c
+func newTranscriptID(title string) (string, error) {
+	slug := strings.Trim(transcriptIDUnsafe.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > 40 {
+		slug = strings.TrimRight(slug[:40], "-")
+	}
+	if slug == "" {
+		slug = "transcript"
+	}
+	for {
+		suffix := make([]byte, 3)
+		if _, err := rand.Read(suffix); err != nil {
+			return "", fmt.Errorf("failed to generate transcript id: %w", err)
+		}
+		id := slug + "-" + hex.EncodeToString(suffix)
+		if _, taken := findIncident(id); !taken && id != defaultTranscriptID {
+			return id, nil
+		}
+	}
+}
+
+// Each problem validation found, for clients to show next to their input
+func validationProblems(err error) []string {
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		return []string{err.Error()}
+	}
+	var problems []string
+	for _, problem := range joined.Unwrap() {
+		problems = append(problems, problem.Error())
+	}
+	return problems
+}
+
+// Create a transcript from a JSON body, e.g. authored in another UI: it is
+// validated like a transcript file, saved to -transcripts-dir under a new id
+// and loaded as an incident room right away, ready to stream or switch to
+func createTranscriptHandler(w http.ResponseWriter, r *http.Request) {
+	if incidentsDir == "" {
+		writeJSONError(w, http.StatusConflict, "Creating transcripts needs -transcripts-dir")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTranscriptUpload))
+	if errors.As(err, new(*http.MaxBytesError)) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Transcript is larger than %d bytes", maxTranscriptUpload))
+		return
+	}
+	if err != nil {
+		// e.g. the client went away or sent a malformed chunked body
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	t, err := decodeTranscript(body, "upload.json")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateTranscript(t); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid transcript", "errors": validationProblems(err)})
+		return
+	}
+
+	id, err := newTranscriptID(t.Incident.Title)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	path := filepath.Join(incidentsDir, id+".json")
+	data, err := encodeTranscript(t, path)
+	if err == nil {
+		err = writeNewFile(path, data)
+	}
+	if err != nil {
+		slog.Error("❌ Failed to save transcript", "id", id, "path", path, "err", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to save transcript")
+		return
+	}
+
+	// Loading also fills in template variables, which can still fail
+	inc, err := loadIncidentFile(id, path)
+	if err != nil {
+		os.Remove(path)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	incidentsMu.Lock()
+	incidents[id] = inc
+	incidentsMu.Unlock()
+	if wallClock.Now().Before(replayStartAt) {
+		go inc.Replay.scheduleStart()
+	}
+	slog.Info("🆕 Created transcript", "id", id, "title", t.Incident.Title, "path", path)
+
+	created := createdTranscript{Status: "ok", ID: id, Title: inc.Transcript.Incident.Title, Streams: make(map[string]string)}
+	for channel := range describeIncident(inc.Transcript).Channels {
+		created.Streams[channel] = fmt.Sprintf("/incidents/%s/stream/%s", id, channel)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/incidents/"+id+"/status")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// Write a file that must not exist yet, so a new transcript never replaces one
+func writeNewFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return f.Close()
+}
+
+// Handler for switching the primary incident to another transcript
+func switchTranscriptHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, "Missing id parameter")
+		return
+	}
+
+	t, err := switchTranscript(id)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok", "message": fmt.Sprintf("Switched to %s", t.Incident.Title)})
+}
+
+// Handler for one channel stream of a loaded incident
+func incidentChannelStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if inc := lookupIncident(w, r); inc != nil {
+		channel := r.PathValue("channel")
+		streamChannel(w, r, inc, channel, channel)
+	}
+}
+
+// Handler for replay progress of a loaded incident
+func incidentStatusHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if inc := lookupIncident(w, r); inc != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(inc.Replay.status(inc.Transcript.Incident))
+	}
+}
+
+// Handler for the playback speed of a loaded incident
+func incidentSpeedHandler(w http.ResponseWriter, r *http.Request) {
+	if inc := lookupIncident(w, r); inc != nil {
+		serveSpeed(w, r, inc.Replay.speed)
+	}
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// GET /incident, failing the test unless it answers JSON any origin can read
+func getIncident(t *testing.T, url string) incidentMetadata {
+	t.Helper()
+	resp, err := http.Get(url + "/incident")
+	if err != nil {
+		t.Fatalf("GET /incident: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /incident: %s", resp.Status)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type %q, want application/json", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin %q, want *", got)
+	}
+	var metadata incidentMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		t.Fatalf("decode /incident: %v", err)
+	}
+	return metadata
+}
+
+func TestIncidentMetadata(t *testing.T) {
+	useFakeSlack(t)
+	srv := startServer(t, fixtureTranscript())
+
+	tests := []struct {
+		name   string
+		reload string // transcript file reloaded first, if any
+		want   incidentMetadata
+	}{
+		{"loaded fixture", "", incidentMetadata{
+			IncidentInfo: IncidentInfo{Title: "Checkout outage", Description: "Payments failing in us-east-1", DurationSeconds: 20},
+			Channels:     map[string]int{"team": 3, "metrics": 2},
+		}},
+		{"after a hot reload", "testdata/checkout_outage.json", incidentMetadata{
+			IncidentInfo: IncidentInfo{Title: "Checkout outage", Description: "Payments failing in us-east-1: card authorizations time out", DurationSeconds: 120},
+			Channels:     map[string]int{"team": 4, "metrics": 2},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.reload != "" {
+				previous := transcriptFile
+				transcriptFile = tt.reload
+				t.Cleanup(func() { transcriptFile = previous })
+				if err := reloadTranscript(); err != nil {
+					t.Fatalf("reload: %v", err)
+				}
+			}
+			if got := getIncident(t, srv.URL); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GET /incident = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Add a transcript to the library under id for the rest of the test, as
+// -transcripts-dir would
+func useIncident(t *testing.T, id string, tr *IncidentTranscript) {
+	t.Helper()
+	incidentsMu.Lock()
+	defer incidentsMu.Unlock()
+	incidents[id] = &incident{ID: id, Transcript: tr, Replay: newReplay(tr.Events, newSpeedControl(testSpeed), false)}
+	t.Cleanup(func() {
+		incidentsMu.Lock()
+		defer incidentsMu.Unlock()
+		delete(incidents, id)
+	})
+}
+
+func TestSwitchTranscript(t *testing.T) {
+	useFakeSlack(t)
+	srv := startServer(t, fixtureChannels("team"))
+	drill := fixtureChannels("metrics")
+	drill.Incident.Title = "Metrics drill"
+	useIncident(t, "drill", drill)
+
+	// A stream connected before the switch keeps its transcript
+	before := openSSE(t, srv.URL+"/stream/team")
+	before.until(t, "📋 Incident: Checkout outage")
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		active string // transcript listed as active afterwards
+		title  string // title new connections see
+	}{
+		{"missing id", "", http.StatusBadRequest, defaultTranscriptID, "Checkout outage"},
+		{"unknown id", "?id=nope", http.StatusNotFound, defaultTranscriptID, "Checkout outage"},
+		{"switch", "?id=drill", http.StatusOK, "drill", "Metrics drill"},
+		{"switch back", "?id=" + defaultTranscriptID, http.StatusOK, defaultTranscriptID, "Checkout outage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := control(t, http.MethodPost, srv.URL+"/transcript"+tt.query, ""); status != tt.status {
+				t.Fatalf("POST /transcript%s: status %d, want %d: %s", tt.query, status, tt.status, body)
+			}
+
+			status, body := control(t, http.MethodGet, srv.URL+"/transcripts", "")
+			if status != http.StatusOK {
+				t.Fatalf("GET /transcripts: status %d: %s", status, body)
+			}
+			var list []incidentListing
+			if err := json.Unmarshal([]byte(body), &list); err != nil {
+				t.Fatalf("decode %q: %v", body, err)
+			}
+			var ids []string
+			active := ""
+			for _, listing := range list {
+				ids = append(ids, listing.ID)
+				if listing.Active {
+					active = listing.ID
+				}
+			}
+			if want := []string{defaultTranscriptID, "drill"}; !reflect.DeepEqual(ids, want) {
+				t.Errorf("listed %v, want %v", ids, want)
+			}
+			if active != tt.active {
+				t.Errorf("active transcript %q, want %q", active, tt.active)
+			}
+			if got := getIncident(t, srv.URL).Title; got != tt.title {
+				t.Errorf("new connections get %q, want %q", got, tt.title)
+			}
+		})
+	}
+
+	// The earlier stream plays out the transcript it connected to
+	for _, line := range sseData(before.until(t, "✅ Incident replay completed")) {
+		if strings.Contains(line, "CPU") {
+			t.Errorf("stream connected before the switch got %q", line)
+		}
+	}
+}
+
+// Switching renames the incident in chat posts while the outbox may be
+// publishing; run with -race to check the two don't race
+func TestSwitchTranscriptWhilePublishing(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend func(t *testing.T) (notifier, func() string) // chat backend and the title it posts under
+	}{
+		{"Slack", func(t *testing.T) (notifier, func() string) {
+			useFakeSlack(t)
+			slackClient.BlockKit = true
+			slackClient.ThreadMode = slackThreadReuse
+			return slackClient, func() string { title, _ := slackClient.incident(); return title }
+		}},
+		{"Teams", func(t *testing.T) (notifier, func() string) {
+			teams, _ := fakeTeams(t, http.StatusOK)
+			return teams, teams.title
+		}},
+		{"Discord", func(t *testing.T) (notifier, func() string) {
+			discord, _ := fakeDiscord(t, useFakeClock(t))
+			return discord, discord.title
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeSlack(t)
+			backend, title := tt.backend(t)
+			chatNotifier = backend
+			startServer(t, fixtureChannels("team"))
+			drill := fixtureChannels("metrics")
+			drill.Incident.Title = "Metrics drill"
+
+			// Publish the way the outbox worker would, switching once it's under way
+			started, published := make(chan struct{}), make(chan struct{})
+			go func() {
+				defer close(published)
+				for i := 0; i < 50; i++ {
+					if err := backend.Publish(Event{TimeOffset: i, Channel: "team", Message: "Rolling back"}); err != nil {
+						t.Errorf("publish %d: %v", i, err)
+					}
+					if i == 1 {
+						close(started)
+					}
+				}
+			}()
+			<-started
+			// Straight to the switch itself: the log line switchTranscript writes
+			// would order the two goroutines and hide a race from the detector
+			for i := 0; i < 20; i++ {
+				if i%2 == 0 {
+					activateTranscript("drill", drill)
+				} else {
+					activateTranscript(defaultTranscriptID, defaultTranscript)
+				}
+			}
+			<-published
+
+			if got := title(); got != "Checkout outage" {
+				t.Errorf("posting under %q after the switches, want %q", got, "Checkout outage")
+			}
+		})
+	}
+}
+
+func TestTranscriptNotLoaded(t *testing.T) {
+	activeMu.Lock()
+	previousTranscript, previousReplay := transcript, incidentReplay
+	transcript, incidentReplay = nil, nil
+	activeMu.Unlock()
+	t.Cleanup(func() {
+		activeMu.Lock()
+		defer activeMu.Unlock()
+		transcript, incidentReplay = previousTranscript, previousReplay
+	})
+	srv := httptest.NewServer(newServer())
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		name   string
+		viewer string // -viewer-token, empty when unset
+		method string
+		path   string
+		status int
+	}{
+		{"team stream", "", http.MethodGet, "/stream/team", http.StatusServiceUnavailable},
+		{"combined stream", "", http.MethodGet, "/stream?channels=team,metrics", http.StatusServiceUnavailable},
+		{"WebSocket", "", http.MethodGet, "/ws/team", http.StatusServiceUnavailable},
+		{"status", "", http.MethodGet, "/status", http.StatusServiceUnavailable},
+		{"metadata", "", http.MethodGet, "/incident", http.StatusServiceUnavailable},
+		{"config", "", http.MethodGet, "/config", http.StatusServiceUnavailable},
+		{"search", "", http.MethodGet, "/search?q=rollback", http.StatusServiceUnavailable},
+		{"timeline", "", http.MethodGet, "/timeline", http.StatusServiceUnavailable},
+		{"activity", "", http.MethodGet, "/activity", http.StatusServiceUnavailable},
+		{"summary", "", http.MethodGet, "/summary", http.StatusServiceUnavailable},
+		{"export", "", http.MethodGet, "/export", http.StatusServiceUnavailable},
+		{"seek", "", http.MethodPost, "/seek?offset=10", http.StatusServiceUnavailable},
+		{"restart", "", http.MethodPost, "/restart", http.StatusServiceUnavailable},
+		// Authentication is still checked first
+		{"stream without a viewer token", "v1ew", http.MethodGet, "/stream/team", http.StatusUnauthorized},
+		// Routes that don't need the primary incident keep answering
+		{"page", "", http.MethodGet, "/", http.StatusOK},
+		{"healthz", "", http.MethodGet, "/healthz", http.StatusOK},
+		{"incident rooms", "", http.MethodGet, "/incidents", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousViewer := viewerToken
+			viewerToken = tt.viewer
+			t.Cleanup(func() { viewerToken = previousViewer })
+
+			status, body := control(t, tt.method, srv.URL+tt.path, "")
+			if status != tt.status {
+				t.Fatalf("%s %s: status %d, want %d: %s", tt.method, tt.path, status, tt.status, body)
+			}
+			if status != http.StatusServiceUnavailable {
+				return
+			}
+			var got map[string]string
+			if err := json.Unmarshal([]byte(body), &got); err != nil || got["error"] != "transcript not loaded" {
+				t.Errorf("body %q, want {\"error\":\"transcript not loaded\"}", body)
+			}
+		})
+	}
+}
+
+func TestCreateTranscript(t *testing.T) {
+	valid := `{"incident":{"title":"Checkout outage","duration_seconds":20},"events":[
+		{"time_offset":0,"channel":"team","message":"Paging on-call"},
+		{"time_offset":1,"channel":"metrics","message":"CPU 92%"}]}`
+
+	tests := []struct {
+		name   string
+		noDir  bool // -transcripts-dir unset
+		token  string
+		body   string
+		status int
+		errs   []string // validation problems listed in the 400 response
+	}{
+		{"valid", false, "s3cret", valid, http.StatusCreated, nil},
+		{"invalid", false, "s3cret", `{"incident":{"title":"Broken"},"events":[
+			{"time_offset":-5,"channel":"team","message":"Paging on-call"},
+			{"time_offset":1,"channel":"","message":""}]}`, http.StatusBadRequest, []string{
+			"event 0: time_offset -5 is negative", "event 1: channel is empty", "event 1: message is empty",
+		}},
+		{"no events", false, "s3cret", `{"incident":{"title":"Empty"},"events":[]}`, http.StatusBadRequest, []string{"transcript has no events"}},
+		{"not JSON", false, "s3cret", "incident: yaml", http.StatusBadRequest, nil},
+		{"too large", false, "s3cret", `{"padding":"` + strings.Repeat("x", maxTranscriptUpload) + `"}`, http.StatusRequestEntityTooLarge, nil},
+		{"no admin token", false, "", valid, http.StatusUnauthorized, nil},
+		{"no transcripts dir", true, "s3cret", valid, http.StatusConflict, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousToken, previousDir := adminToken, incidentsDir
+			adminToken, incidentsDir = "s3cret", t.TempDir()
+			if tt.noDir {
+				incidentsDir = ""
+			}
+			dir := incidentsDir
+			t.Cleanup(func() { adminToken, incidentsDir = previousToken, previousDir })
+			srv := startServer(t, fixtureTranscript())
+
+			req, err := http.NewRequest(http.MethodPost, srv.URL+"/transcripts", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			data, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status %d, want %d: %s", resp.StatusCode, tt.status, data)
+			}
+			if dir != "" {
+				if saved, _ := os.ReadDir(dir); (len(saved) == 1) != (tt.status == http.StatusCreated) {
+					t.Errorf("%d files saved, want one only for a created transcript", len(saved))
+				}
+			}
+
+			switch resp.StatusCode {
+			case http.StatusBadRequest:
+				var got struct {
+					Error  string   `json:"error"`
+					Errors []string `json:"errors"`
+				}
+				if err := json.Unmarshal(data, &got); err != nil {
+					t.Fatalf("decode %q: %v", data, err)
+				}
+				if tt.errs != nil && (got.Error != "invalid transcript" || !reflect.DeepEqual(got.Errors, tt.errs)) {
+					t.Errorf("error %q with %q, want invalid transcript with %q", got.Error, got.Errors, tt.errs)
+				}
+			case http.StatusCreated:
+				var got createdTranscript
+				if err := json.Unmarshal(data, &got); err != nil {
+					t.Fatalf("decode %q: %v", data, err)
+				}
+				t.Cleanup(func() {
+					incidentsMu.Lock()
+					defer incidentsMu.Unlock()
+					delete(incidents, got.ID)
+				})
+				if !strings.HasPrefix(got.ID, "checkout-outage-") || got.Title != "Checkout outage" {
+					t.Errorf("created %q titled %q, want an id from the title", got.ID, got.Title)
+				}
+				want := map[string]string{
+					"team":    "/incidents/" + got.ID + "/stream/team",
+					"metrics": "/incidents/" + got.ID + "/stream/metrics",
+				}
+				if !reflect.DeepEqual(got.Streams, want) {
+					t.Errorf("streams %v, want %v", got.Streams, want)
+				}
+				if location := resp.Header.Get("Location"); location != "/incidents/"+got.ID+"/status" {
+					t.Errorf("Location %q, want the room's status", location)
+				}
+
+				// Saved under its id and loaded as a room straight away
+				if _, err := os.Stat(filepath.Join(dir, got.ID+".json")); err != nil {
+					t.Errorf("transcript not saved: %v", err)
+				}
+				if status, body := control(t, http.MethodGet, srv.URL+"/incidents/"+got.ID+"/status", ""); status != http.StatusOK {
+					t.Errorf("room status %d: %s", status, body)
+				}
+			}
+		})
+	}
+
+	// A body that can't be read is a bad request, not an oversized one
+	t.Run("malformed chunked body", func(t *testing.T) {
+		previousToken, previousDir := adminToken, incidentsDir
+		adminToken, incidentsDir = "s3cret", t.TempDir()
+		t.Cleanup(func() { adminToken, incidentsDir = previousToken, previousDir })
+		srv := startServer(t, fixtureTranscript())
+
+		conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		io.WriteString(conn, "POST /transcripts HTTP/1.1\r\nHost: contentgen\r\nAuthorization: Bearer s3cret\r\n"+
+			"Content-Type: application/json\r\nTransfer-Encoding: chunked\r\n\r\nnot-a-chunk-size\r\n")
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			data, _ := io.ReadAll(resp.Body)
+			t.Errorf("status %d, want 400: %s", resp.StatusCode, data)
+		}
+	})
+}
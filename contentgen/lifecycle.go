@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Lifecycle signals sent to -lifecycle-webhooks
+const (
+	hookStart           = "start"            // a replay run began, including each loop or restart
+	hookChannelComplete = "channel_complete" // a channel emitted its last event
+	hookComplete        = "complete"         // every channel finished
+)
+
+// Body POSTed to every lifecycle webhook
+type lifecyclePayload struct {
+	Event     string `json:"event"`
+	Incident  string `json:"incident"`
+	Channel   string `json:"channel,omitempty"`
+	Timestamp string `json:"ts"`
+}
+
+// Replay-level webhooks for orchestration: unlike WebhookSink they get
+// lifecycle signals rather than individual events. Deliveries happen in
+// order on a background worker, and failures are only logged.
+type LifecycleHooks struct {
+	URLs       []string
+	HTTPClient *http.Client
+	queue      chan lifecyclePayload
+}
+
+// Create lifecycle hooks and start their delivery worker; nil when there are no URLs
+func NewLifecycleHooks(urls []string) *LifecycleHooks {
+	if len(urls) == 0 {
+		return nil
+	}
+	hooks := &LifecycleHooks{
+		URLs:       urls,
+		HTTPClient: outboundHTTPClient,
+		queue:      make(chan lifecyclePayload, webhookQueueSize),
+	}
+	go hooks.run()
+	return hooks
+}
+
+// Parse a comma-separated list of lifecycle webhook URLs
+func parseLifecycleURLs(value string) ([]string, error) {
+	var urls []string
+	for _, url := range strings.Split(value, ",") {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return nil, fmt.Errorf("invalid lifecycle webhook URL %q", url)
+		}
+		urls = append(urls, url)
+	}
+	return urls, nil
+}
+
+// Queue a lifecycle signal for every webhook
+func (h *LifecycleHooks) Notify(event, incident, channel string) {
+	if h == nil {
+		return
+	}
+
+	payload := lifecyclePayload{
+		Event:     event,
+		Incident:  incident,
+		Channel:   channel,
+		Timestamp: wallClock.Now().Format(time.RFC3339),
+	}
+	select {
+	case h.queue <- payload:
+	default:
+		slog.Warn("⚠️  Lifecycle webhook queue full, dropping signal", "event", event, "incident", incident, "channel", channel)
+	}
+}
+
+// Deliver queued signals one at a time, retrying transient failures
+func (h *LifecycleHooks) run() {
+	for payload := range h.queue {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			slog.Warn("⚠️  Failed to marshal lifecycle payload", "event", payload.Event, "err", err)
+			continue
+		}
+
+		for _, url := range h.URLs {
+			err := withRetry("lifecycle webhook", func(ctx context.Context) error {
+				return postWebhook(ctx, h.HTTPClient, url, body)
+			})
+			if err != nil {
+				slog.Warn("⚠️  Failed to deliver lifecycle webhook", "event", payload.Event, "channel", payload.Channel, "err", err)
+				continue
+			}
+			slog.Debug("Delivered lifecycle webhook", "event", payload.Event, "channel", payload.Channel)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Receiver answering every lifecycle POST with status and, when it accepts
+// them, handing the payloads on in order
+func lifecycleReceiver(t *testing.T, status int) (string, chan lifecyclePayload) {
+	t.Helper()
+	received := make(chan lifecyclePayload, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload lifecyclePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("lifecycle body: %v", err)
+		}
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			received <- payload
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, received
+}
+
+func TestLifecycleWebhooks(t *testing.T) {
+	// The fixture's metrics finish at T+3 and its team channel at T+20
+	signals := []lifecyclePayload{
+		{Event: hookStart, Incident: "Checkout outage", Timestamp: "2024-03-14T09:00:00Z"},
+		{Event: hookChannelComplete, Incident: "Checkout outage", Channel: "metrics", Timestamp: "2024-03-14T09:00:03Z"},
+		{Event: hookChannelComplete, Incident: "Checkout outage", Channel: "team", Timestamp: "2024-03-14T09:00:20Z"},
+		{Event: hookComplete, Incident: "Checkout outage", Timestamp: "2024-03-14T09:00:20Z"},
+	}
+
+	tests := []struct {
+		name    string
+		private bool
+		failing bool // a rejecting webhook is listed first
+		want    []lifecyclePayload
+	}{
+		{"shared replay", false, false, signals},
+		{"a failing webhook doesn't hold the others back", false, true, signals},
+		{"private replays stay quiet", true, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			url, received := lifecycleReceiver(t, http.StatusOK)
+			urls := []string{url}
+			if tt.failing {
+				rejecting, _ := lifecycleReceiver(t, http.StatusBadRequest)
+				urls = []string{rejecting, url}
+			}
+			previous := lifecycleHooks
+			lifecycleHooks = NewLifecycleHooks(urls)
+			t.Cleanup(func() { lifecycleHooks = previous })
+
+			tr := fixtureTranscript()
+			var rp *replay
+			if tt.private {
+				rp = newPrivateReplay(tr.Events, newSpeedControl(1), false, -1)
+			} else {
+				rp = newReplay(tr.Events, newSpeedControl(1), false)
+			}
+			rp.title = tr.Incident.Title
+			t.Cleanup(rp.wait)
+			sub, _ := rp.subscribeWithBacklog([]string{"team", "metrics"})
+			drive(t, clock, sub)
+
+			// Signals are delivered in order, so once this marker arrives
+			// everything the run sent has too
+			lifecycleHooks.Notify("marker", "", "")
+			var got []lifecyclePayload
+			for {
+				select {
+				case payload := <-received:
+					if payload.Event == "marker" {
+						if !reflect.DeepEqual(got, tt.want) {
+							t.Errorf("lifecycle POSTs %+v, want %+v", got, tt.want)
+						}
+						return
+					}
+					got = append(got, payload)
+				case <-time.After(5 * time.Second):
+					t.Fatalf("webhook received %+v, then nothing", got)
+				}
+			}
+		})
+	}
+}
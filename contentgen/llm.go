@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Defaults for the OpenAI-compatible chat completions API
+const (
+	defaultLLMBaseURL = "https://api.openai.com/v1"
+	defaultLLMModel   = "gpt-4o-mini"
+)
+
+// Client for an OpenAI-compatible chat completions API
+type LLMClient struct {
+	BaseURL    string
+	APIKey     string
+	Model      string
+	HTTPClient *http.Client
+}
+
+// Create an LLM client from LLM_BASE_URL, LLM_API_KEY and LLM_MODEL
+func NewLLMClientFromEnv() *LLMClient {
+	client := &LLMClient{
+		BaseURL:    strings.TrimRight(os.Getenv("LLM_BASE_URL"), "/"),
+		APIKey:     os.Getenv("LLM_API_KEY"),
+		Model:      os.Getenv("LLM_MODEL"),
+		HTTPClient: &http.Client{Timeout: 60 * time.Second},
+	}
+	if client.BaseURL == "" {
+		client.BaseURL = defaultLLMBaseURL
+	}
+	if client.Model == "" {
+		client.Model = defaultLLMModel
+	}
+	return client
+}
+
+// Report whether the client has an API key to call with
+func (c *LLMClient) Enabled() bool {
+	return c != nil && c.APIKey != ""
+}
+
+// Chat message in a completion request
+type llmMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Run a single chat completion and return the model's reply. In JSON mode
+// the model is asked to answer with a JSON object.
+func (c *LLMClient) Complete(ctx context.Context, system, user string, jsonMode bool) (string, error) {
+	if !c.Enabled() {
+		return "", fmt.Errorf("LLM API key not configured")
+	}
+
+	payload := map[string]interface{}{
+		"model": c.Model,
+		"messages": []llmMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+	}
+	if jsonMode {
+		payload["response_format"] = map[string]string{"type": "json_object"}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("LLM API error: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message llmMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &completion); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", fmt.Errorf("LLM API returned no choices")
+	}
+	return completion.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Buffer safe for the concurrent writes of handlers and the replay clock
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// Records logged so far, decoded from JSON lines
+func (b *syncBuffer) records(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// Capture the default logger as JSON for the rest of the test
+func captureLogs(t *testing.T, level string) *syncBuffer {
+	t.Helper()
+	t.Setenv("LOG_FORMAT", "json")
+	out := &syncBuffer{}
+	logger, err := newLogger(out, level)
+	if err != nil {
+		t.Fatalf("newLogger: %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return out
+}
+
+func TestNewLoggerLevel(t *testing.T) {
+	tests := []struct {
+		level string
+		debug bool
+		warn  bool
+		err   bool
+	}{
+		{"debug", true, true, false},
+		{"INFO", false, true, false},
+		{"error", false, false, false},
+		{"chatty", false, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			logger, err := newLogger(&bytes.Buffer{}, tt.level)
+			if (err != nil) != tt.err {
+				t.Fatalf("newLogger(%q) error %v, want error %v", tt.level, err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if got := logger.Enabled(context.Background(), slog.LevelDebug); got != tt.debug {
+				t.Errorf("debug enabled %v, want %v", got, tt.debug)
+			}
+			if got := logger.Enabled(context.Background(), slog.LevelWarn); got != tt.warn {
+				t.Errorf("warn enabled %v, want %v", got, tt.warn)
+			}
+		})
+	}
+}
+
+func TestLogFields(t *testing.T) {
+	logs := captureLogs(t, "info")
+	srv := startServer(t, fixtureChannels("team"))
+	expectStatus(t, srv.URL+"/speed?speed=250", http.StatusOK)
+	readSSE(t, srv.URL+"/stream/team?oncomplete=close")
+
+	// Each of these messages carries its structured fields, whatever their values
+	want := map[string][]string{
+		"Client connected to stream": {"channel", "remote_addr", "clients"},
+		"⚡ Playback speed set":       {"speed"},
+		"[TEAM] Rolling back":        {"channel", "index", "offset"},
+	}
+	for _, record := range logs.records(t) {
+		msg, _ := record["msg"].(string)
+		fields, ok := want[msg]
+		if !ok {
+			continue
+		}
+		delete(want, msg)
+		for _, field := range fields {
+			if _, ok := record[field]; !ok {
+				t.Errorf("%q logged without %q: %v", msg, field, record)
+			}
+		}
+	}
+	for msg := range want {
+		t.Errorf("%q never logged", msg)
+	}
+}
@@ -2,473 +2,1366 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
+	"math"
 	"net/http"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Transcript structures
 type IncidentTranscript struct {
-	Incident IncidentInfo `json:"incident"`
-	Events   []Event      `json:"events"`
+	Incident IncidentInfo `json:"incident" yaml:"incident"`
+	Events   []Event      `json:"events" yaml:"events"`
 }
 
 type IncidentInfo struct {
-	Title           string `json:"title"`
-	DurationSeconds int    `json:"duration_seconds"`
-	Description     string `json:"description"`
+	Title           string `json:"title" yaml:"title"`
+	DurationSeconds int    `json:"duration_seconds" yaml:"duration_seconds"`
+	Description     string `json:"description" yaml:"description"`
 }
 
 type Event struct {
-	TimeOffset int    `json:"time_offset"`
-	Channel    string `json:"channel"`
-	Message    string `json:"message"`
+	TimeOffset int               `json:"time_offset" yaml:"time_offset"`
+	DelayAfter *int              `json:"delay_after,omitempty" yaml:"delay_after,omitempty"` // seconds after the channel's previous event; overrides time_offset
+	Timestamp  string            `json:"timestamp,omitempty" yaml:"timestamp,omitempty"`     // RFC3339 wall time it happened; overrides delay_after and time_offset
+	Channel    string            `json:"channel" yaml:"channel"`
+	Speaker    string            `json:"speaker,omitempty" yaml:"speaker,omitempty"` // who said it, shown as "[Speaker] message"
+	Message    string            `json:"message" yaml:"message"`
+	Type       string            `json:"type,omitempty" yaml:"type,omitempty"`   // text (default), link or image
+	Meta       map[string]string `json:"meta,omitempty" yaml:"meta,omitempty"`   // e.g. url and alt for links and images
+	Level      string            `json:"level,omitempty" yaml:"level,omitempty"` // info (default), warn or error
+}
+
+// Event payload types
+const (
+	eventText  = "text"
+	eventLink  = "link"
+	eventImage = "image"
+)
+
+// Payload type of an event, defaulting to plain text
+func (e Event) kind() string {
+	if e.Type == "" {
+		return eventText
+	}
+	return e.Type
+}
+
+// Event severity levels
+const (
+	levelInfo  = "info"
+	levelWarn  = "warn"
+	levelError = "error"
+)
+
+// Severity of an event, defaulting to info
+func (e Event) level() string {
+	if e.Level == "" {
+		return levelInfo
+	}
+	return e.Level
+}
+
+// Human-readable text of an event, attributed to its speaker if it has one,
+// with the URL of a link or image appended
+func (e Event) displayText() string {
+	text := e.Message
+	if e.Speaker != "" {
+		text = fmt.Sprintf("[%s] %s", e.Speaker, text)
+	}
+	if url := e.Meta["url"]; url != "" && e.kind() != eventText {
+		return text + " " + url
+	}
+	return text
 }
 
+// Default web UI and transcript bundled into the binary
+var (
+	//go:embed index.html
+	embeddedIndex []byte
+
+	//go:embed incident_transcript.json
+	embeddedTranscript []byte
+)
+
 // Global variables
 var (
-	transcript     *IncidentTranscript
-	playbackSpeed  float64 = 2.0
-	speedMutex     sync.RWMutex
-	transcriptFile = "incident_transcript.json"
-	slackBotToken  string
-	slackChannelID string = "C09QB9P3XST" // Team channel ID
+	transcript      *IncidentTranscript
+	incidentReplay  *replay
+	playback        = newSpeedControl(2.0)
+	loopReplay      bool              // start the replay over when it finishes
+	loopSlack       bool              // keep publishing to Slack on every loop, not just the first
+	stepMode        bool              // hold each event until POST /advance releases it, instead of timing it
+	replayStartAt   time.Time         // scheduled start for every replay; zero starts with the first viewer
+	adminToken      string            // bearer token guarding control endpoints, if set
+	viewerToken     string            // token required to watch streams, if set
+	corsOrigins     map[string]bool   // allowed cross-origin callers; empty allows any
+	transcriptFile  string            // file or URL override for the embedded transcript
+	mergeSources    []string          // transcripts combined into one incident instead of a single one
+	mergeNamespace  bool              // prefix merged channels with their source's name, e.g. db:team
+	indexFile       string            // disk override for the embedded web UI
+	templateVars    map[string]string // values for {{.Name}} placeholders in the transcript
+	strictVars      bool              // fail loading when a placeholder has no value
+	inferLevels     bool              // derive missing event levels from ERROR/WARN message prefixes
+	inferSpeakers   bool              // move "[Name] " message prefixes into the speaker field
+	timeScale       = 1.0             // factor applied to every transcript offset at load
+	titleStamp      string            // format for date-stamping the incident title; empty keeps the author's
+	slackClient     *SlackClient
+	pagerDutyClient *PagerDutyClient
+	webhookSink     *WebhookSink
+	lifecycleHooks  *LifecycleHooks
+	slackChannelID  string = "C09QB9P3XST" // Default team channel ID
+
+	// Event timestamp display, shared by every stream and Slack
+	timestampLocation = time.Local
+	timestampLayout   = "15:04:05"
+	timestampMode     = "wall" // wall, offset or both
 )
 
+// Lifecycle signal types sent as `event: system` frames when ?lifecycle=true
+const (
+	lifecycleStart           = "start"
+	lifecycleChannelComplete = "channel_complete"
+	lifecycleComplete        = "complete"
+	lifecycleRestarted       = "restarted"
+	lifecyclePaused          = "paused"
+	lifecycleResumed         = "resumed"
+)
+
+// Read and parse the transcript to serve: the -merge sources combined, the
+// -transcript file or URL, or else the embedded default
+func readPrimaryTranscript() (*IncidentTranscript, error) {
+	if len(mergeSources) > 0 {
+		return loadMergedTranscript(mergeSources, mergeNamespace)
+	}
+
+	data := embeddedTranscript
+	if transcriptFile != "" {
+		var err error
+		data, err = readTranscriptSource(transcriptFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// JSON and YAML transcripts parse into the same structure
+	return parseTranscript(data, transcriptFormatSource(transcriptFile))
+}
+
 // Load transcript from file
 func loadTranscript() error {
-	data, err := os.ReadFile(transcriptFile)
+	t, err := prepareTranscript()
+	if err != nil {
+		return err
+	}
+	transcript = t
+	slog.Info("✅ Loaded transcript", "title", t.Incident.Title, "description", t.Incident.Description, "events", len(t.Events))
+	return nil
+}
+
+// Longest wait between startup load attempts
+const transcriptLoadMaxBackoff = 30 * time.Second
+
+// Load the transcript at startup, trying up to attempts times with
+// exponential backoff, for sources that come up after the server does such
+// as a sidecar still fetching the file. Every failure is retried, since a
+// file being written can fail to parse as well as to read.
+func loadTranscriptWithRetry(attempts int, backoff time.Duration) error {
+	for attempt := 1; ; attempt++ {
+		err := loadTranscript()
+		if err == nil || attempt >= attempts {
+			return err
+		}
+		slog.Warn("⏳ Failed to load transcript, retrying", "attempt", attempt, "attempts", attempts, "err", err, "retry_in", backoff)
+		<-wallClock.After(backoff)
+		backoff = min(backoff*2, transcriptLoadMaxBackoff)
+	}
+}
+
+// Read the primary transcript and apply the load-time flags: time scale,
+// template variables and title stamp
+func prepareTranscript() (*IncidentTranscript, error) {
+	t, err := readPrimaryTranscript()
 	if err != nil {
-		return fmt.Errorf("failed to read transcript file: %w", err)
+		return nil, err
 	}
 
-	var t IncidentTranscript
-	if err := json.Unmarshal(data, &t); err != nil {
-		return fmt.Errorf("failed to parse transcript: %w", err)
+	// Rewrite the canonical offsets once, unlike playback speed
+	if timeScale != 1 {
+		scaleTranscript(t, timeScale)
+		slog.Info("📏 Scaled transcript timeline", "factor", timeScale, "duration_seconds", t.Incident.DurationSeconds)
 	}
 
-	// Update title with current date
-	currentDate := time.Now().Format("Jan 2, 2006")
-	t.Incident.Title = fmt.Sprintf("Production API Gateway Outage - %s", currentDate)
+	// Fill in per-demo variables such as {{.Service}} and {{.Region}}
+	if err := renderTranscript(t, templateVars, strictVars); err != nil {
+		return nil, err
+	}
 
-	transcript = &t
-	log.Printf("✅ Loaded transcript: %s", t.Incident.Title)
-	log.Printf("   Description: %s", t.Incident.Description)
-	log.Printf("   Events: %d", len(t.Events))
-	return nil
+	// Keep the author's title unless asked to date-stamp it
+	if titleStamp != "" {
+		title, err := stampTitle(t.Incident.Title, titleStamp, wallClock.Now())
+		if err != nil {
+			return nil, err
+		}
+		t.Incident.Title = title
+	}
+
+	return t, nil
+}
+
+// Read a boolean setting from the environment, false when unset or invalid
+func envBool(name string) bool {
+	value, _ := strconv.ParseBool(os.Getenv(name))
+	return value
 }
 
-// Get current playback speed
-func getPlaybackSpeed() float64 {
-	speedMutex.RLock()
-	defer speedMutex.RUnlock()
-	return playbackSpeed
+// Format a virtual incident offset as T+HH:MM:SS
+func formatOffset(seconds int) string {
+	return fmt.Sprintf("T+%02d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
 }
 
-// Set playback speed
-func setPlaybackSpeed(speed float64) {
-	speedMutex.Lock()
-	defer speedMutex.Unlock()
-	if speed < 0.1 {
-		speed = 0.1
-	} else if speed > 10.0 {
-		speed = 10.0
+// Format the timestamp shown next to an event emitted at the given wall time:
+// wall-clock time in the configured zone, the virtual incident offset, or both
+func formatEventTime(event Event, at time.Time) string {
+	switch timestampMode {
+	case "offset":
+		return formatOffset(event.TimeOffset)
+	case "both":
+		return at.In(timestampLocation).Format(timestampLayout) + " " + formatOffset(event.TimeOffset)
+	default:
+		return at.In(timestampLocation).Format(timestampLayout)
 	}
-	playbackSpeed = speed
-	log.Printf("⚡ Playback speed set to %.1fx", speed)
 }
 
-// Publish message to Slack channel
-func publishToSlack(message string) error {
-	if slackBotToken == "" {
-		return fmt.Errorf("Slack bot token not configured")
+// Playback speed of one incident, shared by its replay and every private
+// timeline derived from it. Channels may override the default speed.
+type speedControl struct {
+	mu        sync.RWMutex
+	speed     float64
+	overrides map[string]float64 // per-channel speeds
+	changed   chan struct{}
+	ramp      *speedRamp     // speed change in progress, if any
+	ramps     sync.WaitGroup // ramp goroutines, including replaced ones still winding down
+}
+
+// Gradual change of one channel's speed, or the default for ""
+type speedRamp struct {
+	channel string
+	target  float64
+	stop    chan struct{}
+}
+
+// Interval between speed updates while ramping
+const speedRampTick = 100 * time.Millisecond
+
+// Keep a playback speed within the supported range
+func clampSpeed(speed float64) float64 {
+	return math.Min(math.Max(speed, 0.1), 10.0)
+}
+
+// Create a speed control at the given default speed
+func newSpeedControl(speed float64) *speedControl {
+	return &speedControl{speed: speed, overrides: make(map[string]float64), changed: make(chan struct{})}
+}
+
+// Get current playback speed of a channel, or the default for ""
+func (sc *speedControl) get(channel string) float64 {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	if speed, ok := sc.overrides[channel]; ok {
+		return speed
 	}
+	return sc.speed
+}
 
-	// Prepare Slack API request
-	payload := map[string]interface{}{
-		"channel": slackChannelID,
-		"text":    message,
+// Set playback speed of a channel, or the default for "", stopping any ramp
+func (sc *speedControl) set(channel string, speed float64) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.stopRampLocked()
+	speed = clampSpeed(speed)
+	sc.setLocked(channel, speed)
+	if channel == "" {
+		slog.Info("⚡ Playback speed set", "speed", speed)
+	} else {
+		slog.Info("⚡ Playback speed set", "channel", channel, "speed", speed)
 	}
+}
 
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+func (sc *speedControl) setLocked(channel string, speed float64) {
+	if channel == "" {
+		sc.speed = speed
+	} else {
+		sc.overrides[channel] = speed
 	}
+	// Wake the replay clock so it re-anchors at the new speed
+	close(sc.changed)
+	sc.changed = make(chan struct{})
+}
 
-	// Create HTTP request
-	req, err := http.NewRequest("POST", "https://slack.com/api/chat.postMessage", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+// Move a channel's speed, or the default for "", linearly to a target over
+// a duration. A new ramp or an explicit set replaces one in progress.
+func (sc *speedControl) rampTo(channel string, target float64, over time.Duration) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.stopRampLocked()
+
+	from := sc.speed
+	if speed, ok := sc.overrides[channel]; ok {
+		from = speed
 	}
+	ramp := &speedRamp{channel: channel, target: clampSpeed(target), stop: make(chan struct{})}
+	sc.ramp = ramp
+	slog.Info("📈 Ramping playback speed", "channel", channel, "from", from, "target", ramp.target, "over", over)
+	sc.ramps.Add(1)
+	go func() {
+		defer sc.ramps.Done()
+		sc.runRamp(ramp, from, over)
+	}()
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+slackBotToken)
+// Step the speed toward the ramp's target until it arrives or is replaced
+func (sc *speedControl) runRamp(ramp *speedRamp, from float64, over time.Duration) {
+	start := wallClock.Now()
+	for {
+		select {
+		case <-ramp.stop:
+			return
+		case <-wallClock.After(speedRampTick):
+		}
 
-	// Send request
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		progress := float64(wallClock.Now().Sub(start)) / float64(over)
+		sc.mu.Lock()
+		if sc.ramp != ramp {
+			sc.mu.Unlock()
+			return
+		}
+		if progress >= 1 {
+			sc.setLocked(ramp.channel, ramp.target)
+			sc.ramp = nil
+			sc.mu.Unlock()
+			slog.Info("⚡ Playback speed ramp finished", "channel", ramp.channel, "speed", ramp.target)
+			return
+		}
+		sc.setLocked(ramp.channel, from+(ramp.target-from)*progress)
+		sc.mu.Unlock()
 	}
-	defer resp.Body.Close()
+}
 
-	// Check response
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+func (sc *speedControl) stopRampLocked() {
+	if sc.ramp != nil {
+		close(sc.ramp.stop)
+		sc.ramp = nil
 	}
+}
 
-	if ok, exists := result["ok"].(bool); !exists || !ok {
-		errorMsg := "unknown error"
-		if errStr, exists := result["error"].(string); exists {
-			errorMsg = errStr
-		}
-		return fmt.Errorf("Slack API error: %s", errorMsg)
+// Channel and target of the ramp in progress, if any
+func (sc *speedControl) rampTarget() (string, float64, bool) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	if sc.ramp == nil {
+		return "", 0, false
 	}
+	return sc.ramp.channel, sc.ramp.target, true
+}
 
-	return nil
+// Get the default speed and a copy of the per-channel overrides
+func (sc *speedControl) snapshot() (float64, map[string]float64) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.speed, maps.Clone(sc.overrides)
+}
+
+// Get a channel that is closed on the next playback speed change
+func (sc *speedControl) changes() <-chan struct{} {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.changed
+}
+
+// Get current playback speed of a primary incident channel, or the default for ""
+func getPlaybackSpeed(channel string) float64 {
+	return playback.get(channel)
+}
+
+// Send a machine-parseable lifecycle event, distinct from content events
+func sendSystemEvent(w http.ResponseWriter, flusher http.Flusher, eventType, channel string) {
+	payload, err := json.Marshal(map[string]string{"type": eventType, "channel": channel})
+	if err != nil {
+		slog.Warn("⚠️  Failed to marshal system event", "err", err)
+		return
+	}
+	fmt.Fprintf(w, "event: system\ndata: %s\n\n", payload)
+	flusher.Flush()
 }
 
 // Handler for incident/metrics stream
 func incidentStreamHandler(w http.ResponseWriter, r *http.Request) {
-	// Set headers for SSE
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	streamChannel(w, r, primaryIncident(), "metrics", "System Metrics")
+}
 
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
-		return
+// Handler for team communication stream
+func teamStreamHandler(w http.ResponseWriter, r *http.Request) {
+	streamChannel(w, r, primaryIncident(), "team", "Team Communication")
+}
+
+// Handler for zoom bridge stream
+func zoomStreamHandler(w http.ResponseWriter, r *http.Request) {
+	streamChannel(w, r, primaryIncident(), "zoom", "Zoom Bridge")
+}
+
+// Parse the comma-separated channels a combined stream asked for, in the
+// order given, rejecting any the transcript doesn't have
+func parseStreamChannels(value string, t *IncidentTranscript) ([]string, error) {
+	known := make(map[string]bool)
+	for _, event := range t.Events {
+		known[event.Channel] = true
 	}
 
-	log.Printf("Client connected to metrics stream: %s", r.RemoteAddr)
+	var channels []string
+	for _, channel := range strings.Split(value, ",") {
+		channel = strings.TrimSpace(channel)
+		if channel == "" || slices.Contains(channels, channel) {
+			continue
+		}
+		if !known[channel] {
+			return nil, fmt.Errorf("unknown channel %q", channel)
+		}
+		channels = append(channels, channel)
+	}
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("missing channels parameter, e.g. ?channels=metrics,team")
+	}
+	return channels, nil
+}
 
-	// Send initial connection message
-	fmt.Fprintf(w, "data: 🔗 Connected to System Metrics stream\n\n")
-	fmt.Fprintf(w, "data: 📋 Incident: %s\n\n", transcript.Incident.Title)
-	flusher.Flush()
+// Handler for several channels interleaved on one stream, e.g.
+// /stream?channels=metrics,team
+func channelsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	inc := primaryIncident()
+	channels, err := parseStreamChannels(r.URL.Query().Get("channels"), inc.Transcript)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	streamChannels(w, r, inc, channels, "combined")
+}
+
+// Report a control endpoint failure as a JSON body, {"error": msg}, so API
+// clients can handle errors the same way as successes
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
 
-	// Context for detecting client disconnect
-	ctx := r.Context()
+// Handler for speed control
+func speedHandler(w http.ResponseWriter, r *http.Request) {
+	serveSpeed(w, r, playback)
+}
 
-	// Replay events
-	startTime := time.Now()
-	eventIndex := 0
-	metricsEvents := make([]Event, 0)
+// Get or set an incident's playback speed
+func serveSpeed(w http.ResponseWriter, r *http.Request, sc *speedControl) {
 
-	// Filter events for metrics channel
-	for _, event := range transcript.Events {
-		if event.Channel == "metrics" {
-			metricsEvents = append(metricsEvents, event)
+	if r.Method == http.MethodGet {
+		// Return the default speed and any per-channel overrides, and where
+		// a ramp in progress is heading
+		speed, channels := sc.snapshot()
+		response := map[string]interface{}{"speed": speed, "channels": channels}
+		if channel, target, ok := sc.rampTarget(); ok {
+			response["ramp"] = map[string]interface{}{"channel": channel, "target": target}
 		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
 	}
 
-	for eventIndex < len(metricsEvents) {
-		select {
-		case <-ctx.Done():
-			log.Printf("Client disconnected from metrics stream: %s", r.RemoteAddr)
+	if r.Method == http.MethodPost {
+		// Set new speed, given as speed or, for ramps, as target
+		query := r.URL.Query()
+		speedStr := query.Get("speed")
+		if speedStr == "" {
+			speedStr = query.Get("target")
+		}
+		if speedStr == "" {
+			writeJSONError(w, http.StatusBadRequest, "Missing speed parameter")
 			return
-		default:
-			event := metricsEvents[eventIndex]
-
-			// Calculate when this event should fire based on playback speed
-			speed := getPlaybackSpeed()
-			targetTime := startTime.Add(time.Duration(float64(event.TimeOffset)*1000/speed) * time.Millisecond)
-
-			// Wait until it's time for this event
-			waitDuration := time.Until(targetTime)
-			if waitDuration > 0 {
-				timer := time.NewTimer(waitDuration)
-				select {
-				case <-ctx.Done():
-					timer.Stop()
-					log.Printf("Client disconnected from metrics stream: %s", r.RemoteAddr)
-					return
-				case <-timer.C:
-					// Time to send the event
-				}
-			}
+		}
 
-			// Format and send the event
-			timestamp := time.Now().Format("15:04:05")
-			fmt.Fprintf(w, "data: [%s] %s\n\n", timestamp, event.Message)
-			flusher.Flush()
-			// Log to console
-			log.Printf("[METRICS] %s", event.Message)
+		speed, err := strconv.ParseFloat(speedStr, 64)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid speed value")
+			return
+		}
 
-			eventIndex++
+		var ramp time.Duration
+		if rampStr := query.Get("ramp"); rampStr != "" {
+			seconds, err := strconv.ParseFloat(rampStr, 64)
+			if err != nil || seconds <= 0 {
+				writeJSONError(w, http.StatusBadRequest, "Invalid ramp value, expected seconds above 0")
+				return
+			}
+			ramp = time.Duration(seconds * float64(time.Second))
 		}
-	}
 
-	// Send completion message
-	fmt.Fprintf(w, "data: ✅ Incident replay completed\n\n")
-	flusher.Flush()
-	log.Printf("✅ Metrics stream replay completed")
+		// Without a channel the speed becomes the default for every channel
+		// that has no override of its own
+		channel := query.Get("channel")
+		var message string
+		switch {
+		case ramp > 0 && channel != "":
+			sc.rampTo(channel, speed, ramp)
+			message = fmt.Sprintf("Ramping speed of channel %s to %.1fx over %s", channel, clampSpeed(speed), ramp)
+		case ramp > 0:
+			sc.rampTo(channel, speed, ramp)
+			message = fmt.Sprintf("Ramping speed to %.1fx over %s", clampSpeed(speed), ramp)
+		case channel != "":
+			sc.set(channel, speed)
+			message = fmt.Sprintf("Speed of channel %s set to %.1fx", channel, speed)
+		default:
+			sc.set(channel, speed)
+			message = fmt.Sprintf("Speed set to %.1fx", speed)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok", "message": message})
+		return
+	}
 
-	// Keep connection open
-	<-ctx.Done()
-	log.Printf("Client disconnected from metrics stream: %s", r.RemoteAddr)
+	writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 }
 
-// Handler for team communication stream
-func teamStreamHandler(w http.ResponseWriter, r *http.Request) {
-	// Set headers for SSE
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+// Handler for jumping the replay to a time offset
+func seekHandler(w http.ResponseWriter, r *http.Request) {
 
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	log.Printf("Client connected to team stream: %s", r.RemoteAddr)
+	offsetStr := r.URL.Query().Get("offset")
+	if offsetStr == "" {
+		writeJSONError(w, http.StatusBadRequest, "Missing offset parameter")
+		return
+	}
 
-	// Send initial connection message
-	fmt.Fprintf(w, "data: 🔗 Connected to Team Communication stream\n\n")
-	fmt.Fprintf(w, "data: 📋 Incident: %s\n\n", transcript.Incident.Title)
-	flusher.Flush()
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid offset value")
+		return
+	}
+	inc := primaryIncident()
+	if offset < 0 || offset > inc.Transcript.Incident.DurationSeconds {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Offset must be between 0 and %d", inc.Transcript.Incident.DurationSeconds))
+		return
+	}
 
-	// Context for detecting client disconnect
-	ctx := r.Context()
+	inc.Replay.seek(offset)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok", "message": fmt.Sprintf("Seeked to T+%ds", offset)})
+}
+
+// Handler for starting the replay over from the beginning
+func restartHandler(w http.ResponseWriter, r *http.Request) {
 
-	// Replay events
-	startTime := time.Now()
-	eventIndex := 0
-	teamEvents := make([]Event, 0)
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
 
-	// Filter events for team channel
-	for _, event := range transcript.Events {
-		if event.Channel == "team" {
-			teamEvents = append(teamEvents, event)
+	// The restarted run publishes to Slack again unless told not to
+	republish := true
+	if value := r.URL.Query().Get("slack"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid slack value")
+			return
 		}
+		republish = parsed
 	}
 
-	for eventIndex < len(teamEvents) {
-		select {
-		case <-ctx.Done():
-			log.Printf("Client disconnected from team stream: %s", r.RemoteAddr)
+	// With a client id, only that client's streams start over, each on its
+	// private timeline, which never publishes
+	if client := r.URL.Query().Get("client"); client != "" {
+		replays := primaryIncident().Replay.clientReplays(client)
+		if len(replays) == 0 {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("No streams open for client %s", client))
 			return
-		default:
-			event := teamEvents[eventIndex]
-
-			// Calculate when this event should fire based on playback speed
-			speed := getPlaybackSpeed()
-			targetTime := startTime.Add(time.Duration(float64(event.TimeOffset)*1000/speed) * time.Millisecond)
-
-			// Wait until it's time for this event
-			waitDuration := time.Until(targetTime)
-			if waitDuration > 0 {
-				timer := time.NewTimer(waitDuration)
-				select {
-				case <-ctx.Done():
-					timer.Stop()
-					log.Printf("Client disconnected from team stream: %s", r.RemoteAddr)
-					return
-				case <-timer.C:
-					// Time to send the event
-				}
-			}
+		}
+		restarted := 0
+		for _, rp := range replays {
+			restarted += rp.rewind(false)
+		}
+		slog.Info("⏮️  Restarted client replay on request", "client", client, "streams", restarted)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "message": fmt.Sprintf("Replay restarted for client %s", client), "streams": restarted})
+		return
+	}
 
-			// Publish to Slack
-			err := publishToSlack(event.Message)
-			if err != nil {
-				log.Printf("⚠️  Failed to publish to Slack: %v", err)
-			} else {
-				log.Printf("Published to Slack: %s", event.Message)
-			}
+	restarted := primaryIncident().Replay.rewind(republish)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "message": "Replay restarted", "streams": restarted})
+}
 
-			// Format and send the event to HTTP stream
-			timestamp := time.Now().Format("15:04:05")
-			fmt.Fprintf(w, "data: [%s] %s\n\n", timestamp, event.Message)
-			flusher.Flush()
+// Handler for freezing the replay clock, e.g. to discuss what just happened
+func pauseHandler(w http.ResponseWriter, r *http.Request) {
 
-			eventIndex++
-		}
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
 	}
 
-	// Send completion message
-	fmt.Fprintf(w, "data: ✅ Incident replay completed\n\n")
-	flusher.Flush()
-	log.Printf("✅ Team stream replay completed")
-
-	// Keep connection open
-	<-ctx.Done()
-	log.Printf("Client disconnected from team stream: %s", r.RemoteAddr)
+	if !primaryIncident().Replay.pause(true) {
+		writeJSONError(w, http.StatusConflict, "Replay is already paused")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok", "message": "Replay paused"})
 }
 
-// Handler for zoom bridge stream
-func zoomStreamHandler(w http.ResponseWriter, r *http.Request) {
-	// Set headers for SSE
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+// Handler for starting a paused replay clock again where it stopped
+func resumeHandler(w http.ResponseWriter, r *http.Request) {
 
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	log.Printf("Client connected to zoom stream: %s", r.RemoteAddr)
-
-	// Send initial connection message
-	fmt.Fprintf(w, "data: 🔗 Connected to Zoom Bridge stream\n\n")
-	fmt.Fprintf(w, "data: 📋 Incident: %s\n\n", transcript.Incident.Title)
-	flusher.Flush()
+	if !primaryIncident().Replay.pause(false) {
+		writeJSONError(w, http.StatusConflict, "Replay is not paused")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok", "message": "Replay resumed"})
+}
 
-	// Context for detecting client disconnect
-	ctx := r.Context()
+// Handler for releasing the next event of a channel in step mode
+func advanceHandler(w http.ResponseWriter, r *http.Request) {
 
-	// Replay events
-	startTime := time.Now()
-	eventIndex := 0
-	zoomEvents := make([]Event, 0)
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
 
-	// Filter events for zoom channel
-	for _, event := range transcript.Events {
-		if event.Channel == "zoom" {
-			zoomEvents = append(zoomEvents, event)
-		}
+	if !stepMode {
+		writeJSONError(w, http.StatusConflict, "Step mode is not enabled")
+		return
+	}
+	channel := r.URL.Query().Get("channel")
+	if channel == "" {
+		writeJSONError(w, http.StatusBadRequest, "Missing channel parameter")
+		return
 	}
 
-	for eventIndex < len(zoomEvents) {
-		select {
-		case <-ctx.Done():
-			log.Printf("Client disconnected from zoom stream: %s", r.RemoteAddr)
+	// With a client id, only that client's streams move on, each on its
+	// private timeline
+	if client := r.URL.Query().Get("client"); client != "" {
+		replays := primaryIncident().Replay.clientReplays(client)
+		if len(replays) == 0 {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("No streams open for client %s", client))
 			return
-		default:
-			event := zoomEvents[eventIndex]
-
-			// Calculate when this event should fire based on playback speed
-			speed := getPlaybackSpeed()
-			targetTime := startTime.Add(time.Duration(float64(event.TimeOffset)*1000/speed) * time.Millisecond)
-
-			// Wait until it's time for this event
-			waitDuration := time.Until(targetTime)
-			if waitDuration > 0 {
-				timer := time.NewTimer(waitDuration)
-				select {
-				case <-ctx.Done():
-					timer.Stop()
-					log.Printf("Client disconnected from zoom stream: %s", r.RemoteAddr)
-					return
-				case <-timer.C:
-					// Time to send the event
-				}
+		}
+		var event Event
+		advanced := 0
+		for _, rp := range replays {
+			if next, ok := rp.advance(channel); ok {
+				event = next
+				advanced++
 			}
+		}
+		if advanced == 0 {
+			writeJSONError(w, http.StatusConflict, fmt.Sprintf("No pending events on channel %s for client %s", channel, client))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok", "message": fmt.Sprintf("Advanced %s to T+%ds for client %s", channel, event.TimeOffset, client)})
+		return
+	}
+
+	event, ok := primaryIncident().Replay.advance(channel)
+	if !ok {
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("No pending events on channel %s", channel))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok", "message": fmt.Sprintf("Advanced %s to T+%ds", channel, event.TimeOffset)})
+}
 
-			// Format and send the event
-			timestamp := time.Now().Format("15:04:05")
-			fmt.Fprintf(w, "data: [%s] %s\n\n", timestamp, event.Message)
-			flusher.Flush()
+// Body accepted by the inject endpoint
+type injectRequest struct {
+	Channel    string `json:"channel"`
+	Message    string `json:"message"`
+	TimeOffset *int   `json:"time_offset,omitempty"` // defaults to the current virtual time
+	Immediate  bool   `json:"immediate"`             // fire now, ignoring time_offset
+}
 
-			// Log to console
-			log.Printf("[ZOOM] %s", event.Message)
+// Handler for injecting live events into the running replay
+func injectHandler(w http.ResponseWriter, r *http.Request) {
 
-			eventIndex++
-		}
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
 	}
 
-	// Send completion message
-	fmt.Fprintf(w, "data: ✅ Incident replay completed\n\n")
-	flusher.Flush()
-	log.Printf("✅ Zoom stream replay completed")
+	var req injectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid event body")
+		return
+	}
+	if strings.TrimSpace(req.Channel) == "" || strings.TrimSpace(req.Message) == "" {
+		writeJSONError(w, http.StatusBadRequest, "Event needs a channel and a message")
+		return
+	}
 
-	// Keep connection open
-	<-ctx.Done()
-	log.Printf("Client disconnected from zoom stream: %s", r.RemoteAddr)
+	offset := -1
+	if req.TimeOffset != nil && !req.Immediate {
+		offset = *req.TimeOffset
+	}
+	assigned := primaryIncident().Replay.inject(Event{Channel: req.Channel, Message: req.Message}, offset)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "time_offset": assigned})
 }
 
-// Handler for speed control
-func speedHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+// Handler for replay progress and status
+func statusHandler(w http.ResponseWriter, r *http.Request) {
 
-	if r.Method == http.MethodGet {
-		// Return current speed
-		speed := getPlaybackSpeed()
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]float64{"speed": speed})
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	if r.Method == http.MethodPost {
-		// Set new speed
-		speedStr := r.URL.Query().Get("speed")
-		if speedStr == "" {
-			http.Error(w, "Missing speed parameter", http.StatusBadRequest)
-			return
-		}
+	w.Header().Set("Content-Type", "application/json")
+	inc := primaryIncident()
+	json.NewEncoder(w).Encode(inc.Replay.status(inc.Transcript.Incident))
+}
 
-		speed, err := strconv.ParseFloat(speedStr, 64)
-		if err != nil {
-			http.Error(w, "Invalid speed value", http.StatusBadRequest)
-			return
-		}
+// Liveness probe: the process is up and serving
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, "ok")
+}
 
-		setPlaybackSpeed(speed)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "ok", "message": fmt.Sprintf("Speed set to %.1fx", speed)})
+// Readiness probe: a non-empty transcript is loaded
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if t := primaryIncident().Transcript; t == nil || len(t.Events) == 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, "not ready")
 		return
 	}
-
-	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	fmt.Fprint(w, "ok")
 }
 
 // Handler for the web interface
 func indexHandler(w http.ResponseWriter, r *http.Request) {
-	// load HTML template from index.html
-	html, err := os.ReadFile("index.html")
-	if html == nil || err != nil {
-		http.Error(w, "Failed to load index.html", http.StatusInternalServerError)
-		return
+	// Serve the embedded page unless a customized one was supplied on disk
+	html, etag := embeddedIndex, embeddedIndexETag()
+	if indexFile != "" {
+		var err error
+		html, err = os.ReadFile(indexFile)
+		if err != nil {
+			http.Error(w, "Failed to load "+indexFile, http.StatusInternalServerError)
+			return
+		}
+		etag = contentETag(html)
 	}
+
+	// Browsers revalidate on every load, getting a 304 while the page is unchanged
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	fmt.Fprint(w, string(html))
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, "index.html", time.Time{}, bytes.NewReader(html))
+}
+
+// Strong ETag for a static response body
+func contentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// The embedded page never changes, so its ETag is computed once
+var embeddedIndexETag = sync.OnceValue(func() string {
+	return contentETag(embeddedIndex)
+})
+
+// SSE routes of the channels served on the top level
+var channelStreamPaths = map[string]string{
+	"metrics": "/stream/incidents",
+	"team":    "/stream/team",
+	"zoom":    "/stream/zoom",
+}
+
+// A transcript channel as the web UI should render it
+type uiChannel struct {
+	Name      string `json:"name"`
+	Events    int    `json:"events"`
+	Stream    string `json:"stream,omitempty"` // SSE route, for channels that have one
+	WebSocket string `json:"websocket"`
+}
+
+// Settings the web UI renders from instead of assuming three fixed channels
+type uiConfig struct {
+	Title        string      `json:"title"`
+	Channels     []uiChannel `json:"channels"` // in order of first appearance in the transcript
+	Speed        float64     `json:"speed"`
+	SlackEnabled bool        `json:"slack_enabled"`
+}
+
+// Build the web UI config for a transcript
+func newUIConfig(t *IncidentTranscript) uiConfig {
+	config := uiConfig{
+		Title:        t.Incident.Title,
+		Channels:     []uiChannel{},
+		Speed:        getPlaybackSpeed(""),
+		SlackEnabled: chatNotifier != nil && chatNotifier.Name() == "slack",
+	}
+	index := make(map[string]int)
+	for _, event := range t.Events {
+		i, ok := index[event.Channel]
+		if !ok {
+			i = len(config.Channels)
+			index[event.Channel] = i
+			config.Channels = append(config.Channels, uiChannel{
+				Name:      event.Channel,
+				Stream:    channelStreamPaths[event.Channel],
+				WebSocket: "/ws/" + event.Channel,
+			})
+		}
+		config.Channels[i].Events++
+	}
+	return config
+}
+
+// Handler for the web UI config of the primary incident
+func configHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(newUIConfig(primaryIncident().Transcript))
+}
+
+// Build the HTTP handler serving every route, from the state main has
+// already loaded, so the server can also be started in-process
+func newServer() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", indexHandler)
+	mux.HandleFunc("/stream/incidents", requireViewer(requireTranscript(incidentStreamHandler)))
+	mux.HandleFunc("/stream/team", requireViewer(requireTranscript(teamStreamHandler)))
+	mux.HandleFunc("/stream/zoom", requireViewer(requireTranscript(zoomStreamHandler)))
+	mux.HandleFunc("/stream", requireViewer(requireTranscript(channelsStreamHandler)))
+	mux.HandleFunc("/ws/{channel}", requireViewer(requireTranscript(wsStreamHandler)))
+	mux.HandleFunc("/speed", requireAdmin(speedHandler))
+	mux.HandleFunc("/status", requireTranscript(statusHandler))
+	mux.HandleFunc("/incident", requireTranscript(incidentInfoHandler))
+	mux.HandleFunc("/config", requireTranscript(configHandler))
+	mux.HandleFunc("/transcripts", requireAdmin(requireTranscript(transcriptsHandler)))
+	mux.HandleFunc("/transcript", requireAdmin(requireTranscript(switchTranscriptHandler)))
+	mux.HandleFunc("/seek", requireAdmin(requireTranscript(seekHandler)))
+	mux.HandleFunc("/inject", requireAdmin(requireTranscript(injectHandler)))
+	mux.HandleFunc("/restart", requireAdmin(requireTranscript(restartHandler)))
+	mux.HandleFunc("/advance", requireAdmin(requireTranscript(advanceHandler)))
+	mux.HandleFunc("/pause", requireAdmin(requireTranscript(pauseHandler)))
+	mux.HandleFunc("/resume", requireAdmin(requireTranscript(resumeHandler)))
+	mux.HandleFunc("/slack/command", requireTranscript(slackCommandHandler))
+	mux.HandleFunc("/annotate", requireAdmin(requireTranscript(annotateHandler)))
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/incidents", incidentsHandler)
+	mux.HandleFunc("/incidents/{id}/stream/{channel}", requireViewer(incidentChannelStreamHandler))
+	mux.HandleFunc("/incidents/{id}/status", incidentStatusHandler)
+	mux.HandleFunc("/incidents/{id}/speed", requireAdmin(incidentSpeedHandler))
+	mux.HandleFunc("/search", requireTranscript(searchHandler))
+	mux.HandleFunc("/timeline", requireTranscript(timelineHandler))
+	mux.HandleFunc("/activity", requireTranscript(activityHandler))
+	mux.HandleFunc("/summary", requireTranscript(summaryHandler))
+	mux.HandleFunc("/export", requireTranscript(exportHandler))
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/readyz", readyzHandler)
+	return withCORS(mux)
 }
 
 func main() {
-	// Load Slack bot token from environment
-	slackBotToken = os.Getenv("SLACK_BOT_TOKEN")
-	if slackBotToken == "" {
-		log.Printf("⚠️  SLACK_BOT_TOKEN not set - Slack publishing will be disabled")
+	flag.BoolVar(&loopReplay, "loop", envBool("REPLAY_LOOP"), "loop the incident replay continuously (kiosk/demo mode)")
+	flag.BoolVar(&stepMode, "step", envBool("REPLAY_STEP"), "step-through mode: each event waits for POST /advance?channel=<channel> instead of its time offset; streams opened with ?client=<id> wait for /advance?client=<id>&channel=<channel>")
+	flag.BoolVar(&loopSlack, "loop-slack", envBool("REPLAY_LOOP_SLACK"), "publish to Slack on every loop instead of only the first")
+	flag.StringVar(&transcriptFile, "transcript", "", "path or http(s) URL of a JSON or YAML transcript overriding the embedded default")
+	merge := flag.String("merge", os.Getenv("REPLAY_MERGE"), "comma-separated transcript files or URLs to replay together as one incident, instead of -transcript")
+	flag.BoolVar(&mergeNamespace, "merge-namespace", envBool("REPLAY_MERGE_NAMESPACE"), "prefix merged channels with their source file's name, e.g. db:team from db.json")
+	flag.StringVar(&indexFile, "index", "", "path to an index.html overriding the embedded web UI")
+	vars := flag.String("vars", os.Getenv("REPLAY_VARS"), "transcript template variables as comma-separated key=value pairs, e.g. Service=checkout,Region=us-east-1")
+	flag.BoolVar(&strictVars, "strict-vars", envBool("REPLAY_STRICT_VARS"), "fail to load the transcript if a template references an undefined variable")
+	stamp := flag.Bool("stamp-title", envBool("REPLAY_STAMP_TITLE"), "rewrite the incident title with today's date using -title-format")
+	titleFormat := flag.String("title-format", defaultTitleFormat, "stamped title format; may reference {{.Title}} and {{.Date}}")
+	redactPresets := flag.String("redact", os.Getenv("REPLAY_REDACT"), "comma-separated built-in patterns hidden as [REDACTED] wherever events are emitted: email, ip, aws-key")
+	redactFile := flag.String("redact-file", os.Getenv("REPLAY_REDACT_FILE"), "file of custom regular expressions to redact, one per line")
+	flag.BoolVar(&inferSpeakers, "infer-speakers", envBool("REPLAY_INFER_SPEAKERS"), "give events without a speaker the name in a leading [Name] of the message, outside the metrics channel")
+	flag.BoolVar(&inferLevels, "infer-levels", envBool("REPLAY_INFER_LEVELS"), "give events without a level one inferred from ERROR or WARN message prefixes")
+	flag.Float64Var(&timeScale, "time-scale", timeScale, "multiply every transcript offset and the duration by this factor at load, e.g. 0.5 to halve the timeline")
+	loadAttempts := flag.Int("load-attempts", 1, "startup tries at loading the transcript before giving up, for sources such as a sidecar that may not be ready yet")
+	loadBackoff := flag.Duration("load-backoff", time.Second, "wait before the first startup load retry, doubling after each one up to 30s")
+	flag.DurationVar(&transcriptHTTPClient.Timeout, "load-timeout", transcriptFetchTimeout, "time allowed for each fetch of a transcript URL, body included")
+	validateOnly := flag.Bool("validate", false, "load and validate the transcript, print a report of its channels and exit without serving")
+	mode := flag.String("mode", "serve", "serve to replay the transcript, record to capture a Slack channel into one, import to convert a log file into one, or fmt to sort and tidy the -transcript file in place")
+	dryRun := flag.Bool("dry-run", false, "fmt mode: print the formatted transcript to stdout instead of rewriting the file")
+	recordChannel := flag.String("record-channel", slackChannelID, "record mode: Slack channel ID to capture")
+	recordFrom := flag.String("record-from", "", "record mode: RFC3339 start of the window (default one hour before -record-to)")
+	recordTo := flag.String("record-to", "", "record mode: RFC3339 end of the window (default now)")
+	recordAs := flag.String("record-as", "team", "record mode: transcript channel for the captured messages")
+	recordOut := flag.String("record-out", "recorded_transcript.json", "record mode: output transcript path")
+	var importOpts logImportOptions
+	flag.StringVar(&importOpts.In, "import-in", "", "import mode: application log file to convert")
+	flag.StringVar(&importOpts.Out, "import-out", "imported_transcript.json", "import mode: output transcript path")
+	flag.StringVar(&importOpts.Pattern, "import-pattern", defaultImportPattern, "import mode: regexp with named groups ts and msg, and optionally level")
+	flag.StringVar(&importOpts.TimeLayout, "import-time-layout", time.RFC3339, "import mode: Go time layout of the ts group")
+	flag.StringVar(&importOpts.Channel, "import-channel", "team", "import mode: transcript channel for imported lines")
+	flag.StringVar(&importOpts.LevelChannels, "import-level-channels", "", "import mode: route log levels to channels, e.g. error=metrics,warn=metrics")
+	flag.StringVar(&viewerToken, "viewer-token", os.Getenv("VIEWER_TOKEN"), "token required to watch streams, as a bearer token, basic-auth password or ?token= (default streams are open)")
+	corsOriginList := flag.String("cors-origins", os.Getenv("CORS_ORIGINS"), "comma-separated origins allowed to call the API cross-origin (default any)")
+	timeZone := flag.String("tz", os.Getenv("REPLAY_TZ"), "IANA time zone for event timestamps, e.g. America/New_York (default server local time)")
+	flag.StringVar(&timestampLayout, "time-format", timestampLayout, "Go time layout for wall-clock event timestamps")
+	flag.StringVar(&timestampMode, "timestamps", timestampMode, "event timestamps to show: wall, offset (virtual T+HH:MM:SS) or both")
+	flag.BoolVar(&aiCommander, "ai-commander", envBool("AI_COMMANDER"), "inject LLM-generated commander responses to team messages (needs LLM_API_KEY)")
+	flag.DurationVar(&aiCommanderInterval, "ai-commander-interval", 5*time.Second, "minimum time between AI commander LLM calls")
+	pagerDutyCritical := flag.String("pagerduty-critical", defaultPagerDutyCritical, "regexp for metrics messages that trigger a PagerDuty incident")
+	pagerDutyRecovered := flag.String("pagerduty-recovered", defaultPagerDutyRecovered, "regexp for metrics messages that resolve the PagerDuty incident")
+	lifecycleWebhooks := flag.String("lifecycle-webhooks", os.Getenv("LIFECYCLE_WEBHOOKS"), "comma-separated URLs POSTed when a replay starts, a channel completes and the replay completes")
+	webhooksFile := flag.String("webhooks-file", "", "JSON file mapping transcript channels to webhook URLs (default WEBHOOK_MAP env JSON)")
+	transcriptsDir := flag.String("transcripts-dir", os.Getenv("TRANSCRIPTS_DIR"), "directory of transcripts to replay as independent incidents under /incidents/{id}")
+	notify := flag.String("notify", "auto", "chat backend for replayed events: auto, slack, teams, discord or none")
+	publishList := flag.String("publish-channels", os.Getenv("PUBLISH_CHANNELS"), "comma-separated transcript channels published to chat, e.g. team,metrics; ones without a Slack mapping post to the team channel (default the backend's mapping, just team)")
+	teamsChannels := flag.String("teams-channels", "team", "comma-separated transcript channels published to Teams")
+	discordChannels := flag.String("discord-channels", "team", "comma-separated transcript channels published to Discord")
+	chaosMode := flag.Bool("chaos", envBool("REPLAY_CHAOS"), "simulate a lossy, laggy feed by randomly dropping and delaying events")
+	var chaosOpts chaosConfig
+	flag.Float64Var(&chaosOpts.DropRate, "chaos-drop", 0.1, "chaos mode: probability that an event is dropped")
+	flag.DurationVar(&chaosOpts.Jitter, "chaos-jitter", 2*time.Second, "chaos mode: maximum random extra delay before an event")
+	flag.Int64Var(&chaosOpts.Seed, "chaos-seed", 1, "chaos mode: random seed, so a scenario replays identically")
+	gapWindowText := flag.String("telemetry-gap", os.Getenv("REPLAY_TELEMETRY_GAP"), "withhold metrics events from streams between two incident offsets in seconds, e.g. 300-600, simulating a monitoring outage")
+	humanizeMode := flag.Bool("humanize", envBool("REPLAY_HUMANIZE"), "delay each event by a small random fraction of the gap to its channel's next event, so messages don't arrive like clockwork")
+	var humanizeOpts humanizeConfig
+	flag.Float64Var(&humanizeOpts.Jitter, "humanize-jitter", 0.2, "humanize mode: largest delay as a fraction of the gap to the next event, below 1")
+	flag.Int64Var(&humanizeOpts.Seed, "humanize-seed", 1, "humanize mode: random seed, so a run's timing can be reproduced")
+	startAt := flag.String("start-at", os.Getenv("REPLAY_START_AT"), "RFC3339 wall-clock time at which the replay begins for everyone (default when the first viewer connects)")
+	slackThread := flag.String("slack-thread", slackThreadOff, "thread replayed Slack messages under a root incident message: off, new (a new thread per replay run) or reuse (one thread across runs)")
+	slackTokenFile := flag.String("slack-token-file", os.Getenv("SLACK_TOKEN_FILE"), "file holding the Slack bot token, re-read when Slack rejects the current one (default SLACK_BOT_TOKEN env)")
+	slackOversize := flag.String("slack-oversize", "split", "Slack messages over the length limit: split into several posts or truncate")
+	slackMinInterval := flag.Duration("slack-min-interval", 0, "minimum time between Slack posts, e.g. 1s, pacing fast playback under Slack's rate limits (default no pacing)")
+	slackDigestInterval := flag.Duration("slack-digest-interval", 0, "post one Slack digest per this much incident time, e.g. 5m, with each channel's event count and notable lines, instead of every event (default post every event)")
+	slackRatePolicy := flag.String("slack-rate-policy", slackPaceQueue, "events arriving faster than -slack-min-interval: queue them, drop them, or coalesce a channel's queued text messages into one post")
+	maxClients := flag.Int("max-clients", 0, "maximum concurrent stream connections across SSE and WebSocket; more get 503 with Retry-After (default no limit)")
+	flag.DurationVar(&completionGrace, "completion-grace", completionGrace, "how long streams opened with ?oncomplete=grace stay open after completing before they close")
+	flag.DurationVar(&maxStreamDuration, "max-stream-duration", 0, "close stream connections after this long, e.g. 8h (default no limit)")
+	flag.DurationVar(&maxQuietWait, "max-quiet", 0, "longest wait between events, e.g. 30s; longer quiet stretches are skipped with a marker (default wait out every gap)")
+	flag.DurationVar(&streamWriteTimeout, "stream-write-timeout", streamWriteTimeout, "drop an SSE client when one write or flush blocks this long, e.g. a client that stopped reading")
+	flag.DurationVar(&progressInterval, "progress-interval", progressInterval, "incident time between progress frames on JSON and WebSocket streams, e.g. 10s; 0 sends none")
+	flag.DurationVar(&batchInterval, "batch-interval", 0, "coalesce SSE events written within this interval into one flush, e.g. 50ms (default flush every event)")
+	logLevel := flag.String("log-level", "info", "minimum log level: debug, info, warn or error (LOG_FORMAT=json for JSON logs)")
+	flag.Parse()
+
+	if err := setupLogging(*logLevel); err != nil {
+		fatal("❌ Invalid logging configuration", "err", err)
+	}
+
+	if maxQuietWait < 0 {
+		fatal("❌ Invalid -max-quiet, must not be negative", "max_quiet", maxQuietWait)
+	}
+	if completionGrace <= 0 {
+		fatal("❌ Invalid -completion-grace, must be positive", "completion_grace", completionGrace)
+	}
+	if progressInterval < 0 {
+		fatal("❌ Invalid -progress-interval, must not be negative", "progress_interval", progressInterval)
+	}
+	if streamWriteTimeout <= 0 {
+		fatal("❌ Invalid -stream-write-timeout, must be positive", "stream_write_timeout", streamWriteTimeout)
+	}
+
+	// Cap concurrent streams so a crowd of viewers can't overload the server
+	if *maxClients < 0 {
+		fatal("❌ Invalid -max-clients, must not be negative", "max_clients", *maxClients)
+	}
+	if *maxClients > 0 {
+		streamSlots = make(chan struct{}, *maxClients)
+	}
+
+	// Optional token protecting the mutating control endpoints
+	adminToken = os.Getenv("ADMIN_TOKEN")
+	if adminToken != "" {
+		slog.Info("🔒 Control endpoints require the admin token")
+	}
+	if viewerToken != "" {
+		slog.Info("🔒 Streams require the viewer token")
+	}
+	slackSigningSecret = os.Getenv("SLACK_SIGNING_SECRET")
+
+	// Values substituted into templated transcript text
+	parsedVars, err := parseTemplateVars(*vars)
+	if err != nil {
+		fatal("❌ Invalid -vars", "err", err)
+	}
+	templateVars = parsedVars
+	if *stamp {
+		titleStamp = *titleFormat
+	}
+
+	if timeScale <= 0 || math.IsInf(timeScale, 0) || math.IsNaN(timeScale) {
+		fatal("❌ Invalid -time-scale, expected a positive factor", "time_scale", timeScale)
+	}
+	if *loadAttempts < 1 {
+		fatal("❌ Invalid -load-attempts, must be at least 1", "load_attempts", *loadAttempts)
+	}
+	if *loadBackoff < 0 {
+		fatal("❌ Invalid -load-backoff, must not be negative", "load_backoff", *loadBackoff)
+	}
+	if transcriptHTTPClient.Timeout <= 0 {
+		fatal("❌ Invalid -load-timeout, must be positive", "load_timeout", transcriptHTTPClient.Timeout)
+	}
+
+	// Render event timestamps consistently for distributed viewers
+	if *timeZone != "" {
+		location, err := time.LoadLocation(*timeZone)
+		if err != nil {
+			fatal("❌ Invalid -tz time zone", "tz", *timeZone, "err", err)
+		}
+		timestampLocation = location
+	}
+	switch timestampMode {
+	case "wall", "offset", "both":
+	default:
+		fatal("❌ Invalid -timestamps mode, expected wall, offset or both", "timestamps", timestampMode)
+	}
+
+	// Coordinated drills start at the same wall-clock moment for everyone
+	if *startAt != "" {
+		replayStartAt, err = time.Parse(time.RFC3339, *startAt)
+		if err != nil {
+			fatal("❌ Invalid -start-at time, expected RFC3339", "start_at", *startAt, "err", err)
+		}
+		slog.Info("⏳ Replay scheduled", "start_at", replayStartAt.Format(time.RFC3339))
+	}
+
+	// Hide secrets and names in production-derived transcripts as they're emitted
+	if *redactPresets != "" || *redactFile != "" {
+		r, err := newRedaction(*redactPresets, *redactFile)
+		if err != nil {
+			fatal("❌ Invalid redaction configuration", "err", err)
+		}
+		redactor = r
+		slog.Info("🕶️  Redacting event text", "patterns", len(r.patterns))
+	}
+
+	// Withhold metrics for a stretch to simulate missing observability
+	if *gapWindowText != "" {
+		window, err := parseGapWindow(*gapWindowText)
+		if err != nil {
+			fatal("❌ Invalid -telemetry-gap", "err", err)
+		}
+		telemetryGap = window
+		slog.Info("📉 Telemetry gap enabled", "channel", telemetryChannel, "start", window.Start, "end", window.End)
+	}
+
+	// Chaos stays off unless asked for, so normal replays are deterministic
+	if *chaosMode {
+		if err := chaosOpts.validate(); err != nil {
+			fatal("❌ Invalid chaos configuration", "err", err)
+		}
+		chaos = &chaosOpts
+		slog.Info("🎲 Chaos mode enabled", "drop_rate", chaos.DropRate, "jitter", chaos.Jitter, "seed", chaos.Seed)
+	}
+	if *humanizeMode {
+		if err := humanizeOpts.validate(); err != nil {
+			fatal("❌ Invalid humanize configuration", "err", err)
+		}
+		humanize = &humanizeOpts
+		slog.Info("🧍 Humanize mode enabled", "jitter", humanize.Jitter, "seed", humanize.Seed)
+	}
+
+	// Replay several transcripts as one coordinated incident
+	mergeSources = parseMergeSources(*merge)
+	if len(mergeSources) > 0 && transcriptFile != "" {
+		fatal("❌ -merge and -transcript are mutually exclusive")
+	}
+
+	// Check transcripts in CI without serving or touching Slack
+	if *validateOnly {
+		if err := runValidate(os.Stdout, *transcriptsDir); err != nil {
+			fatal("❌ Transcript validation failed", "err", err)
+		}
+		return
+	}
+
+	// Tidy a transcript file in place, like gofmt for scenarios
+	if *mode == "fmt" {
+		if err := runFmt(os.Stdout, transcriptFile, *dryRun); err != nil {
+			fatal("❌ Failed to format transcript", "err", err)
+		}
+		return
+	}
+
+	// Restrict cross-origin access when an allowlist is given
+	corsOrigins = parseCORSOrigins(*corsOriginList)
+	if len(corsOrigins) > 0 {
+		slog.Info("🛡️  CORS restricted to allowed origins", "origins", *corsOriginList)
+	}
+
+	// Map transcript channels to Slack channels; by default only team publishes
+	slackChannels := map[string]string{"team": slackChannelID}
+	if channelMap := os.Getenv("SLACK_CHANNEL_MAP"); channelMap != "" {
+		parsed, err := parseSlackChannelMap(channelMap)
+		if err != nil {
+			fatal("❌ Invalid SLACK_CHANNEL_MAP", "err", err)
+		}
+		slackChannels = parsed
+	}
+
+	// Channels chosen for publishing but not mapped to a Slack channel of
+	// their own are mirrored into the team channel
+	if *publishList != "" {
+		publishChannels = parseChannelSet(*publishList)
+		teamChannel, ok := slackChannels["team"]
+		if !ok {
+			teamChannel = slackChannelID
+		}
+		for channel := range publishChannels {
+			if _, mapped := slackChannels[channel]; !mapped {
+				slackChannels[channel] = teamChannel
+			}
+		}
+		slog.Info("📣 Publishing channels to chat", "channels", strings.Join(slices.Sorted(maps.Keys(publishChannels)), ","))
+	}
+
+	// Load Slack bot token from environment, or from a file that an external
+	// rotation mechanism keeps fresh
+	slackClient = NewSlackClient(os.Getenv("SLACK_BOT_TOKEN"), slackChannels)
+	if *slackTokenFile != "" {
+		data, err := os.ReadFile(*slackTokenFile)
+		if err != nil {
+			fatal("❌ Failed to read Slack token file", "path", *slackTokenFile, "err", err)
+		}
+		slackClient.Token = strings.TrimSpace(string(data))
+		slackClient.TokenFile = *slackTokenFile
+	}
+	if !slackClient.Enabled() {
+		slog.Warn("⚠️  SLACK_BOT_TOKEN not set - Slack publishing will be disabled")
 	} else {
-		log.Printf("✅ Slack bot token loaded (length: %d)", len(slackBotToken))
+		slog.Info("✅ Slack bot token loaded", "token_length", len(slackClient.Token))
+	}
+
+	// Plain-text Slack messages for users who prefer them over Block Kit
+	if blockKit, err := strconv.ParseBool(os.Getenv("SLACK_BLOCK_KIT")); err == nil {
+		slackClient.BlockKit = blockKit
+	}
+	switch *slackThread {
+	case slackThreadOff, slackThreadNew, slackThreadReuse:
+		slackClient.ThreadMode = *slackThread
+	default:
+		fatal("❌ Invalid -slack-thread, expected off, new or reuse", "slack_thread", *slackThread)
+	}
+	switch *slackOversize {
+	case "split":
+	case "truncate":
+		slackClient.SplitLong = false
+	default:
+		fatal("❌ Invalid -slack-oversize, expected split or truncate", "slack_oversize", *slackOversize)
+	}
+
+	// Optional PagerDuty paging for fire drills
+	critical, err := regexp.Compile(*pagerDutyCritical)
+	if err != nil {
+		fatal("❌ Invalid -pagerduty-critical pattern", "err", err)
+	}
+	recovered, err := regexp.Compile(*pagerDutyRecovered)
+	if err != nil {
+		fatal("❌ Invalid -pagerduty-recovered pattern", "err", err)
+	}
+	pagerDutyClient = NewPagerDutyClient(os.Getenv("PAGERDUTY_ROUTING_KEY"), critical, recovered)
+	if pagerDutyClient.Enabled() {
+		slog.Info("📟 PagerDuty paging enabled", "channel", pagerDutyClient.Channel)
+	}
+
+	// Optional generic webhooks per channel
+	webhookURLs, err := loadWebhookMap(*webhooksFile)
+	if err != nil {
+		fatal("❌ Invalid webhook configuration", "err", err)
+	}
+	webhookSink = NewWebhookSink(webhookURLs)
+	for channel := range webhookURLs {
+		slog.Info("🪝 Webhook enabled", "channel", channel)
+	}
+
+	// Optional replay-level start and completion signals for orchestration
+	lifecycleURLs, err := parseLifecycleURLs(*lifecycleWebhooks)
+	if err != nil {
+		fatal("❌ Invalid lifecycle webhook configuration", "err", err)
+	}
+	lifecycleHooks = NewLifecycleHooks(lifecycleURLs)
+	if lifecycleHooks != nil {
+		slog.Info("🪝 Lifecycle webhooks enabled", "count", len(lifecycleURLs))
+	}
+
+	// Optional OpenAI-compatible model for the AI features
+	llmClient = NewLLMClientFromEnv()
+	if !llmClient.Enabled() {
+		slog.Info("🧠 LLM_API_KEY not set - AI summary disabled")
+		if aiCommander {
+			slog.Warn("⚠️  -ai-commander needs LLM_API_KEY - commander responses disabled")
+		}
+	} else if aiCommander {
+		slog.Info("🤖 AI commander enabled", "model", llmClient.Model, "min_interval", aiCommanderInterval)
+	}
+
+	switch *mode {
+	case "serve":
+	case "record":
+		if err := runRecord(slackClient, *recordChannel, *recordAs, *recordFrom, *recordTo, *recordOut); err != nil {
+			fatal("❌ Failed to record transcript", "err", err)
+		}
+		return
+	case "import":
+		if err := runImport(importOpts); err != nil {
+			fatal("❌ Failed to import log file", "err", err)
+		}
+		return
+	default:
+		fatal("❌ Unknown mode (expected serve, record, import or fmt)", "mode", *mode)
+	}
+
+	// Teams Incoming Webhook as an alternative chat backend to Slack
+	teamsNotifier := NewTeamsNotifier(os.Getenv("TEAMS_WEBHOOK_URL"), strings.Split(*teamsChannels, ","))
+	for channel := range publishChannels {
+		teamsNotifier.Channels[channel] = true
+	}
+	// Discord webhook for community-run drills
+	discordNotifier := NewDiscordNotifier(os.Getenv("DISCORD_WEBHOOK_URL"), strings.Split(*discordChannels, ","))
+	for channel := range publishChannels {
+		discordNotifier.Channels[channel] = true
+	}
+	chatNotifier, err = selectNotifier(*notify, slackClient, teamsNotifier, discordNotifier)
+	if err != nil {
+		fatal("❌ Invalid chat notifier", "err", err)
+	}
+	// Pace Slack posts so fast playback can't flood the channel
+	if *slackMinInterval < 0 {
+		fatal("❌ Invalid -slack-min-interval, must not be negative", "slack_min_interval", *slackMinInterval)
+	}
+	switch *slackRatePolicy {
+	case slackPaceQueue, slackPaceDrop, slackPaceCoalesce:
+	default:
+		fatal("❌ Invalid -slack-rate-policy, expected queue, drop or coalesce", "slack_rate_policy", *slackRatePolicy)
+	}
+	if *slackDigestInterval < 0 {
+		fatal("❌ Invalid -slack-digest-interval, must not be negative", "slack_digest_interval", *slackDigestInterval)
+	}
+	if *slackDigestInterval > 0 && *slackMinInterval > 0 {
+		fatal("❌ -slack-digest-interval and -slack-min-interval can't be combined, digests already post rarely")
+	}
+	if *slackDigestInterval > 0 {
+		if chatNotifier == notifier(slackClient) {
+			chatNotifier = newSlackDigest(slackClient, *slackDigestInterval)
+			slog.Info("📰 Posting Slack digests instead of every event", "interval", *slackDigestInterval)
+		} else {
+			slog.Warn("⚠️  -slack-digest-interval has no effect without the Slack backend", "notify", *notify)
+		}
+	}
+	if *slackMinInterval > 0 && chatNotifier == notifier(slackClient) {
+		chatNotifier = newSlackPacer(slackClient, *slackMinInterval, *slackRatePolicy)
+		slog.Info("🚦 Pacing Slack posts", "min_interval", *slackMinInterval, "policy", *slackRatePolicy)
+	}
+	if chatNotifier != nil {
+		slog.Info("📣 Publishing replayed events to chat", "notifier", chatNotifier.Name())
 	}
 
 	// Load incident transcript
-	if err := loadTranscript(); err != nil {
-		log.Fatalf("❌ Failed to load transcript: %v", err)
+	if err := loadTranscriptWithRetry(*loadAttempts, *loadBackoff); err != nil {
+		fatal("❌ Failed to load transcript", "err", err, "attempts", *loadAttempts)
+	}
+	slackClient.IncidentTitle = transcript.Incident.Title
+	slackClient.Description = transcript.Incident.Description
+	teamsNotifier.IncidentTitle = transcript.Incident.Title
+	discordNotifier.IncidentTitle = transcript.Incident.Title
+	incidentReplay = newReplay(transcript.Events, playback, true)
+	incidentReplay.title = transcript.Incident.Title
+	defaultTranscript = transcript
+
+	// Independent incidents for parallel training rooms
+	incidentsDir = *transcriptsDir
+	if *transcriptsDir != "" {
+		loaded, err := loadIncidentsDir(*transcriptsDir)
+		if err != nil {
+			fatal("❌ Failed to load transcripts directory", "err", err)
+		}
+		incidents = loaded
 	}
 
-	// Set up routes
-	http.HandleFunc("/", indexHandler)
-	http.HandleFunc("/stream/incidents", incidentStreamHandler)
-	http.HandleFunc("/stream/team", teamStreamHandler)
-	http.HandleFunc("/stream/zoom", zoomStreamHandler)
-	http.HandleFunc("/speed", speedHandler)
+	// Scheduled replays start on time even with no viewers connected
+	if !replayStartAt.IsZero() {
+		go incidentReplay.scheduleStart()
+		for _, inc := range incidents {
+			go inc.Replay.scheduleStart()
+		}
+	}
 
 	// Start server
 	port := ":8081"
-	log.Printf("🚀 Server starting on http://localhost%s", port)
-	log.Printf("📊 Metrics stream: http://localhost%s/stream/incidents", port)
-	log.Printf("💬 Slack stream: http://localhost%s/stream/team", port)
-	log.Printf("📞 Zoom stream: http://localhost%s/stream/zoom", port)
-	log.Printf("⚡ Speed control: http://localhost%s/speed", port)
-	log.Printf("🌐 Web interface: http://localhost%s/", port)
-	log.Printf("📋 Incident: %s", transcript.Incident.Title)
-
-	if err := http.ListenAndServe(port, nil); err != nil {
-		log.Fatal(err)
+	slog.Info("🚀 Server starting", "url", "http://localhost"+port)
+	slog.Info("📊 Metrics stream", "url", "http://localhost"+port+"/stream/incidents")
+	slog.Info("💬 Slack stream", "url", "http://localhost"+port+"/stream/team")
+	slog.Info("📞 Zoom stream", "url", "http://localhost"+port+"/stream/zoom")
+	slog.Info("🧵 Combined stream", "url", "http://localhost"+port+"/stream?channels=metrics,team")
+	slog.Info("🔌 WebSocket streams", "url", "ws://localhost"+port+"/ws/{channel}")
+	slog.Info("⚡ Speed control", "url", "http://localhost"+port+"/speed")
+	slog.Info("📈 Replay status", "url", "http://localhost"+port+"/status")
+	slog.Info("📋 Incident metadata", "url", "http://localhost"+port+"/incident")
+	slog.Info("🧩 UI config", "url", "http://localhost"+port+"/config")
+	slog.Info("🔎 Transcript search", "url", "http://localhost"+port+"/search?q=<text>")
+	slog.Info("🗒️  Timeline preview", "url", "http://localhost"+port+"/timeline?format=json|text")
+	slog.Info("📶 Event activity", "url", "http://localhost"+port+"/activity?bucket=<seconds>")
+	slog.Info("⏩ Seek control", "url", "http://localhost"+port+"/seek?offset=<seconds>")
+	slog.Info("💉 Event injection", "url", "http://localhost"+port+"/inject")
+	slog.Info("⏮️  Restart control", "url", "http://localhost"+port+"/restart")
+	slog.Info("⏸️  Pause control", "url", "http://localhost"+port+"/pause", "resume", "http://localhost"+port+"/resume")
+	if slackSigningSecret != "" {
+		slog.Info("💬 Slack slash command", "url", "http://localhost"+port+"/slack/command")
+	}
+	slog.Info("📌 Annotations", "url", "http://localhost"+port+"/annotate")
+	if len(incidents) > 0 {
+		slog.Info("🏫 Incident rooms", "url", "http://localhost"+port+"/incidents", "count", len(incidents))
+		slog.Info("🔀 Transcript switching", "url", "http://localhost"+port+"/transcripts")
+	}
+	slog.Info("🧠 AI summary", "url", "http://localhost"+port+"/summary")
+	slog.Info("📤 Replay export", "url", "http://localhost"+port+"/export?format=json|csv")
+	slog.Info("📉 Prometheus metrics", "url", "http://localhost"+port+"/metrics")
+	slog.Info("🌐 Web interface", "url", "http://localhost"+port+"/")
+	slog.Info("📋 Incident", "title", transcript.Incident.Title, "speed", getPlaybackSpeed(""))
+	if stepMode {
+		slog.Info("👣 Step mode enabled", "url", "http://localhost"+port+"/advance?channel=<channel>")
+	}
+	if loopReplay {
+		slog.Info("🔁 Loop mode enabled", "slack_every_loop", loopSlack)
+	}
+
+	watchReloadSignal()
+
+	if err := http.ListenAndServe(port, newServer()); err != nil {
+		fatal("❌ Server stopped", "err", err)
 	}
 }
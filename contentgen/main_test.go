@@ -0,0 +1,431 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSpeedOverridePrecedence(t *testing.T) {
+	tests := []struct {
+		name      string
+		posts     []string           // POST /speed queries, in order
+		want      map[string]float64 // effective speed by channel, "" for the default
+		overrides map[string]float64 // per-channel speeds GET /speed reports
+	}{
+		{"default only", []string{"?speed=2"},
+			map[string]float64{"": 2, "team": 2, "metrics": 2}, map[string]float64{}},
+		{"override beats default", []string{"?channel=metrics&speed=1", "?speed=4"},
+			map[string]float64{"": 4, "team": 4, "metrics": 1}, map[string]float64{"metrics": 1}},
+		{"later override replaces earlier", []string{"?channel=team&speed=3", "?channel=team&speed=0.5"},
+			map[string]float64{"": testSpeed, "team": 0.5, "metrics": testSpeed}, map[string]float64{"team": 0.5}},
+		{"overrides are clamped", []string{"?channel=team&speed=50"},
+			map[string]float64{"": testSpeed, "team": 10, "metrics": testSpeed}, map[string]float64{"team": 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startServer(t, fixtureTranscript())
+			for _, query := range tt.posts {
+				expectStatus(t, srv.URL+"/speed"+query, http.StatusOK)
+			}
+
+			for channel, want := range tt.want {
+				if got := getPlaybackSpeed(channel); got != want {
+					t.Errorf("speed of %q is %v, want %v", channel, got, want)
+				}
+			}
+
+			status, body := control(t, http.MethodGet, srv.URL+"/speed", "")
+			if status != http.StatusOK {
+				t.Fatalf("GET /speed: status %d: %s", status, body)
+			}
+			var got struct {
+				Speed    float64            `json:"speed"`
+				Channels map[string]float64 `json:"channels"`
+			}
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatalf("decode %q: %v", body, err)
+			}
+			if got.Speed != tt.want[""] || !reflect.DeepEqual(got.Channels, tt.overrides) {
+				t.Errorf("GET /speed = %s, want speed %v and channels %v", body, tt.want[""], tt.overrides)
+			}
+		})
+	}
+}
+
+func TestTitleStamp(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string // -title-format with -stamp-title, empty without
+		want   string
+		err    string
+	}{
+		{"author's title by default", "", "Checkout outage", ""},
+		{"default stamp", defaultTitleFormat, "Checkout outage - Mar 14, 2024", ""},
+		{"custom format", "[{{.Date}}] {{.Title}} drill", "[Mar 14, 2024] Checkout outage drill", ""},
+		{"unknown field", "{{.Team}} - {{.Title}}", "", "failed to stamp title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeClock(t)
+			previousFile, previousStamp := transcriptFile, titleStamp
+			transcriptFile, titleStamp = "testdata/checkout_outage.json", tt.format
+			t.Cleanup(func() { transcriptFile, titleStamp = previousFile, previousStamp })
+
+			tr, err := prepareTranscript()
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to mention %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("prepare transcript: %v", err)
+			}
+			if tr.Incident.Title != tt.want {
+				t.Errorf("title %q, want %q", tr.Incident.Title, tt.want)
+			}
+		})
+	}
+}
+
+func TestControlErrorsAreJSON(t *testing.T) {
+	srv := startServer(t, fixtureTranscript())
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		error  string // substring of the error message
+	}{
+		{"speed missing", http.MethodPost, "/speed", "", http.StatusBadRequest, "Missing speed parameter"},
+		{"speed not a number", http.MethodPost, "/speed?speed=fast", "", http.StatusBadRequest, "Invalid speed value"},
+		{"speed bad ramp", http.MethodPost, "/speed?target=4&ramp=0", "", http.StatusBadRequest, "Invalid ramp value"},
+		{"speed wrong method", http.MethodDelete, "/speed", "", http.StatusMethodNotAllowed, "Method not allowed"},
+		{"seek missing offset", http.MethodPost, "/seek", "", http.StatusBadRequest, "Missing offset parameter"},
+		{"seek wrong method", http.MethodGet, "/seek?offset=5", "", http.StatusMethodNotAllowed, "Method not allowed"},
+		{"resume while playing", http.MethodPost, "/resume", "", http.StatusConflict, "Replay is not paused"},
+		{"advance outside step mode", http.MethodPost, "/advance?channel=team", "", http.StatusConflict, "Step mode is not enabled"},
+		{"inject bad body", http.MethodPost, "/inject", "{", http.StatusBadRequest, "Invalid event body"},
+		{"restart wrong method", http.MethodGet, "/restart", "", http.StatusMethodNotAllowed, "Method not allowed"},
+		{"annotate without label", http.MethodPost, "/annotate", "{}", http.StatusBadRequest, "Annotation needs a label"},
+		{"switch to unknown transcript", http.MethodPost, "/transcript?id=nope", "", http.StatusNotFound, "nope"},
+		{"transcripts wrong method", http.MethodDelete, "/transcripts", "", http.StatusMethodNotAllowed, "Method not allowed"},
+		{"speed of unknown incident", http.MethodPost, "/incidents/nope/speed?speed=2", "", http.StatusNotFound, "Unknown incident"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s: %v", tt.method, tt.path, err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status %d, want %d", resp.StatusCode, tt.status)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type %q, want application/json", ct)
+			}
+			var body struct {
+				Error string `json:"error"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("error body is not JSON: %v", err)
+			}
+			if !strings.Contains(body.Error, tt.error) {
+				t.Errorf("error %q, want it to mention %q", body.Error, tt.error)
+			}
+		})
+	}
+}
+
+func TestUIConfig(t *testing.T) {
+	withPager := fixtureTranscript()
+	withPager.Events = append(withPager.Events,
+		Event{TimeOffset: 25, Channel: "pager", Message: "Page acknowledged"},
+		Event{TimeOffset: 26, Channel: "zoom", Message: "Bridge opened"},
+	)
+
+	tests := []struct {
+		name     string
+		tr       *IncidentTranscript
+		slack    bool
+		channels []uiChannel
+	}{
+		{"fixture", fixtureTranscript(), false, []uiChannel{
+			{Name: "team", Events: 3, Stream: "/stream/team", WebSocket: "/ws/team"},
+			{Name: "metrics", Events: 2, Stream: "/stream/incidents", WebSocket: "/ws/metrics"},
+		}},
+		{"one channel, Slack on", fixtureChannels("team"), true, []uiChannel{
+			{Name: "team", Events: 3, Stream: "/stream/team", WebSocket: "/ws/team"},
+		}},
+		// Channels without an SSE route of their own are still listed
+		{"channels beyond the fixed three", withPager, false, []uiChannel{
+			{Name: "team", Events: 3, Stream: "/stream/team", WebSocket: "/ws/team"},
+			{Name: "metrics", Events: 2, Stream: "/stream/incidents", WebSocket: "/ws/metrics"},
+			{Name: "pager", Events: 1, WebSocket: "/ws/pager"},
+			{Name: "zoom", Events: 1, Stream: "/stream/zoom", WebSocket: "/ws/zoom"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.slack {
+				useFakeSlack(t)
+			}
+			srv := startServer(t, tt.tr)
+
+			status, body := control(t, http.MethodGet, srv.URL+"/config", "")
+			if status != http.StatusOK {
+				t.Fatalf("GET /config: status %d: %s", status, body)
+			}
+			var got uiConfig
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatalf("decode config: %v", err)
+			}
+			want := uiConfig{Title: "Checkout outage", Channels: tt.channels, Speed: testSpeed, SlackEnabled: tt.slack}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("config %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestIndexCaching(t *testing.T) {
+	custom := filepath.Join(t.TempDir(), "index.html")
+	if err := os.WriteFile(custom, []byte("<h1>v1</h1>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		file string // -index-file, empty for the embedded page
+		edit bool   // change the file between loads
+	}{
+		{"embedded page", "", false},
+		{"page on disk", custom, false},
+		{"page on disk edited", custom, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := indexFile
+			indexFile = tt.file
+			t.Cleanup(func() { indexFile = previous })
+			srv := httptest.NewServer(newServer())
+			t.Cleanup(srv.Close)
+
+			first := getIndex(t, srv.URL, "")
+			if first.StatusCode != http.StatusOK || first.Header.Get("Cache-Control") != "no-cache" {
+				t.Fatalf("first load: %s, Cache-Control %q", first.Status, first.Header.Get("Cache-Control"))
+			}
+			etag := first.Header.Get("ETag")
+			if etag == "" {
+				t.Fatal("page served without an ETag")
+			}
+
+			if tt.edit {
+				if err := os.WriteFile(tt.file, []byte("<h1>v2</h1>"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			again := getIndex(t, srv.URL, etag)
+			want := http.StatusNotModified
+			if tt.edit {
+				want = http.StatusOK
+			}
+			if again.StatusCode != want {
+				t.Errorf("revalidation got %s, want %d", again.Status, want)
+			}
+		})
+	}
+}
+
+// Load the index page, revalidating against etag when one is given
+func getIndex(t *testing.T, url, etag string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /: %v", err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+// Speed control at 1x whose ramps are stopped and waited out when the test
+// ends, before the fake clock goes
+func rampingSpeedControl(t *testing.T) *speedControl {
+	sc := newSpeedControl(1)
+	t.Cleanup(func() {
+		sc.mu.Lock()
+		sc.stopRampLocked()
+		sc.mu.Unlock()
+		sc.ramps.Wait()
+	})
+	return sc
+}
+
+func TestSpeedRamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		from    float64 // speed before the ramp
+		target  float64
+		over    time.Duration
+		want    []float64 // speed after each tick of the ramp
+	}{
+		{"default speeding up", "", 1, 5, time.Second, []float64{1.4, 1.8, 2.2, 2.6, 3, 3.4, 3.8, 4.2, 4.6, 5}},
+		{"one channel slowing down", "team", 4, 1, 500 * time.Millisecond, []float64{3.4, 2.8, 2.2, 1.6, 1}},
+		{"target clamped", "", 2, 50, 400 * time.Millisecond, []float64{4, 6, 8, 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			sc := rampingSpeedControl(t)
+			sc.set(tt.channel, tt.from)
+			sc.rampTo(tt.channel, tt.target, tt.over)
+
+			for i, want := range tt.want {
+				if _, target, ok := sc.rampTarget(); !ok || target != min(tt.target, 10) {
+					t.Fatalf("tick %d: ramp target %v %v, want %v", i, target, ok, min(tt.target, 10))
+				}
+				changed := sc.changes()
+				clock.step(t)
+				<-changed
+				if got := sc.get(tt.channel); math.Abs(got-want) > 1e-9 {
+					t.Errorf("tick %d: speed %v, want %v", i, got, want)
+				}
+			}
+			if _, _, ok := sc.rampTarget(); ok {
+				t.Error("ramp still in progress after reaching its target")
+			}
+		})
+	}
+}
+
+func TestSpeedRampReplaced(t *testing.T) {
+	clock := useFakeClock(t)
+	sc := rampingSpeedControl(t)
+	sc.rampTo("", 10, time.Second)
+	sc.rampTo("", 0.5, 500*time.Millisecond)
+
+	// Only the second ramp moves the speed, toward its own target. The first
+	// one still sets a timer before it sees it was replaced, so the first
+	// tick waits for both.
+	for i, want := range []float64{0.9, 0.8, 0.7, 0.6, 0.5} {
+		changed := sc.changes()
+		if i == 0 {
+			clock.stepTogether(t, 2)
+		} else {
+			clock.step(t)
+		}
+		<-changed
+		if got := sc.get(""); math.Abs(got-want) > 1e-9 {
+			t.Errorf("tick %d: speed %v, want %v", i, got, want)
+		}
+	}
+
+	// An explicit speed ends a ramp outright
+	sc.rampTo("", 8, time.Second)
+	sc.set("", 2)
+	if _, _, ok := sc.rampTarget(); ok {
+		t.Error("ramp still in progress after an explicit speed")
+	}
+	clock.Advance(2 * time.Second)
+	if got := sc.get(""); got != 2 {
+		t.Errorf("speed %v after the canceled ramp's end, want 2", got)
+	}
+}
+
+func TestLoadTranscriptWithRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		failures []string // how the first fetches fail: unavailable, truncated or slow
+		err      string   // substring of the final error, empty when it loads
+	}{
+		{"first try", 1, nil, ""},
+		{"fails fast by default", 1, []string{"unavailable"}, "unexpected status 503"},
+		{"ready on the third try", 3, []string{"unavailable", "unavailable"}, ""},
+		{"file still being written", 2, []string{"truncated"}, ""},
+		{"slow fetch times out", 2, []string{"slow"}, ""},
+		{"gives up after the last attempt", 2, []string{"unavailable", "unavailable", "unavailable"}, "unexpected status 503"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := 0
+			store := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				calls++
+				call := calls
+				mu.Unlock()
+				if call > len(tt.failures) {
+					http.ServeFile(w, r, filepath.Join("testdata", "checkout_outage.json"))
+					return
+				}
+				switch tt.failures[call-1] {
+				case "unavailable":
+					w.WriteHeader(http.StatusServiceUnavailable)
+				case "truncated":
+					io.WriteString(w, `{"incident": {"title": "Checkout`)
+				case "slow":
+					select {
+					case <-time.After(time.Second):
+					case <-r.Context().Done():
+					}
+				}
+			}))
+			t.Cleanup(store.Close)
+
+			previousFile, previousTranscript, previousTimeout := transcriptFile, transcript, transcriptHTTPClient.Timeout
+			transcriptFile, transcriptHTTPClient.Timeout = store.URL+"/checkout_outage.json", 100*time.Millisecond
+			t.Cleanup(func() {
+				transcriptFile, transcript, transcriptHTTPClient.Timeout = previousFile, previousTranscript, previousTimeout
+			})
+
+			err := loadTranscriptWithRetry(tt.attempts, time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to mention %q", err, tt.err)
+				}
+				if calls != tt.attempts {
+					t.Errorf("fetched %d times, want one per attempt, %d", calls, tt.attempts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if calls != len(tt.failures)+1 {
+				t.Errorf("fetched %d times, want %d", calls, len(tt.failures)+1)
+			}
+			if transcript.Incident.Title != "Checkout outage" || len(transcript.Events) != 6 {
+				t.Errorf("loaded %q with %d events, want the checkout fixture", transcript.Incident.Title, len(transcript.Events))
+			}
+		})
+	}
+}
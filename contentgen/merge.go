@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Parse a comma-separated list of transcripts to merge
+func parseMergeSources(value string) []string {
+	var sources []string
+	for _, source := range strings.Split(value, ",") {
+		if source = strings.TrimSpace(source); source != "" {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// Namespace for a merged transcript's channels: its file name without the
+// extension, e.g. "db" for db.json or https://bucket/db.yaml
+func mergeNamespaceFor(source string) string {
+	name := path.Base(transcriptFormatSource(source))
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// Read, parse and combine independently captured transcripts into one
+// incident, e.g. the database and network teams' views of the same outage
+func loadMergedTranscript(sources []string, namespace bool) (*IncidentTranscript, error) {
+	parts := make([]*IncidentTranscript, 0, len(sources))
+	names := make([]string, 0, len(sources))
+	for _, source := range sources {
+		data, err := readTranscriptSource(source)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		t, err := parseTranscript(data, transcriptFormatSource(source))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+
+		name := mergeNamespaceFor(source)
+		if namespace && slices.Contains(names, name) {
+			return nil, fmt.Errorf("merged transcripts %s and another share the channel namespace %q", source, name)
+		}
+		parts = append(parts, t)
+		names = append(names, name)
+	}
+
+	merged := mergeTranscripts(parts, names, namespace)
+	slog.Info("🧬 Merged transcripts", "sources", len(sources), "events", len(merged.Events), "namespaced", namespace)
+	return merged, nil
+}
+
+// Combine transcripts into one timeline. Events keep their offsets and are
+// stably re-sorted, so events sharing an offset stay in source order. With
+// namespace, channels are prefixed by their source's name, e.g. db:team;
+// otherwise same-named channels combine. The incident runs for the longest
+// duration, under the distinct titles and descriptions joined in order.
+func mergeTranscripts(parts []*IncidentTranscript, names []string, namespace bool) *IncidentTranscript {
+	merged := &IncidentTranscript{}
+	var titles, descriptions []string
+	for i, t := range parts {
+		if t.Incident.Title != "" && !slices.Contains(titles, t.Incident.Title) {
+			titles = append(titles, t.Incident.Title)
+		}
+		if t.Incident.Description != "" && !slices.Contains(descriptions, t.Incident.Description) {
+			descriptions = append(descriptions, t.Incident.Description)
+		}
+		merged.Incident.DurationSeconds = max(merged.Incident.DurationSeconds, t.Incident.DurationSeconds)
+
+		for _, event := range t.Events {
+			if namespace {
+				event.Channel = names[i] + ":" + event.Channel
+			}
+			merged.Events = append(merged.Events, event)
+		}
+	}
+	merged.Incident.Title = strings.Join(titles, " + ")
+	merged.Incident.Description = strings.Join(descriptions, "\n")
+
+	sort.SliceStable(merged.Events, func(i, j int) bool {
+		return merged.Events[i].TimeOffset < merged.Events[j].TimeOffset
+	})
+	return merged
+}
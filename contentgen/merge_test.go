@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadMergedTranscript(t *testing.T) {
+	checkout := filepath.Join("testdata", "checkout_outage.json")
+	interleaved := filepath.Join("testdata", "interleaved_channels.yaml")
+
+	tests := []struct {
+		name      string
+		sources   []string
+		namespace bool
+		title     string
+		events    []string // channel@offset in merged order
+		err       string   // substring of the load error, empty when it merges
+	}{
+		// Same-named channels combine, and events sharing an offset stay in
+		// source order
+		{"channels combined", []string{checkout, interleaved}, false, "Checkout outage + Interleaved channels", []string{
+			"team@0", "team@0", "metrics@5", "metrics@10", "team@30", "team@30", "metrics@30", "team@45", "metrics@90", "team@120",
+		}, ""},
+		{"channels namespaced", []string{checkout, interleaved}, true, "Checkout outage + Interleaved channels", []string{
+			"checkout_outage:team@0", "interleaved_channels:team@0", "checkout_outage:metrics@5", "interleaved_channels:metrics@10",
+			"checkout_outage:team@30", "interleaved_channels:team@30", "interleaved_channels:metrics@30",
+			"checkout_outage:team@45", "checkout_outage:metrics@90", "checkout_outage:team@120",
+		}, ""},
+		{"source order breaks ties", []string{interleaved, checkout}, true, "Interleaved channels + Checkout outage", []string{
+			"interleaved_channels:team@0", "checkout_outage:team@0", "checkout_outage:metrics@5", "interleaved_channels:metrics@10",
+			"interleaved_channels:team@30", "interleaved_channels:metrics@30", "checkout_outage:team@30",
+			"checkout_outage:team@45", "checkout_outage:metrics@90", "checkout_outage:team@120",
+		}, ""},
+		{"namespaces collide", []string{checkout, filepath.Join("testdata", "checkout_outage.yaml")}, true, "", nil, `share the channel namespace "checkout_outage"`},
+		{"invalid source", []string{checkout, filepath.Join("testdata", "negative_offset.json")}, false, "", nil, "negative_offset.json: invalid transcript: event 0: time_offset -5 is negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := loadMergedTranscript(tt.sources, tt.namespace)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to mention %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("merge: %v", err)
+			}
+
+			var events []string
+			for _, event := range merged.Events {
+				events = append(events, fmt.Sprintf("%s@%d", event.Channel, event.TimeOffset))
+			}
+			if !reflect.DeepEqual(events, tt.events) {
+				t.Errorf("merged events %q, want %q", events, tt.events)
+			}
+			if merged.Incident.Title != tt.title {
+				t.Errorf("title %q, want %q", merged.Incident.Title, tt.title)
+			}
+			if merged.Incident.DurationSeconds != 120 {
+				t.Errorf("duration %d, want the longest, 120", merged.Incident.DurationSeconds)
+			}
+			if err := validateTranscript(merged); err != nil {
+				t.Errorf("merged transcript invalid: %v", err)
+			}
+		})
+	}
+}
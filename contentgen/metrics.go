@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics for the replay service itself, exposed at /metrics
+var (
+	connectedClientsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "contentgen_connected_clients",
+		Help: "Number of currently connected SSE clients per channel.",
+	}, []string{"channel"})
+
+	eventsEmittedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "contentgen_events_emitted_total",
+		Help: "Total transcript events emitted by the replay per channel.",
+	}, []string{"channel"})
+
+	chatPublishCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "contentgen_chat_publishes_total",
+		Help: "Total chat publish attempts by notifier (slack or teams) and result (success, failure, or dropped by Slack pacing).",
+	}, []string{"notifier", "result"})
+
+	webhookDeliveryCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "contentgen_webhook_deliveries_total",
+		Help: "Total webhook deliveries by result (success, failure or dropped).",
+	}, []string{"result"})
+
+	eventLatenessHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "contentgen_event_lateness_seconds",
+		Help:    "Wall time between when the shared replay meant to emit each event and when it did.",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "contentgen_playback_speed",
+		Help: "Current default playback speed multiplier.",
+	}, func() float64 { return getPlaybackSpeed("") })
+)
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Backend that publishes replayed events to an external chat tool
+type notifier interface {
+	Name() string
+	Routes(channel string) bool // whether events on this transcript channel are published
+	Publish(event Event) error
+}
+
+// Chat backend replayed events are published to, or nil for none
+var chatNotifier notifier
+
+// Chat backend that names the incident in its posts, renamed on a transcript switch
+type incidentNamer interface {
+	setIncident(info IncidentInfo)
+}
+
+// Transcript channels published to chat, from -publish-channels; nil leaves
+// the decision to the backend's own channel mapping
+var publishChannels map[string]bool
+
+// Parse a comma-separated list of transcript channels into a set
+func parseChannelSet(value string) map[string]bool {
+	channels := make(map[string]bool)
+	for _, channel := range strings.Split(value, ",") {
+		if channel = strings.TrimSpace(channel); channel != "" {
+			channels[channel] = true
+		}
+	}
+	return channels
+}
+
+// Pick the chat backend: "slack", "teams", "discord" or "none", or with
+// "auto" whichever is configured, preferring Slack, then Teams
+func selectNotifier(mode string, slack *SlackClient, teams *TeamsNotifier, discord *DiscordNotifier) (notifier, error) {
+	switch mode {
+	case "auto":
+		if slack.Enabled() {
+			return slack, nil
+		}
+		if teams.Enabled() {
+			return teams, nil
+		}
+		if discord.Enabled() {
+			return discord, nil
+		}
+		return nil, nil
+	case "slack":
+		if !slack.Enabled() {
+			return nil, fmt.Errorf("-notify slack needs SLACK_BOT_TOKEN")
+		}
+		return slack, nil
+	case "teams":
+		if !teams.Enabled() {
+			return nil, fmt.Errorf("-notify teams needs TEAMS_WEBHOOK_URL")
+		}
+		return teams, nil
+	case "discord":
+		if !discord.Enabled() {
+			return nil, fmt.Errorf("-notify discord needs DISCORD_WEBHOOK_URL")
+		}
+		return discord, nil
+	case "none":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown -notify backend %q, expected auto, slack, teams, discord or none", mode)
+}
+
+// What became of an event's chat publish, shown to stream viewers; the zero
+// value means the event wasn't sent to chat
+type chatResult struct {
+	Backend string `json:"backend"`          // slack, teams or discord
+	Status  string `json:"status"`           // ok, queued, dropped or failed
+	Reason  string `json:"reason,omitempty"` // failed: short cause, e.g. rate_limited
+}
+
+// Short cause of a failed chat publish for viewers, e.g. rate_limited or
+// channel_not_found, without the detail the logs carry
+func chatFailureReason(err error) string {
+	var slackErr *SlackError
+	if errors.As(err, &slackErr) {
+		switch {
+		case slackErr.Kind == SlackErrorRateLimited:
+			return "rate_limited"
+		case slackErr.Code != "":
+			return slackErr.Code
+		case slackErr.Kind == SlackErrorAuth:
+			return "auth_failed"
+		}
+	}
+	var webhookErr *WebhookError
+	if errors.As(err, &webhookErr) && webhookErr.RetryAfter > 0 {
+		return "rate_limited"
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	return "error"
+}
+
+// Text appended to an event line when its chat publish didn't go through.
+// Successful and queued publishes stay silent.
+func (r chatResult) suffix() string {
+	switch r.Status {
+	case "failed":
+		return fmt.Sprintf(" (⚠️ %s)", r.outcome())
+	case "dropped":
+		return fmt.Sprintf(" (⏭️ %s)", r.outcome())
+	}
+	return ""
+}
+
+// Backend and what went wrong, e.g. "slack: rate_limited"
+func (r chatResult) outcome() string {
+	if r.Status == "failed" {
+		return r.Backend + ": " + r.Reason
+	}
+	return r.Backend + ": " + r.Status
+}
+
+// Report whether a chat backend publishes events on a channel. This is the
+// one publishing decision for every channel, whichever stream it feeds.
+func notifierRoutes(n notifier, channel string) bool {
+	if publishChannels != nil && !publishChannels[channel] {
+		return false
+	}
+	return n != nil && n.Routes(channel)
+}
+
+// Name of the Slack backend
+func (c *SlackClient) Name() string {
+	return "slack"
+}
+
+// Publish a replayed event to Slack
+func (c *SlackClient) Publish(event Event) error {
+	return c.PostEvent(event)
+}
+
+// Microsoft Teams Incoming Webhook publisher posting each event as an Adaptive Card
+type TeamsNotifier struct {
+	WebhookURL    string
+	Channels      map[string]bool // transcript channels published to Teams
+	IncidentTitle string          // card title
+	HTTPClient    *http.Client
+
+	titleMu sync.RWMutex // guards IncidentTitle once publishing has started
+}
+
+// Create a Teams notifier for the given transcript channels
+func NewTeamsNotifier(webhookURL string, channels []string) *TeamsNotifier {
+	routes := make(map[string]bool)
+	for _, channel := range channels {
+		if channel = strings.TrimSpace(channel); channel != "" {
+			routes[channel] = true
+		}
+	}
+	return &TeamsNotifier{WebhookURL: webhookURL, Channels: routes, HTTPClient: outboundHTTPClient}
+}
+
+// Report whether the notifier has a webhook to post to
+func (t *TeamsNotifier) Enabled() bool {
+	return t != nil && t.WebhookURL != ""
+}
+
+// Name of the Teams backend
+func (t *TeamsNotifier) Name() string {
+	return "teams"
+}
+
+// Report whether a transcript channel is published to Teams
+func (t *TeamsNotifier) Routes(channel string) bool {
+	return t != nil && t.Channels[channel]
+}
+
+// Post a replayed event to the Teams channel, retrying transient failures
+func (t *TeamsNotifier) Publish(event Event) error {
+	if !t.Enabled() {
+		return fmt.Errorf("Teams webhook URL not configured")
+	}
+
+	body, err := json.Marshal(t.buildCard(event))
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	return withRetry("Teams webhook", func(ctx context.Context) error {
+		return postWebhook(ctx, t.HTTPClient, t.WebhookURL, body)
+	})
+}
+
+// Speaker shown after the channel in the card's subtitle
+func teamsSpeaker(event Event) string {
+	if event.Speaker == "" {
+		return ""
+	}
+	return " · 🗣 " + event.Speaker
+}
+
+// Card title for the incident being published
+func (t *TeamsNotifier) title() string {
+	t.titleMu.RLock()
+	defer t.titleMu.RUnlock()
+	return t.IncidentTitle
+}
+
+// Title cards with a different incident from now on
+func (t *TeamsNotifier) setIncident(info IncidentInfo) {
+	t.titleMu.Lock()
+	defer t.titleMu.Unlock()
+	t.IncidentTitle = info.Title
+}
+
+// Build the Incoming Webhook message wrapping an Adaptive Card
+func (t *TeamsNotifier) buildCard(event Event) map[string]interface{} {
+	body := make([]map[string]interface{}, 0, 3)
+	if title := t.title(); title != "" {
+		body = append(body, map[string]interface{}{
+			"type": "TextBlock", "text": title, "weight": "Bolder", "size": "Medium", "wrap": true,
+		})
+	}
+	body = append(body, map[string]interface{}{
+		"type": "TextBlock", "text": fmt.Sprintf("🕒 %s · #%s%s", formatEventTime(event, wallClock.Now()), event.Channel, teamsSpeaker(event)), "isSubtle": true, "spacing": "None",
+	})
+
+	switch event.kind() {
+	case eventLink:
+		body = append(body, map[string]interface{}{
+			"type": "TextBlock", "text": fmt.Sprintf("🔗 [%s](%s)", event.Message, event.Meta["url"]), "wrap": true,
+		})
+	case eventImage:
+		body = append(body,
+			map[string]interface{}{"type": "TextBlock", "text": event.Message, "wrap": true},
+			map[string]interface{}{"type": "Image", "url": event.Meta["url"], "altText": event.Meta["alt"]},
+		)
+	default:
+		body = append(body, map[string]interface{}{
+			"type": "TextBlock", "text": event.Message, "wrap": true,
+		})
+	}
+
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body":    body,
+				},
+			},
+		},
+	}
+}
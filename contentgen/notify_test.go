@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Teams Incoming Webhook stand-in answering with status, returning the
+// posted messages
+func fakeTeams(t *testing.T, status int) (*TeamsNotifier, *[]map[string]interface{}) {
+	t.Helper()
+	var posts []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var post map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+			t.Errorf("Teams body: %v", err)
+		}
+		posts = append(posts, post)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	teams := NewTeamsNotifier(srv.URL, []string{"team", " zoom "})
+	teams.HTTPClient = srv.Client()
+	return teams, &posts
+}
+
+func TestTeamsAdaptiveCard(t *testing.T) {
+	clock := useFakeClock(t)
+	teams, posts := fakeTeams(t, http.StatusOK)
+	teams.IncidentTitle = "Checkout outage"
+
+	event := Event{TimeOffset: 10, Channel: "team", Speaker: "Priya", Message: "Rolling back"}
+	if err := teams.Publish(event); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if len(*posts) != 1 {
+		t.Fatalf("Teams got %d posts, want 1", len(*posts))
+	}
+
+	want := fmt.Sprintf(`{
+		"type": "message",
+		"attachments": [{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": {
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type": "AdaptiveCard",
+				"version": "1.4",
+				"body": [
+					{"type": "TextBlock", "text": "Checkout outage", "weight": "Bolder", "size": "Medium", "wrap": true},
+					{"type": "TextBlock", "text": "🕒 %s · #team · 🗣 Priya", "isSubtle": true, "spacing": "None"},
+					{"type": "TextBlock", "text": "Rolling back", "wrap": true}
+				]
+			}
+		}]
+	}`, formatEventTime(event, clock.Now()))
+	var card map[string]interface{}
+	if err := json.Unmarshal([]byte(want), &card); err != nil {
+		t.Fatalf("expected card: %v", err)
+	}
+	if got := (*posts)[0]; !reflect.DeepEqual(got, card) {
+		body, _ := json.MarshalIndent(got, "", "  ")
+		t.Errorf("posted card\n%s\nwant\n%s", body, want)
+	}
+}
+
+func TestTeamsRejectedPost(t *testing.T) {
+	teams, posts := fakeTeams(t, http.StatusBadRequest)
+	if err := teams.Publish(Event{Channel: "team", Message: "Paging on-call"}); err == nil {
+		t.Fatal("Publish reported no error for a rejected card")
+	}
+	if len(*posts) != 1 {
+		t.Errorf("rejected card posted %d times, want once without retrying", len(*posts))
+	}
+}
+
+func TestTeamsRoutes(t *testing.T) {
+	teams, _ := fakeTeams(t, http.StatusOK)
+	for channel, want := range map[string]bool{"team": true, "zoom": true, "metrics": false} {
+		if got := teams.Routes(channel); got != want {
+			t.Errorf("Routes(%q) = %v, want %v", channel, got, want)
+		}
+	}
+}
+
+func TestSelectNotifier(t *testing.T) {
+	slack := NewSlackClient("xoxb-test", nil)
+	teams := NewTeamsNotifier("https://example.webhook.office.com/hook", nil)
+	discord := NewDiscordNotifier("https://discord.com/api/webhooks/1/token", nil)
+
+	tests := []struct {
+		mode    string
+		slack   *SlackClient
+		teams   *TeamsNotifier
+		discord *DiscordNotifier
+		want    string // backend name, empty for none
+		err     bool
+	}{
+		{"auto", slack, teams, discord, "slack", false},
+		{"auto", nil, teams, discord, "teams", false},
+		{"auto", nil, nil, discord, "discord", false},
+		{"auto", nil, nil, nil, "", false},
+		{"teams", slack, teams, nil, "teams", false},
+		{"teams", slack, nil, nil, "", true},
+		{"slack", nil, teams, nil, "", true},
+		{"none", slack, teams, discord, "", false},
+		{"carrier-pigeon", slack, nil, nil, "", true},
+	}
+	for _, tt := range tests {
+		got, err := selectNotifier(tt.mode, tt.slack, tt.teams, tt.discord)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: error %v, want error %v", tt.mode, err, tt.err)
+			continue
+		}
+		name := ""
+		if got != nil {
+			name = got.Name()
+		}
+		if name != tt.want {
+			t.Errorf("%s: picked %q, want %q", tt.mode, name, tt.want)
+		}
+	}
+}
+
+func TestPublishChannels(t *testing.T) {
+	tests := []struct {
+		name    string
+		publish string // -publish-channels, empty for the default
+		stream  string // the one viewer, which is what starts the replay
+		want    []string
+	}{
+		{"default publishes team only", "", "/stream?channels=team,metrics", []string{"Paging on-call", "Rolling back", "Resolved"}},
+		{"metrics added", "team,metrics", "/stream?channels=team,metrics", []string{"Paging on-call", "CPU 92%", "CPU 99%", "Rolling back", "Resolved"}},
+		{"metrics alone", "metrics", "/stream?channels=team,metrics", []string{"CPU 92%", "CPU 99%"}},
+		// Publishing doesn't depend on which stream is watched
+		{"metrics viewer only", "team,metrics", "/stream/incidents", []string{"Paging on-call", "CPU 92%", "CPU 99%", "Rolling back", "Resolved"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slack := useFakeSlack(t)
+			previous := publishChannels
+			t.Cleanup(func() { publishChannels = previous })
+			if tt.publish != "" {
+				// As main does, unmapped channels mirror into the team channel
+				publishChannels = parseChannelSet(tt.publish)
+				for channel := range publishChannels {
+					slackClient.Channels[channel] = "C0123ABCD"
+				}
+			}
+			srv := startServer(t, fixtureTranscript())
+
+			// The stream closes when its own channels finish, then the rest
+			// of the replay plays out
+			separator := "?"
+			if strings.Contains(tt.stream, "?") {
+				separator = "&"
+			}
+			readSSE(t, srv.URL+tt.stream+separator+"oncomplete=close")
+			incidentReplay.wait()
+			if got := postedTexts(slack); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Slack posts %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatFailureReachesStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		pace   string // -slack-rate-policy, unpaced when empty
+		status int
+		body   string
+		note   string // outcome on the chat_failed frame, empty when the post succeeds
+	}{
+		{"posted", "", http.StatusOK, `{"ok":true,"ts":"1700000000.000100"}`, ""},
+		{"unknown channel", "", http.StatusOK, `{"ok":false,"error":"channel_not_found"}`, "slack: channel_not_found"},
+		{"revoked token", "", http.StatusOK, `{"ok":false,"error":"token_revoked"}`, "slack: token_revoked"},
+		{"unauthorized", "", http.StatusUnauthorized, "", "slack: auth_failed"},
+		// Paced posts fail later, on the pacer's worker
+		{"paced and posted", slackPaceQueue, http.StatusOK, `{"ok":true,"ts":"1700000000.000100"}`, ""},
+		{"paced, unknown channel", slackPaceQueue, http.StatusOK, `{"ok":false,"error":"channel_not_found"}`, "slack: channel_not_found"},
+		{"coalesced, unknown channel", slackPaceCoalesce, http.StatusOK, `{"ok":false,"error":"channel_not_found"}`, "slack: channel_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			slack := useFakeSlack(t)
+			slack.respond = func(call int, w http.ResponseWriter) bool {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+				return true
+			}
+			if tt.pace != "" {
+				pacer := newSlackPacer(slackClient, time.Second, tt.pace)
+				chatNotifier = pacer
+				t.Cleanup(pacer.wait)
+			}
+			// Held back until both streams are watching
+			tr := fixtureChannels("team")
+			tr.Events = []Event{
+				{TimeOffset: 1, Channel: "team", Message: "Paging on-call"},
+				{TimeOffset: 10, Channel: "team", Message: "Rolling back"},
+			}
+			srv := startServer(t, tr)
+			text := openSSE(t, srv.URL+"/stream/team")
+			frames := openSSE(t, srv.URL+"/stream/team?format=json")
+			text.until(t, "📋 Incident:")
+			frames.until(t, `"incident"`)
+			clock.step(t)
+			text.until(t, "Paging on-call")
+			frames.until(t, "Paging on-call")
+			eventOutbox.wait()
+
+			if tt.note == "" {
+				text.quietFor(t, 100*time.Millisecond)
+				return
+			}
+
+			// The failure follows the event as a line of its own
+			lines := sseData(text.until(t, "📮"))
+			if got, want := lines[len(lines)-1], "Paging on-call (⚠️ "+tt.note+")"; !strings.HasPrefix(got, "📮 ") || !strings.HasSuffix(got, want) {
+				t.Errorf("text stream line %q, want 📮 ... %q", got, want)
+			}
+			got := frames.until(t, `"chat_failed"`)
+			var marker markerFrame
+			if err := json.Unmarshal([]byte(got[len(got)-1].Data), &marker); err != nil {
+				t.Fatalf("decode chat_failed frame: %v", err)
+			}
+			if marker.Channel != "team" || marker.Message != "Paging on-call" || marker.Note != tt.note {
+				t.Errorf("chat_failed frame %+v, want the team event with note %q", marker, tt.note)
+			}
+			if emitted := primaryIncident().Replay.emittedLog(); len(emitted) != 1 || emitted[0].Published {
+				t.Errorf("emitted %+v, want the event recorded as unpublished", emitted)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Retry policy for outbound deliveries: bounded attempts with exponential
+// backoff, capped overall so a dead endpoint can't hold up the replay for long
+const (
+	retryMaxAttempts    = 4
+	retryInitialBackoff = 500 * time.Millisecond
+	retryMaxTime        = 15 * time.Second
+)
+
+// HTTP client shared by outbound integrations so connections are reused
+var outboundHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// Delivery error that knows whether another attempt could succeed
+type retryableError interface {
+	error
+	Retryable() bool
+	Backoff() time.Duration // delay the server asked for, or zero
+}
+
+// Run an outbound call, retrying retryable failures within the retry budget
+func withRetry(target string, attemptCall func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), retryMaxTime)
+	defer cancel()
+
+	backoff := retryInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := attemptCall(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var retryErr retryableError
+		if !errors.As(err, &retryErr) || !retryErr.Retryable() || attempt == retryMaxAttempts {
+			return err
+		}
+
+		// Honor the server's requested delay, otherwise back off exponentially
+		wait := backoff
+		if requested := retryErr.Backoff(); requested > 0 {
+			wait = requested
+		}
+		if deadline, ok := ctx.Deadline(); ok && wallClock.Now().Add(wait).After(deadline) {
+			return err
+		}
+
+		slog.Warn("⏳ Outbound attempt failed, retrying", "target", target, "attempt", attempt, "err", err, "retry_in", wait)
+		if waitUntil(ctx, wallClock.Now().Add(wait)) != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
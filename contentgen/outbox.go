@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// Fired events waiting for chat and PagerDuty delivery before new ones are dropped
+const outboxQueueSize = 256
+
+// Outbound work for one fired event. The backends are captured when the
+// event fires, so a transcript switch never redirects queued work; the
+// incident title they post under is guarded inside each backend.
+type outboxJob struct {
+	replay   *replay
+	event    Event
+	index    int
+	chat     notifier         // chat backend to publish to, nil when the event isn't published
+	pager    *PagerDutyClient // PagerDuty client to hand the event to, nil when paging is off
+	dedupKey string           // PagerDuty dedup key for the replay pass
+	digest   *slackDigest     // instead of an event: post the final digest once earlier events are in
+}
+
+// Delivers fired events to chat and PagerDuty on a background worker, like
+// WebhookSink does for webhooks, so a slow, failing or rate-limited backend
+// never holds up the replay clock. One worker keeps deliveries in timeline
+// order; it starts with the first job.
+type outbox struct {
+	start   sync.Once
+	queue   chan outboxJob
+	pending sync.WaitGroup // queued jobs not yet delivered
+}
+
+// Outbox shared by every replay that publishes
+var eventOutbox = newOutbox()
+
+func newOutbox() *outbox {
+	return &outbox{queue: make(chan outboxJob, outboxQueueSize)}
+}
+
+// Queue a fired event's outbound work, reporting false when the queue is full
+func (o *outbox) enqueue(job outboxJob) bool {
+	o.start.Do(func() { go o.run() })
+	o.pending.Add(1)
+	select {
+	case o.queue <- job:
+		return true
+	default:
+		o.pending.Done()
+		return false
+	}
+}
+
+// Wait until everything queued so far has been delivered
+func (o *outbox) wait() {
+	o.pending.Wait()
+}
+
+// Deliver queued jobs one at a time
+func (o *outbox) run() {
+	for job := range o.queue {
+		job.deliver()
+		o.pending.Done()
+	}
+}
+
+// Publish the event to chat and hand it to PagerDuty, recording the results
+func (job outboxJob) deliver() {
+	if job.digest != nil {
+		job.digest.flush()
+		return
+	}
+	event, index := job.event, job.index
+	if job.chat != nil {
+		backend := job.chat.Name()
+		var err error
+		if pacer, ok := job.chat.(*slackPacer); ok {
+			err = pacer.publishFor(job.replay, event)
+		} else {
+			err = job.chat.Publish(event)
+		}
+		switch {
+		case errors.Is(err, errPublishQueued):
+			// The pacer records the outcome, and reports a failure, when it posts
+			slog.Debug("Queued for chat", "notifier", backend, "channel", event.Channel, "index", index)
+		case errors.Is(err, errPublishDropped):
+			chatPublishCounter.WithLabelValues(backend, "dropped").Inc()
+			slog.Info("⏭️  Skipped chat publish to stay under the rate limit", "notifier", backend, "channel", event.Channel, "index", index, "result", "dropped", "err", err)
+			job.replay.reportChat(event, chatResult{Backend: backend, Status: "dropped"})
+		case err != nil:
+			chatPublishCounter.WithLabelValues(backend, "failure").Inc()
+			slog.Warn("⚠️  Failed to publish to chat", "notifier", backend, "channel", event.Channel, "index", index, "result", "failure", "err", err)
+			job.replay.reportChat(event, chatResult{Backend: backend, Status: "failed", Reason: chatFailureReason(err)})
+		default:
+			chatPublishCounter.WithLabelValues(backend, "success").Inc()
+			slog.Info("Published to chat", "notifier", backend, "channel", event.Channel, "index", index, "result", "success", "message", event.Message)
+		}
+	}
+
+	if job.pager != nil {
+		action, err := job.pager.HandleEvent(event, job.dedupKey)
+		if err != nil {
+			slog.Warn("⚠️  Failed to send PagerDuty event", "action", action, "index", index, "dedup_key", job.dedupKey, "err", err)
+		} else if action != "" {
+			slog.Info("📟 Sent PagerDuty event", "action", action, "index", index, "dedup_key", job.dedupKey, "message", event.Message)
+		}
+	}
+}
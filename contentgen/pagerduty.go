@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default PagerDuty Events API v2 endpoint
+const defaultPagerDutyBaseURL = "https://events.pagerduty.com/v2"
+
+// Default patterns deciding which metrics events page and which resolve
+const (
+	defaultPagerDutyCritical  = `(?i)critical|outage|oom_kill`
+	defaultPagerDutyRecovered = `(?i)recovered|resolved|all_health_checks=passing`
+)
+
+// PagerDuty Events API v2 client that pages on critical metrics events and
+// resolves the page when the replay reports recovery
+type PagerDutyClient struct {
+	BaseURL    string
+	RoutingKey string
+	Channel    string         // transcript channel watched for alerts
+	Critical   *regexp.Regexp // messages that trigger an incident
+	Recovered  *regexp.Regexp // messages that resolve it
+	HTTPClient *http.Client
+
+	mu        sync.Mutex
+	triggered map[string]bool // dedup keys with an open incident
+}
+
+// Create a PagerDuty client against the public Events API
+func NewPagerDutyClient(routingKey string, critical, recovered *regexp.Regexp) *PagerDutyClient {
+	return &PagerDutyClient{
+		BaseURL:    defaultPagerDutyBaseURL,
+		RoutingKey: routingKey,
+		Channel:    "metrics",
+		Critical:   critical,
+		Recovered:  recovered,
+		HTTPClient: outboundHTTPClient,
+		triggered:  make(map[string]bool),
+	}
+}
+
+// Report whether the client has a routing key to send events with
+func (c *PagerDutyClient) Enabled() bool {
+	return c != nil && c.RoutingKey != ""
+}
+
+// Trigger or resolve a PagerDuty incident for an event, if it matches a
+// pattern. Every trigger in one replay shares dedupKey, so repeats update the
+// same incident instead of opening new ones. Returns the action sent, if any.
+func (c *PagerDutyClient) HandleEvent(event Event, dedupKey string) (string, error) {
+	if !c.Enabled() || event.Channel != c.Channel {
+		return "", nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case c.Recovered != nil && c.Recovered.MatchString(event.Message):
+		if !c.triggered[dedupKey] {
+			return "", nil
+		}
+		if err := c.send(event, "resolve", dedupKey); err != nil {
+			return "resolve", err
+		}
+		delete(c.triggered, dedupKey)
+		return "resolve", nil
+	case c.Critical != nil && c.Critical.MatchString(event.Message):
+		if err := c.send(event, "trigger", dedupKey); err != nil {
+			return "trigger", err
+		}
+		c.triggered[dedupKey] = true
+		return "trigger", nil
+	}
+	return "", nil
+}
+
+// Send one event to the Events API
+func (c *PagerDutyClient) send(event Event, action, dedupKey string) error {
+	payload := map[string]interface{}{
+		"routing_key":  c.RoutingKey,
+		"event_action": action,
+		"dedup_key":    dedupKey,
+	}
+	if action == "trigger" {
+		payload["payload"] = map[string]interface{}{
+			"summary":  event.Message,
+			"source":   "contentgen",
+			"severity": "critical",
+			"custom_details": map[string]interface{}{
+				"incident": primaryIncident().Transcript.Incident.Title,
+				"offset":   formatOffset(event.TimeOffset),
+				"channel":  event.Channel,
+			},
+		}
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/enqueue", bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("PagerDuty API error: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+)
+
+// Stand-in for the PagerDuty Events API, recording each enqueued event
+type fakePagerDuty struct {
+	*httptest.Server
+	mu     sync.Mutex
+	events []map[string]interface{}
+	status int
+}
+
+func newFakePagerDuty(t *testing.T) *fakePagerDuty {
+	t.Helper()
+	pd := &fakePagerDuty{status: http.StatusAccepted}
+	pd.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/enqueue" {
+			t.Errorf("PagerDuty request to %s, want /enqueue", r.URL.Path)
+		}
+		var event map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("PagerDuty body: %v", err)
+		}
+		pd.mu.Lock()
+		pd.events = append(pd.events, event)
+		status := pd.status
+		pd.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(pd.Close)
+	return pd
+}
+
+func TestPagerDutyTriggerAndResolve(t *testing.T) {
+	startServer(t, fixtureTranscript())
+	pd := newFakePagerDuty(t)
+	client := NewPagerDutyClient("routing-key", regexp.MustCompile(defaultPagerDutyCritical), regexp.MustCompile(defaultPagerDutyRecovered))
+	client.BaseURL = pd.URL
+	client.HTTPClient = pd.Client()
+
+	// One replay pass, in order; every action shares the pass's dedup key
+	steps := []struct {
+		event  Event
+		action string
+	}{
+		{Event{TimeOffset: 1, Channel: "metrics", Message: "cpu=45%"}, ""},
+		{Event{TimeOffset: 2, Channel: "metrics", Message: "recovered before anything paged"}, ""},
+		{Event{TimeOffset: 3, Channel: "metrics", Message: "api-gateway-prod-04 oom_kill"}, "trigger"},
+		{Event{TimeOffset: 4, Channel: "team", Message: "CRITICAL: we're on it"}, ""},
+		{Event{TimeOffset: 5, Channel: "metrics", Message: "CRITICAL error_rate=31%"}, "trigger"},
+		{Event{TimeOffset: 6, Channel: "metrics", Message: "all_health_checks=passing"}, "resolve"},
+		{Event{TimeOffset: 7, Channel: "metrics", Message: "resolved again"}, ""},
+	}
+	for _, step := range steps {
+		action, err := client.HandleEvent(step.event, "contentgen-run-0")
+		if err != nil {
+			t.Fatalf("%q: %v", step.event.Message, err)
+		}
+		if action != step.action {
+			t.Errorf("%q sent %q, want %q", step.event.Message, action, step.action)
+		}
+	}
+
+	want := []struct{ action, summary string }{
+		{"trigger", "api-gateway-prod-04 oom_kill"},
+		{"trigger", "CRITICAL error_rate=31%"},
+		{"resolve", ""},
+	}
+	if len(pd.events) != len(want) {
+		t.Fatalf("PagerDuty got %d events, want %d: %v", len(pd.events), len(want), pd.events)
+	}
+	for i, w := range want {
+		event := pd.events[i]
+		if event["routing_key"] != "routing-key" || event["dedup_key"] != "contentgen-run-0" || event["event_action"] != w.action {
+			t.Errorf("event %d = %v, want %s with the routing and dedup keys", i, event, w.action)
+		}
+		payload, _ := event["payload"].(map[string]interface{})
+		if w.summary == "" {
+			if payload != nil {
+				t.Errorf("resolve carried a payload: %v", payload)
+			}
+			continue
+		}
+		if payload["summary"] != w.summary || payload["severity"] != "critical" {
+			t.Errorf("event %d payload %v, want a critical page for %q", i, payload, w.summary)
+		}
+		details, _ := payload["custom_details"].(map[string]interface{})
+		if details["incident"] != "Checkout outage" {
+			t.Errorf("event %d details %v, want the incident title", i, details)
+		}
+	}
+}
+
+func TestPagerDutyFailedTriggerDoesNotResolve(t *testing.T) {
+	startServer(t, fixtureTranscript())
+	pd := newFakePagerDuty(t)
+	pd.status = http.StatusBadRequest
+	client := NewPagerDutyClient("routing-key", regexp.MustCompile(defaultPagerDutyCritical), regexp.MustCompile(defaultPagerDutyRecovered))
+	client.BaseURL = pd.URL
+	client.HTTPClient = pd.Client()
+
+	if _, err := client.HandleEvent(Event{Channel: "metrics", Message: "outage"}, "k"); err == nil {
+		t.Fatal("trigger rejected by PagerDuty reported no error")
+	}
+	// Nothing is open, so recovery has nothing to resolve
+	if action, err := client.HandleEvent(Event{Channel: "metrics", Message: "recovered"}, "k"); action != "" || err != nil {
+		t.Errorf("recovery after a failed trigger sent %q, %v", action, err)
+	}
+}
+
+func TestPagerDutyDisabled(t *testing.T) {
+	var unset *PagerDutyClient
+	for _, client := range []*PagerDutyClient{unset, NewPagerDutyClient("", nil, nil)} {
+		if action, err := client.HandleEvent(Event{Channel: "metrics", Message: "outage"}, "k"); action != "" || err != nil {
+			t.Errorf("disabled client sent %q, %v", action, err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Messages requested per conversations.history page
+const slackHistoryPageSize = 200
+
+// Message subtypes that are channel noise rather than conversation
+var skippedSlackSubtypes = map[string]bool{
+	"channel_join":    true,
+	"channel_leave":   true,
+	"channel_topic":   true,
+	"channel_purpose": true,
+}
+
+// A message as returned by conversations.history
+type slackHistoryMessage struct {
+	TS       string `json:"ts"`
+	Text     string `json:"text"`
+	User     string `json:"user"`
+	Username string `json:"username"`
+	Subtype  string `json:"subtype"`
+}
+
+// One page of conversations.history
+type slackHistoryPage struct {
+	Messages         []slackHistoryMessage `json:"messages"`
+	HasMore          bool                  `json:"has_more"`
+	ResponseMetadata struct {
+		NextCursor string `json:"next_cursor"`
+	} `json:"response_metadata"`
+}
+
+// Fetch every message posted to a Slack channel within [from, to], oldest first
+func (c *SlackClient) ChannelHistory(channelID string, from, to time.Time) ([]slackHistoryMessage, error) {
+	if !c.Enabled() {
+		return nil, fmt.Errorf("Slack bot token not configured")
+	}
+
+	var messages []slackHistoryMessage
+	cursor := ""
+	for {
+		params := url.Values{}
+		params.Set("channel", channelID)
+		params.Set("oldest", strconv.FormatInt(from.Unix(), 10))
+		params.Set("latest", strconv.FormatInt(to.Unix(), 10))
+		params.Set("inclusive", "true")
+		params.Set("limit", strconv.Itoa(slackHistoryPageSize))
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+
+		// Each page gets its own retry budget so rate limits on long histories are honored
+		var page slackHistoryPage
+		err := withRetry("Slack API", func(ctx context.Context) error {
+			return c.call(ctx, "conversations.history", "application/x-www-form-urlencoded", []byte(params.Encode()), &page)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch channel history: %w", err)
+		}
+
+		messages = append(messages, page.Messages...)
+		cursor = page.ResponseMetadata.NextCursor
+		if !page.HasMore || cursor == "" {
+			break
+		}
+	}
+
+	// Slack returns newest first
+	sort.SliceStable(messages, func(i, j int) bool {
+		return parseSlackTS(messages[i].TS).Before(parseSlackTS(messages[j].TS))
+	})
+	return messages, nil
+}
+
+// Convert a Slack message timestamp like "1700000000.123456" to a time
+func parseSlackTS(ts string) time.Time {
+	seconds, err := strconv.ParseFloat(ts, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// Turn recorded Slack messages into a transcript on a single channel, with
+// offsets relative to the first message
+func buildRecordedTranscript(messages []slackHistoryMessage, slackChannelID, transcriptChannel string, from, to time.Time) (*IncidentTranscript, error) {
+	t := &IncidentTranscript{
+		Incident: IncidentInfo{
+			Title:       fmt.Sprintf("Recorded incident from Slack channel %s", slackChannelID),
+			Description: fmt.Sprintf("Captured from Slack between %s and %s", from.Format(time.RFC3339), to.Format(time.RFC3339)),
+		},
+	}
+
+	var first time.Time
+	for _, msg := range messages {
+		if msg.Text == "" || skippedSlackSubtypes[msg.Subtype] {
+			continue
+		}
+		posted := parseSlackTS(msg.TS)
+		if first.IsZero() {
+			first = posted
+		}
+
+		// Keep the transcript's "[speaker] message" convention
+		text := msg.Text
+		if speaker := msg.Username; speaker != "" || msg.User != "" {
+			if speaker == "" {
+				speaker = msg.User
+			}
+			text = fmt.Sprintf("[%s] %s", speaker, msg.Text)
+		}
+
+		offset := int(posted.Sub(first).Seconds())
+		t.Events = append(t.Events, Event{TimeOffset: offset, Channel: transcriptChannel, Message: text})
+		t.Incident.DurationSeconds = offset
+	}
+
+	if err := validateTranscript(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Record a Slack channel's conversation into a replayable transcript file
+func runRecord(client *SlackClient, slackChannelID, transcriptChannel, fromStr, toStr, outPath string) error {
+	to := wallClock.Now()
+	if toStr != "" {
+		parsed, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			return fmt.Errorf("invalid -record-to time: %w", err)
+		}
+		to = parsed
+	}
+	from := to.Add(-time.Hour)
+	if fromStr != "" {
+		parsed, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			return fmt.Errorf("invalid -record-from time: %w", err)
+		}
+		from = parsed
+	}
+	if !from.Before(to) {
+		return fmt.Errorf("record window start %s is not before end %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+
+	slog.Info("🎙️  Recording Slack channel", "slack_channel", slackChannelID, "from", from.Format(time.RFC3339), "to", to.Format(time.RFC3339))
+	messages, err := client.ChannelHistory(slackChannelID, from, to)
+	if err != nil {
+		return err
+	}
+
+	t, err := buildRecordedTranscript(messages, slackChannelID, transcriptChannel, from, to)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal transcript: %w", err)
+	}
+	if err := os.WriteFile(outPath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+
+	slog.Info("✅ Recorded transcript", "events", len(t.Events), "path", outPath)
+	return nil
+}
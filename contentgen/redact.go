@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Text that replaces every redacted match
+const redactedText = "[REDACTED]"
+
+// Built-in patterns selectable by name with -redact
+var redactionPresets = map[string]string{
+	"email":   `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	"ip":      `\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`,
+	"aws-key": `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+}
+
+// Redaction applied to events as they are emitted; nil unless -redact or
+// -redact-file is set
+var redactor *redaction
+
+// Patterns whose matches are hidden from everything the replay emits:
+// streams, chat, webhooks, exports and the AI features. The transcript
+// itself is left as written.
+type redaction struct {
+	patterns []*regexp.Regexp
+}
+
+// Build a redaction from comma-separated preset names and a file of custom
+// regular expressions, one per line, with blank lines and # comments skipped
+func newRedaction(presets, file string) (*redaction, error) {
+	r := &redaction{}
+	for _, name := range strings.Split(presets, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		pattern, ok := redactionPresets[name]
+		if !ok {
+			names := make([]string, 0, len(redactionPresets))
+			for preset := range redactionPresets {
+				names = append(names, preset)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown redaction preset %q, expected %s", name, strings.Join(names, ", "))
+		}
+		r.patterns = append(r.patterns, regexp.MustCompile(pattern))
+	}
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redaction file: %w", err)
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			pattern, err := regexp.Compile(text)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid pattern: %w", file, line, err)
+			}
+			r.patterns = append(r.patterns, pattern)
+		}
+	}
+	return r, nil
+}
+
+// Replace every match in text
+func (r *redaction) text(text string) string {
+	if r == nil {
+		return text
+	}
+	for _, pattern := range r.patterns {
+		text = pattern.ReplaceAllLiteralString(text, redactedText)
+	}
+	return text
+}
+
+// Copy of an event with its message, speaker and image alt text redacted.
+// Link and image URLs are kept so they still work.
+func (r *redaction) event(event Event) Event {
+	if r == nil {
+		return event
+	}
+	event.Message = r.text(event.Message)
+	event.Speaker = r.text(event.Speaker)
+	if alt, ok := event.Meta["alt"]; ok {
+		event.Meta = maps.Clone(event.Meta)
+		event.Meta["alt"] = r.text(alt)
+	}
+	return event
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Re-read the startup transcript with the same flags and swap it in. A
+// transcript that fails to load or validate leaves the current one serving.
+// If another transcript was switched to, the reload only replaces what
+// switching back to the default restores; the running replay is untouched.
+func reloadTranscript() error {
+	t, err := prepareTranscript()
+	if err != nil {
+		return err
+	}
+
+	activeMu.Lock()
+	defaultTranscript = t
+	live := activeID == defaultTranscriptID
+	activeMu.Unlock()
+
+	if live {
+		activateTranscript(defaultTranscriptID, t)
+	}
+	slog.Info("🔄 Reloaded transcript", "title", t.Incident.Title, "events", len(t.Events), "active", live)
+	return nil
+}
+
+// Reload the transcript whenever the process gets SIGHUP, for deployments
+// where the HTTP control endpoints are locked down
+func watchReloadSignal() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := reloadTranscript(); err != nil {
+				slog.Error("❌ Failed to reload transcript, keeping the current one", "err", err)
+			}
+		}
+	}()
+	slog.Info("🔄 Transcript reload", "signal", "SIGHUP", "pid", os.Getpid())
+}
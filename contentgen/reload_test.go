@@ -0,0 +1,101 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReloadTranscript(t *testing.T) {
+	useFakeSlack(t)
+	srv := startServer(t, fixtureTranscript())
+	drill := fixtureChannels("metrics")
+	drill.Incident.Title = "Metrics drill"
+	useIncident(t, "drill", drill)
+	previous := transcriptFile
+	t.Cleanup(func() { transcriptFile = previous })
+
+	// Steps run in order against the same server
+	steps := []struct {
+		name     string
+		switchTo string // transcript switched to before reloading, if any
+		file     string
+		err      string // substring of the reload error, empty when it reloads
+		title    string // title new connections see afterwards
+		duration int
+	}{
+		{"invalid transcript keeps the current one", "", "negative_offset.json", "time_offset -5 is negative", "Checkout outage", 20},
+		{"missing file keeps the current one", "", "gone.json", "gone.json", "Checkout outage", 20},
+		{"valid transcript swapped in", "", "interleaved_channels.yaml", "", "Interleaved channels", 60},
+		// Only the default switching back restores is replaced
+		{"while another is active", "drill", "checkout_outage.json", "", "Metrics drill", 20},
+		{"switched back", defaultTranscriptID, "", "", "Checkout outage", 120},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if step.switchTo != "" {
+				if status, body := control(t, http.MethodPost, srv.URL+"/transcript?id="+step.switchTo, ""); status != http.StatusOK {
+					t.Fatalf("switch to %s: status %d: %s", step.switchTo, status, body)
+				}
+			}
+			if step.file != "" {
+				transcriptFile = filepath.Join("testdata", step.file)
+				err := reloadTranscript()
+				if step.err == "" && err != nil {
+					t.Fatalf("reload %s: %v", step.file, err)
+				}
+				if step.err != "" && (err == nil || !strings.Contains(err.Error(), step.err)) {
+					t.Fatalf("reload %s: error %v, want it to mention %q", step.file, err, step.err)
+				}
+			}
+			got := getIncident(t, srv.URL)
+			if got.Title != step.title || got.DurationSeconds != step.duration {
+				t.Errorf("serving %q for %ds, want %q for %ds", got.Title, got.DurationSeconds, step.title, step.duration)
+			}
+		})
+	}
+}
+
+// A SIGHUP reload renames the incident in chat posts while the outbox may
+// be publishing; run with -race to check the two don't race
+func TestReloadTranscriptWhilePublishing(t *testing.T) {
+	useFakeSlack(t)
+	slackClient.BlockKit = true
+	startServer(t, fixtureTranscript())
+	previous := transcriptFile
+	t.Cleanup(func() { transcriptFile = previous })
+	// The default logger's lock would order the two goroutines and hide a
+	// race from the detector
+	previousLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	t.Cleanup(func() { slog.SetDefault(previousLogger) })
+
+	// Publish the way the outbox worker would, reloading once it's under way
+	started, published := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(published)
+		for i := 0; i < 50; i++ {
+			if err := slackClient.Publish(Event{TimeOffset: i, Channel: "team", Message: "Rolling back"}); err != nil {
+				t.Errorf("publish %d: %v", i, err)
+			}
+			if i == 1 {
+				close(started)
+			}
+		}
+	}()
+	<-started
+	files := []string{"interleaved_channels.yaml", "checkout_outage.json"}
+	for i := 0; i < 10; i++ {
+		transcriptFile = filepath.Join("testdata", files[i%2])
+		if err := reloadTranscript(); err != nil {
+			t.Fatalf("reload %s: %v", transcriptFile, err)
+		}
+	}
+	<-published
+
+	if title, _ := slackClient.incident(); title != "Checkout outage" {
+		t.Errorf("posting under %q after the reloads, want %q", title, "Checkout outage")
+	}
+}
@@ -0,0 +1,1181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of undelivered messages a client may fall behind before it is dropped
+const subscriberBuffer = 64
+
+// Number of emitted events kept for export; the oldest are dropped first
+const emittedLogLimit = 10000
+
+// Longest wall time the clock waits for the next event before skipping the
+// rest of the gap, from -max-quiet; zero waits out every gap
+var maxQuietWait time.Duration
+
+// Kinds of message a subscriber can receive
+type messageKind int
+
+const (
+	messageEvent           messageKind = iota
+	messageComplete                    // no more events will be emitted on this channel
+	messageChannelComplete             // combined streams: Event.Channel emitted its last event, others still play
+	messageRestarted                   // the timeline started over from the beginning
+	messageSeeked                      // the timeline jumped to Event.TimeOffset
+	messageBackfill                    // an event emitted before the client joined, sent with ?catchup=true
+	messageAnnotated                   // a facilitator dropped an annotation, sent on every channel
+	messageGapBegan                    // stream only: metrics went dark for the telemetry gap
+	messageGapEnded                    // stream only: metrics are back after the telemetry gap
+	messageQuietSkip                   // the clock skipped Skipped virtual seconds of dead air
+	messageProgress                    // stream only: how far the timeline has played, in Progress
+	messagePaused                      // a facilitator froze the clocks
+	messageResumed                     // the clocks started again after a pause
+	messageChatFailed                  // the outbox couldn't publish Event to chat, as Chat says
+)
+
+// Message fanned out from the replay to each subscribed client
+type replayMessage struct {
+	Kind      messageKind
+	Event     Event
+	Published bool          // the event reached the chat backend
+	Skipped   int           // backfill: earlier events left out before this one; quiet skip: seconds skipped
+	Mark      annotation    // annotated: the label and note dropped
+	Chat      chatResult    // event: what became of its chat publish; chat failed: why it didn't go through
+	Repeats   int           // event: a ?dedupe rollup standing for this many similar lines
+	Progress  progressFrame // progress: the frame to send
+}
+
+// An event as it was actually emitted by the shared replay
+type emittedEvent struct {
+	Time      time.Time `json:"time"`
+	Offset    int       `json:"offset_seconds"`
+	Channel   string    `json:"channel"`
+	Message   string    `json:"message"`
+	Published bool      `json:"published"`      // reached the chat backend
+	Kind      string    `json:"kind,omitempty"` // "annotation" for facilitator bookmarks, empty for events
+	Note      string    `json:"note,omitempty"` // annotation: the facilitator's note
+}
+
+// A client attached to one or more transcript channels of the replay
+type subscriber struct {
+	channels []string
+	ch       chan replayMessage
+}
+
+// Report whether the subscriber receives a channel's messages
+func (s *subscriber) follows(channel string) bool {
+	return slices.Contains(s.channels, channel)
+}
+
+// Incident time advancing at a playback speed from an anchor
+type virtualClock struct {
+	anchorWall    time.Time
+	anchorVirtual float64
+	anchorSpeed   float64
+}
+
+// Virtual incident time in seconds at the given wall time
+func (c *virtualClock) at(now time.Time) float64 {
+	return c.anchorVirtual + now.Sub(c.anchorWall).Seconds()*c.anchorSpeed
+}
+
+// Keep the current virtual time but advance at a new speed from now on
+func (c *virtualClock) reanchor(now time.Time, speed float64) {
+	c.anchorVirtual = c.at(now)
+	c.anchorWall = now
+	c.anchorSpeed = speed
+}
+
+// One channel's part of the timeline, advanced by its own clock so channels
+// can play at different speeds
+type lane struct {
+	virtualClock
+	events  []Event // in play order
+	next    int     // index of the next event to fire
+	credits int     // step mode: events released by /advance but not fired yet
+	delay   float64 // humanize mode: extra virtual seconds before the next event
+	rolled  bool    // delay has been drawn for the next event
+}
+
+// Virtual time at which the next event fires: when it is due plus any
+// humanize delay, drawn once per event from the gap to the event after it
+func (ln *lane) fireAt(rp *replay) float64 {
+	due := rp.dueLocked(ln.events[ln.next])
+	if !ln.rolled {
+		ln.delay = 0
+		if ln.next+1 < len(ln.events) {
+			ln.delay = rp.jitter.delay(rp.dueLocked(ln.events[ln.next+1]) - due)
+		}
+		ln.rolled = true
+	}
+	return due + ln.delay
+}
+
+// Events not yet emitted on this lane
+func (ln *lane) remaining() int {
+	return len(ln.events) - ln.next
+}
+
+// Shared incident replay: one server-side clock per channel advances the
+// timeline and broadcasts every event to all subscribers of its channel.
+type replay struct {
+	mu          sync.Mutex
+	events      []Event          // full timeline ordered by TimeOffset
+	lanes       map[string]*lane // per-channel timelines
+	channels    []string         // lane order, by first appearance in the timeline
+	position    int              // events fired so far on this pass
+	subscribers map[*subscriber]struct{}
+	wake        chan struct{} // interrupts the clock's wait after a seek or inject
+	startAt     time.Time     // scheduled wall time for the clock to start, if any
+	started     bool
+	completed   bool
+	paused      bool                 // clocks frozen by a facilitator until resumed
+	pass        int                  // completed loops, for loop mode
+	log         []emittedEvent       // realized timeline, across seeks and loops
+	annotations []annotation         // facilitator bookmarks on this run
+	runID       string               // identifies this replay in external dedup keys
+	title       string               // incident title reported to lifecycle webhooks
+	published   map[string]bool      // events already sent to external integrations this run
+	viewers     map[string]int       // connected stream clients per channel, including ones not subscribed yet
+	clients     map[string][]*replay // private timelines of viewers streaming with ?client=<id>, by id
+
+	speed    *speedControl // playback speed of the incident this replay belongs to
+	external bool          // publish to Slack and the other outbound integrations
+	dice     *chaosDice    // drops and delays events in chaos mode, nil otherwise
+	jitter   *humanizer    // varies event timing in humanize mode, nil otherwise
+	retired  bool          // replaced as the primary incident; finishes its pass without publishing
+	timing   timingStats   // how late events fired against their schedule
+
+	// Per-connection playback never publishes, loops or counts toward metrics
+	private bool
+	reverse bool    // play from last to first, timed by the gaps between offsets
+	mirror  int     // reverse mode: offset of the first event played
+	begin   float64 // virtual offset the timeline starts from, and restarts go back to
+	client  string  // id of the ?client=<id> stream steering this private timeline, if any
+
+	// Canceled to stop a private replay; the shared replay's never ends
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Clock goroutines still running, so a caller can wait them out
+	running sync.WaitGroup
+
+	// Default clock, at the speed of channels without an override; new
+	// lanes start from it
+	clock virtualClock
+}
+
+// Create a replay for the given events; the clock starts with the first subscriber.
+// Only an external replay reaches Slack and the other integrations.
+func newReplay(events []Event, speed *speedControl, external bool) *replay {
+	timeline := make([]Event, len(events))
+	copy(timeline, events)
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].TimeOffset < timeline[j].TimeOffset
+	})
+
+	rp := &replay{
+		events:      timeline,
+		subscribers: make(map[*subscriber]struct{}),
+		viewers:     make(map[string]int),
+		clients:     make(map[string][]*replay),
+		wake:        make(chan struct{}, 1),
+		speed:       speed,
+		external:    external,
+		runID:       strconv.FormatInt(wallClock.Now().Unix(), 10),
+		ctx:         context.Background(),
+		startAt:     replayStartAt,
+	}
+	rp.resetLocked()
+	return rp
+}
+
+// Create a replay for a single connection. In reverse mode events play from
+// last to first, each waiting for the gap to the previously played offset.
+// A non-negative start skips events before that incident offset (after it,
+// in reverse) and begins the clock there.
+func newPrivateReplay(events []Event, speed *speedControl, reverse bool, start int) *replay {
+	rp := newReplay(events, speed, false)
+	rp.private = true
+	rp.startAt = time.Time{}
+	rp.ctx, rp.cancel = context.WithCancel(context.Background())
+
+	switch {
+	case reverse:
+		rp.reverse = true
+		slices.Reverse(rp.events)
+		if start >= 0 {
+			rp.mirror = start
+		} else if len(rp.events) > 0 {
+			rp.mirror = rp.events[0].TimeOffset
+		}
+		rp.seekLocked(0)
+	case start >= 0:
+		rp.begin = float64(start)
+		rp.seekLocked(rp.begin)
+	}
+	return rp
+}
+
+// Track a viewer's private timeline under its client id, so per-client
+// controls reach it. A client may have several streams open at once.
+func (rp *replay) addClient(id string, private *replay) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.clients[id] = append(rp.clients[id], private)
+}
+
+// Forget a private timeline once its stream ends
+func (rp *replay) removeClient(id string, private *replay) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.clients[id] = slices.DeleteFunc(rp.clients[id], func(r *replay) bool { return r == private })
+	if len(rp.clients[id]) == 0 {
+		delete(rp.clients, id)
+	}
+}
+
+// Private timelines a client has open, one per stream
+func (rp *replay) clientReplays(id string) []*replay {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return slices.Clone(rp.clients[id])
+}
+
+// Hand the top-level routes to another replay: this one keeps serving the
+// streams already attached to it, but no longer publishes or loops
+func (rp *replay) retire() {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.retired = true
+	rp.external = false
+}
+
+// Send a lifecycle signal for a shared replay that is still the primary
+// incident or a room; private and retired replays stay quiet
+func (rp *replay) notifyLifecycleLocked(event, channel string) {
+	if rp.private || rp.retired {
+		return
+	}
+	lifecycleHooks.Notify(event, rp.title, channel)
+}
+
+// Stop a private replay once its connection goes away
+func (rp *replay) stop() {
+	rp.cancel()
+}
+
+// Rewind the timeline and per-channel counters to the beginning
+func (rp *replay) resetLocked() {
+	rp.seekLocked(rp.begin)
+	rp.published = make(map[string]bool)
+	rp.annotations = nil
+	rp.dice = newChaosDice(chaos)
+	rp.jitter = newHumanizer(humanize)
+	if rp.external {
+		slackClient.ResetThreads()
+	}
+}
+
+// Claim an event for external publishing, at most once per replay run, so
+// seeking back and replaying a stretch doesn't post the same messages again
+func (rp *replay) claimPublishLocked(event Event) bool {
+	key := fmt.Sprintf("%d|%s|%s", event.TimeOffset, event.Channel, event.Message)
+	if rp.published[key] {
+		return false
+	}
+	rp.published[key] = true
+	return true
+}
+
+// Attach a client to a channel, starting the replay clock if needed.
+// Clients joining mid-incident receive events from the current position onward.
+func (rp *replay) subscribe(channel string) *subscriber {
+	sub, _ := rp.subscribeWithBacklog([]string{channel})
+	return sub
+}
+
+// Attach a client like subscribe, to one or more channels, also returning
+// their events already emitted on this pass in timeline order. Both happen
+// under one lock, so the backlog and the live feed neither overlap nor leave
+// a gap.
+func (rp *replay) subscribeWithBacklog(channels []string) (*subscriber, []Event) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	var backlog []Event
+	for _, channel := range channels {
+		if ln, ok := rp.lanes[channel]; ok {
+			backlog = append(backlog, ln.events[:ln.next]...)
+		}
+	}
+	sort.SliceStable(backlog, func(i, j int) bool {
+		return backlog[i].TimeOffset < backlog[j].TimeOffset
+	})
+
+	sub := &subscriber{channels: channels, ch: make(chan replayMessage, subscriberBuffer)}
+	rp.subscribers[sub] = struct{}{}
+
+	for _, channel := range channels {
+		if rp.remainingLocked(channel) == 0 {
+			rp.channelCompleteLocked(sub, channel)
+		}
+	}
+	if rp.remainingForLocked(sub) == 0 {
+		sub.ch <- replayMessage{Kind: messageComplete}
+	}
+
+	if !rp.started {
+		rp.startLocked()
+	}
+	return sub, backlog
+}
+
+// Start the clock. Past a scheduled start time, the timeline begins at
+// the position it would have reached by now.
+func (rp *replay) startLocked() {
+	rp.started = true
+	if !rp.startAt.IsZero() {
+		if late := wallClock.Now().Sub(rp.startAt); late > 0 {
+			rp.seekLocked(math.Floor(late.Seconds() * rp.speed.get("")))
+		}
+	}
+	rp.syncSpeedLocked(wallClock.Now())
+	rp.notifyLifecycleLocked(hookStart, "")
+	rp.goRun()
+}
+
+// Start the clock at the scheduled time, even if nobody is watching yet
+func (rp *replay) scheduleStart() {
+	waitUntil(context.Background(), rp.startAt)
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if !rp.started {
+		rp.startLocked()
+	}
+}
+
+// Detach a client from the replay
+func (rp *replay) unsubscribe(sub *subscriber) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	delete(rp.subscribers, sub)
+}
+
+// Count a stream client connecting to a channel and return the new total
+func (rp *replay) connect(channel string) int {
+	connectedClientsGauge.WithLabelValues(channel).Inc()
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.viewers[channel]++
+	return rp.viewers[channel]
+}
+
+// Count a stream client leaving a channel and return the new total
+func (rp *replay) disconnect(channel string) int {
+	connectedClientsGauge.WithLabelValues(channel).Dec()
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.viewers[channel]--
+	count := rp.viewers[channel]
+	if count == 0 {
+		delete(rp.viewers, channel)
+	}
+	return count
+}
+
+// Report whether events wait for /advance instead of their time offsets.
+// Private timelines stay timed unless a client steers them, since nobody
+// else can advance them.
+func (rp *replay) stepping() bool {
+	return stepMode && (!rp.private || rp.client != "")
+}
+
+// Release the next unreleased event of a channel in step mode and return
+// it, or report false when the channel has nothing left to release
+func (rp *replay) advance(channel string) (Event, bool) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	ln, ok := rp.lanes[channel]
+	if !ok || ln.remaining() <= ln.credits {
+		return Event{}, false
+	}
+	event := ln.events[ln.next+ln.credits]
+	ln.credits++
+	if rp.private {
+		slog.Debug("👣 Advanced replay", "channel", channel, "offset", event.TimeOffset, "released", ln.credits, "client", rp.client, "private", true)
+	} else {
+		slog.Info("👣 Advanced replay", "channel", channel, "offset", event.TimeOffset, "released", ln.credits)
+	}
+	rp.resumeLocked()
+	return event, true
+}
+
+// Current virtual incident time in seconds on the default clock
+func (rp *replay) virtualTimeLocked(now time.Time) float64 {
+	return rp.clock.at(now)
+}
+
+// Current incident offset in seconds: zero before the clock starts, and in
+// step mode, where the clock doesn't drive the replay, the furthest event fired
+func (rp *replay) offsetLocked(now time.Time) float64 {
+	if !rp.started {
+		return 0
+	}
+	if !rp.stepping() {
+		return rp.virtualTimeLocked(now)
+	}
+	offset := 0.0
+	for _, ln := range rp.lanes {
+		if ln.next > 0 {
+			offset = math.Max(offset, rp.dueLocked(ln.events[ln.next-1]))
+		}
+	}
+	return offset
+}
+
+// Events not yet emitted on a channel
+func (rp *replay) remainingLocked(channel string) int {
+	ln, ok := rp.lanes[channel]
+	if !ok {
+		return 0
+	}
+	return ln.remaining()
+}
+
+// Count the events still to fire across every channel a subscriber follows
+func (rp *replay) remainingForLocked(sub *subscriber) int {
+	remaining := 0
+	for _, channel := range sub.channels {
+		remaining += rp.remainingLocked(channel)
+	}
+	return remaining
+}
+
+// Tell a channel's subscribers it has finished, once every other channel
+// they follow has finished too. Subscribers of several channels also hear
+// about each channel as it finishes, the overall completion coming last.
+func (rp *replay) completeLocked(channel string) {
+	for sub := range rp.subscribers {
+		if !sub.follows(channel) {
+			continue
+		}
+		rp.channelCompleteLocked(sub, channel)
+		if rp.remainingForLocked(sub) == 0 {
+			rp.sendLocked(sub, replayMessage{Kind: messageComplete})
+		}
+	}
+	rp.notifyLifecycleLocked(hookChannelComplete, channel)
+}
+
+// Tell a subscriber of several channels that one of them has finished;
+// single-channel subscribers only need the overall completion
+func (rp *replay) channelCompleteLocked(sub *subscriber, channel string) {
+	if len(sub.channels) > 1 {
+		rp.sendLocked(sub, replayMessage{Kind: messageChannelComplete, Event: Event{Channel: channel}})
+	}
+}
+
+// Get a channel's lane, starting a new one from the default clock if needed
+func (rp *replay) laneLocked(channel string, now time.Time) *lane {
+	if ln, ok := rp.lanes[channel]; ok {
+		return ln
+	}
+	ln := &lane{virtualClock: rp.clock}
+	if rp.started {
+		ln.reanchor(now, rp.speedLocked(channel))
+	}
+	rp.lanes[channel] = ln
+	rp.channels = append(rp.channels, channel)
+	return ln
+}
+
+// Virtual time at which an event is due
+func (rp *replay) dueLocked(event Event) float64 {
+	if rp.reverse {
+		return float64(rp.mirror - event.TimeOffset)
+	}
+	return float64(event.TimeOffset)
+}
+
+// Re-anchor the virtual clocks whose playback speed changed
+func (rp *replay) syncSpeed() {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.syncSpeedLocked(wallClock.Now())
+}
+
+func (rp *replay) syncSpeedLocked(now time.Time) {
+	if speed := rp.speedLocked(""); speed != rp.clock.anchorSpeed {
+		rp.clock.reanchor(now, speed)
+	}
+	for channel, ln := range rp.lanes {
+		if speed := rp.speedLocked(channel); speed != ln.anchorSpeed {
+			ln.reanchor(now, speed)
+		}
+	}
+}
+
+// Speed a channel's clock runs at, or the default clock's for "": zero
+// while paused, so incident time stands still
+func (rp *replay) speedLocked(channel string) float64 {
+	if rp.paused {
+		return 0
+	}
+	return rp.speed.get(channel)
+}
+
+// Freeze or unfreeze every clock of the replay, reporting whether that
+// changed anything. Streams stay connected, are told about the pause, and
+// see no events until the replay resumes from the same incident time.
+func (rp *replay) pause(paused bool) bool {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.paused == paused {
+		return false
+	}
+	rp.paused = paused
+	rp.syncSpeedLocked(wallClock.Now())
+	select {
+	case rp.wake <- struct{}{}:
+	default:
+	}
+	kind := messageResumed
+	if paused {
+		kind = messagePaused
+		slog.Info("⏸️  Replay paused", "offset", int(rp.offsetLocked(wallClock.Now())))
+	} else {
+		slog.Info("▶️  Replay resumed", "offset", int(rp.offsetLocked(wallClock.Now())))
+	}
+	for sub := range rp.subscribers {
+		rp.sendLocked(sub, replayMessage{Kind: kind})
+	}
+	return true
+}
+
+// Run the clock on a goroutine of its own
+func (rp *replay) goRun() {
+	rp.running.Add(1)
+	go func() {
+		defer rp.running.Done()
+		rp.run()
+	}()
+}
+
+// Block until the clock has stopped: finished its run, or been stopped
+func (rp *replay) wait() {
+	rp.running.Wait()
+}
+
+// Advance the timeline until every event has been emitted, starting
+// over from the beginning each time in loop mode
+func (rp *replay) run() {
+	if rp.private {
+		rp.playTimeline()
+		return
+	}
+
+	slog.Info("▶️  Incident replay started", "events", len(rp.events), "speed", rp.speed.get(""))
+
+	for {
+		rp.playTimeline()
+		slog.Info("✅ Incident replay completed")
+
+		rp.mu.Lock()
+		rp.notifyLifecycleLocked(hookComplete, "")
+		retired := rp.retired
+		rp.mu.Unlock()
+
+		// Digests hold the last window's events until the run is over. The
+		// flush queues behind the final events so the digest includes them.
+		if digest, ok := chatNotifier.(*slackDigest); ok && rp.external && !retired {
+			if !eventOutbox.enqueue(outboxJob{replay: rp, digest: digest}) {
+				digest.flush()
+			}
+		}
+		if !loopReplay || retired {
+			return
+		}
+		rp.restart()
+	}
+}
+
+// Start the timeline over and tell every subscriber about it
+func (rp *replay) restart() {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	rp.pass++
+	rp.resetLocked()
+	slog.Info("🔁 Restarting incident replay", "loop", rp.pass+1)
+	rp.announceRestartLocked()
+	rp.notifyLifecycleLocked(hookStart, "")
+}
+
+// Start the timeline over on request and return how many subscribers were
+// restarted. With republish the new run publishes externally again, as a
+// fresh run; otherwise events already published stay that way.
+func (rp *replay) rewind(republish bool) int {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	published := rp.published
+	rp.resetLocked()
+	if republish {
+		rp.pass = 0
+		rp.runID = strconv.FormatInt(wallClock.Now().Unix(), 10)
+	} else {
+		rp.published = published
+	}
+	if rp.private {
+		slog.Debug("⏮️  Restarted incident replay on request", "streams", len(rp.subscribers), "private", true)
+	} else {
+		slog.Info("⏮️  Restarted incident replay on request", "republish", republish, "streams", len(rp.subscribers))
+	}
+
+	restarted := len(rp.subscribers)
+	rp.announceRestartLocked()
+	if rp.started {
+		rp.notifyLifecycleLocked(hookStart, "")
+		rp.resumeLocked()
+	}
+	return restarted
+}
+
+// Tell every subscriber the timeline started over
+func (rp *replay) announceRestartLocked() {
+	for sub := range rp.subscribers {
+		rp.sendLocked(sub, replayMessage{Kind: messageRestarted})
+		if rp.remainingForLocked(sub) == 0 {
+			rp.sendLocked(sub, replayMessage{Kind: messageComplete})
+		}
+	}
+}
+
+// Fire events in order until the end of the timeline, always taking the
+// channel whose next event is due soonest on its own clock
+func (rp *replay) playTimeline() {
+	for {
+		select {
+		case <-rp.ctx.Done():
+			return
+		default:
+		}
+
+		changed := rp.speed.changes()
+		rp.syncSpeed()
+
+		rp.mu.Lock()
+		if rp.paused {
+			// Hold until resumed; the clocks aren't moving, so nothing comes due
+			rp.mu.Unlock()
+			select {
+			case <-rp.wake:
+			case <-rp.ctx.Done():
+				return
+			}
+			continue
+		}
+		now := wallClock.Now()
+		stepping := rp.stepping()
+		var next *lane
+		var waitDuration time.Duration
+		held := false // step mode: events remain, but none has been released
+		for _, channel := range rp.channels {
+			ln := rp.lanes[channel]
+			if ln.remaining() == 0 {
+				continue
+			}
+			if stepping {
+				// Released events fire at once, earliest in the timeline first
+				if ln.credits == 0 {
+					held = true
+				} else if next == nil || rp.dueLocked(ln.events[ln.next]) < rp.dueLocked(next.events[next.next]) {
+					next = ln
+				}
+				continue
+			}
+			virtualWait := ln.fireAt(rp) - ln.at(now)
+			wait := time.Duration(virtualWait * float64(time.Second) / ln.anchorSpeed)
+			if next == nil || wait < waitDuration {
+				next, waitDuration = ln, wait
+			}
+		}
+		if next != nil && !stepping && maxQuietWait > 0 && waitDuration > maxQuietWait {
+			rp.skipQuietLocked(now, waitDuration-maxQuietWait)
+			waitDuration = maxQuietWait
+		}
+		if next == nil && held {
+			// Hold until /advance releases an event, or a seek or restart
+			// reshapes the timeline
+			rp.mu.Unlock()
+			select {
+			case <-rp.wake:
+			case <-rp.ctx.Done():
+				return
+			}
+			continue
+		}
+		if next == nil {
+			// Mark completion under the same lock so a concurrent seek
+			// knows whether it needs to start the clock again
+			rp.completed = !loopReplay || rp.private
+			rp.mu.Unlock()
+			return
+		}
+
+		// Claim the event while still holding the lock, so a concurrent seek or
+		// inject can't shift the timeline between the due check and the emit
+		if waitDuration < time.Millisecond {
+			event := next.events[next.next]
+			next.next++
+			next.rolled = false
+			if stepping {
+				next.credits--
+			}
+			rp.position++
+			index := rp.position - 1
+			if !stepping && !rp.private {
+				rp.timing.record(-waitDuration)
+			}
+			drop, delay := rp.dice.roll()
+			publish := !drop && rp.external && (rp.pass == 0 || loopSlack) && rp.claimPublishLocked(event)
+			rp.mu.Unlock()
+
+			if drop {
+				rp.dropChaos(event, index)
+				continue
+			}
+			if delay > 0 {
+				if waitUntil(rp.ctx, wallClock.Now().Add(delay)) != nil {
+					return
+				}
+			}
+			rp.fire(event, index, publish)
+			continue
+		}
+		rp.mu.Unlock()
+
+		// Wait until it's time for this event, waking early on speed changes,
+		// seeks and injected events
+		timer := wallClock.NewTimer(waitDuration)
+		select {
+		case <-changed:
+		case <-rp.wake:
+		case <-rp.ctx.Done():
+			// Checked at the top of the loop
+		case <-timer.C():
+			// Time to fire the event
+		}
+		timer.Stop()
+	}
+}
+
+// Fast-forward through dead air: move every clock on by the same wall time,
+// so channels stay in order relative to each other, and tell each channel
+// still playing how much of its incident time was skipped
+func (rp *replay) skipQuietLocked(now time.Time, skip time.Duration) {
+	rp.clock.anchorWall = rp.clock.anchorWall.Add(-skip)
+	skipped := make(map[string]int)
+	for _, channel := range rp.channels {
+		ln := rp.lanes[channel]
+		ln.anchorWall = ln.anchorWall.Add(-skip)
+		// Trimming a gap by less than a second isn't worth a banner
+		if seconds := int(math.Round(skip.Seconds() * ln.anchorSpeed)); ln.remaining() > 0 && seconds > 0 {
+			skipped[channel] = seconds
+		}
+	}
+	// Subscribers following several channels hear about the skip once
+	for sub := range rp.subscribers {
+		for _, channel := range sub.channels {
+			if seconds, ok := skipped[channel]; ok {
+				rp.sendLocked(sub, replayMessage{Kind: messageQuietSkip, Skipped: seconds})
+				break
+			}
+		}
+	}
+	if !rp.private {
+		slog.Info("⏩ Skipped quiet stretch", "seconds", math.Round(skip.Seconds()*rp.clock.anchorSpeed), "offset", int(rp.virtualTimeLocked(now)))
+	}
+}
+
+// Interrupt the clock's current wait, or start it again if it already finished
+func (rp *replay) resumeLocked() {
+	select {
+	case rp.wake <- struct{}{}:
+	default:
+	}
+	if rp.completed {
+		rp.completed = false
+		rp.goRun()
+	}
+}
+
+// Reposition the replay to the first event at or after offset. Earlier
+// events are skipped without firing or publishing.
+func (rp *replay) seek(offset int) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	previous := make(map[string]int)
+	for channel, ln := range rp.lanes {
+		previous[channel] = ln.remaining()
+	}
+	rp.seekLocked(float64(offset))
+	slog.Info("⏩ Seeked replay", "offset", offset, "index", rp.position)
+
+	for sub := range rp.subscribers {
+		rp.sendLocked(sub, replayMessage{Kind: messageSeeked, Event: Event{TimeOffset: offset}})
+		before := 0
+		for _, channel := range sub.channels {
+			before += previous[channel]
+			if previous[channel] > 0 && rp.remainingLocked(channel) == 0 {
+				rp.channelCompleteLocked(sub, channel)
+			}
+		}
+		if rp.remainingForLocked(sub) == 0 && before > 0 {
+			rp.sendLocked(sub, replayMessage{Kind: messageComplete})
+		}
+	}
+
+	rp.resumeLocked()
+}
+
+// Move every clock to a virtual time, skipping every event due before it
+func (rp *replay) seekLocked(virtual float64) {
+	now := wallClock.Now()
+	rp.clock.anchorWall = now
+	rp.clock.anchorVirtual = virtual
+
+	rp.lanes = make(map[string]*lane)
+	rp.channels = nil
+	rp.position = 0
+	for _, event := range rp.events {
+		ln := rp.laneLocked(event.Channel, now)
+		ln.events = append(ln.events, event)
+		if rp.dueLocked(event) < virtual {
+			ln.next++
+			rp.position++
+		}
+	}
+}
+
+// Add an event to the live timeline at the given offset, or at the current
+// virtual time when offset is negative, and return the offset it was given.
+// Events can't be scheduled in the past, so earlier offsets fire right away.
+func (rp *replay) inject(event Event, offset int) int {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	// Each channel runs on its own clock, so "now" is the channel's time
+	ln := rp.laneLocked(event.Channel, wallClock.Now())
+	now := 0
+	if rp.started {
+		now = int(math.Ceil(ln.at(wallClock.Now())))
+	}
+	if offset < now {
+		offset = now
+	}
+	event.TimeOffset = offset
+
+	// Insert after any event with the same offset
+	index := sort.Search(len(rp.events), func(i int) bool {
+		return rp.events[i].TimeOffset > offset
+	})
+	rp.events = slices.Insert(rp.events, index, event)
+
+	// The channel's own timeline never puts it behind the clock
+	at := sort.Search(len(ln.events), func(i int) bool {
+		return ln.events[i].TimeOffset > offset
+	})
+	if at < ln.next {
+		at = ln.next
+	}
+	ln.events = slices.Insert(ln.events, at, event)
+	if at <= ln.next+1 {
+		// The next event, or the gap after it, changed
+		ln.rolled = false
+	}
+
+	slog.Info("💉 Injected event", "channel", event.Channel, "offset", offset, "index", index, "message", event.Message)
+	rp.resumeLocked()
+	return offset
+}
+
+// Emit one claimed event: publish it externally once, then broadcast to subscribers
+func (rp *replay) fire(event Event, index int, publish bool) {
+	event = redactor.event(event)
+	published := false
+	var chat chatResult
+	job := outboxJob{replay: rp, event: event, index: index}
+	// In loop mode only the first pass reaches chat unless explicitly enabled
+	if rp.private {
+		slog.Debug(fmt.Sprintf("[%s] %s", strings.ToUpper(event.Channel), event.displayText()), "channel", event.Channel, "index", index, "offset", event.TimeOffset, "private", true)
+	} else if publish && notifierRoutes(chatNotifier, event.Channel) {
+		// The outbox posts it; failures reach the streams once it has tried
+		job.chat = chatNotifier
+		chat = chatResult{Backend: chatNotifier.Name(), Status: "queued"}
+		published = true
+	} else {
+		slog.Info(fmt.Sprintf("[%s] %s", strings.ToUpper(event.Channel), event.displayText()), "channel", event.Channel, "index", index, "offset", event.TimeOffset)
+	}
+
+	// Generic webhooks follow the same publishing rules as Slack
+	if publish {
+		webhookSink.Publish(event)
+	}
+
+	// Page on critical metrics and resolve on recovery, on passes that publish
+	if publish && pagerDutyClient.Enabled() {
+		rp.mu.Lock()
+		job.pager, job.dedupKey = pagerDutyClient, fmt.Sprintf("contentgen-%s-%d", rp.runID, rp.pass)
+		rp.mu.Unlock()
+	}
+
+	// Network I/O never happens on the clock goroutine
+	if (job.chat != nil || job.pager != nil) && !eventOutbox.enqueue(job) {
+		slog.Warn("⚠️  Outbox full, dropping chat and PagerDuty delivery", "channel", event.Channel, "index", index, "offset", event.TimeOffset)
+		if job.chat != nil {
+			chatPublishCounter.WithLabelValues(chat.Backend, "dropped").Inc()
+			chat.Status, published = "dropped", false
+		}
+	}
+
+	if !rp.private {
+		eventsEmittedCounter.WithLabelValues(event.Channel).Inc()
+	}
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if !rp.private {
+		if len(rp.log) == emittedLogLimit {
+			rp.log = rp.log[1:]
+		}
+		rp.log = append(rp.log, emittedEvent{
+			Time:      wallClock.Now(),
+			Offset:    event.TimeOffset,
+			Channel:   event.Channel,
+			Message:   event.Message,
+			Published: published,
+		})
+	}
+
+	rp.broadcastLocked(event.Channel, replayMessage{Kind: messageEvent, Event: event, Published: published, Chat: chat})
+	if rp.remainingLocked(event.Channel) == 0 {
+		rp.completeLocked(event.Channel)
+	}
+
+	// Responses join the shared timeline, so later loops replay them instead of asking again
+	if rp.external && rp.pass == 0 && wantsCommanderResponse(event) {
+		go respondAsCommander(rp, event)
+	}
+}
+
+// Record a chat publish that didn't go through once the outbox has tried it:
+// the event's log entry is marked unpublished and its channel's streams are told
+func (rp *replay) reportChat(event Event, chat chatResult) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	for i := len(rp.log) - 1; i >= 0; i-- {
+		entry := &rp.log[i]
+		if entry.Kind == "" && entry.Channel == event.Channel && entry.Offset == event.TimeOffset && entry.Message == event.Message {
+			entry.Published = false
+			break
+		}
+	}
+	rp.broadcastLocked(event.Channel, replayMessage{Kind: messageChatFailed, Event: event, Chat: chat})
+}
+
+// Skip an event lost to chaos mode: nothing is published or broadcast, but
+// subscribers still learn when it was the last one on their channel
+func (rp *replay) dropChaos(event Event, index int) {
+	if rp.private {
+		slog.Debug("🎲 Chaos dropped event", "channel", event.Channel, "index", index, "offset", event.TimeOffset, "private", true)
+	} else {
+		slog.Info("🎲 Chaos dropped event", "channel", event.Channel, "index", index, "offset", event.TimeOffset)
+	}
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.remainingLocked(event.Channel) == 0 {
+		rp.completeLocked(event.Channel)
+	}
+}
+
+// Deliver a message to every subscriber of a channel
+func (rp *replay) broadcastLocked(channel string, msg replayMessage) {
+	for sub := range rp.subscribers {
+		if sub.follows(channel) {
+			rp.sendLocked(sub, msg)
+		}
+	}
+}
+
+// Deliver a message to one subscriber without blocking the clock
+func (rp *replay) sendLocked(sub *subscriber, msg replayMessage) {
+	if _, ok := rp.subscribers[sub]; !ok {
+		// Already dropped earlier in the same broadcast
+		return
+	}
+	select {
+	case sub.ch <- msg:
+	default:
+		// Client can't keep up; drop it rather than stall everyone else
+		if _, ok := rp.subscribers[sub]; ok {
+			delete(rp.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Copy of the events emitted so far, oldest first
+func (rp *replay) emittedLog() []emittedEvent {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return slices.Clone(rp.log)
+}
+
+// Running tally of how late the clock fires events, for spotting a server
+// that can't keep up at high speeds or with dense transcripts
+type timingStats struct {
+	events int
+	total  time.Duration
+	max    time.Duration
+}
+
+// Record how far past its due time an event fired; early by less than the
+// clock's tolerance counts as on time
+func (t *timingStats) record(late time.Duration) {
+	late = max(late, 0)
+	t.events++
+	t.total += late
+	t.max = max(t.max, late)
+	eventLatenessHistogram.Observe(late.Seconds())
+}
+
+// Lateness figures for the status endpoint, nil before any event fired
+func (t *timingStats) status() *timingStatus {
+	if t.events == 0 {
+		return nil
+	}
+	return &timingStatus{
+		Events:            t.events,
+		AverageLatenessMs: math.Round(float64(t.total)/float64(t.events)/float64(time.Millisecond)*100) / 100,
+		MaxLatenessMs:     math.Round(float64(t.max)/float64(time.Millisecond)*100) / 100,
+	}
+}
+
+// Timing accuracy of the events fired so far, in wall milliseconds past due
+type timingStatus struct {
+	Events            int     `json:"events"`
+	AverageLatenessMs float64 `json:"avg_lateness_ms"`
+	MaxLatenessMs     float64 `json:"max_lateness_ms"`
+}
+
+// Per-channel replay progress
+type channelStatus struct {
+	Emitted   int     `json:"emitted"`
+	Remaining int     `json:"remaining"`
+	Speed     float64 `json:"speed"`
+	Clients   int     `json:"clients"`
+	Pending   *Event  `json:"pending,omitempty"` // step mode: the event the next /advance fires
+}
+
+// Snapshot of replay progress for the status endpoint
+type replayStatus struct {
+	Title           string                   `json:"title"`
+	State           string                   `json:"state"` // waiting, playing, paused, stepping or completed
+	OffsetSeconds   float64                  `json:"offset_seconds"`
+	DurationSeconds int                      `json:"duration_seconds"`
+	PercentComplete float64                  `json:"percent_complete"`
+	Speed           float64                  `json:"speed"`
+	Clients         int                      `json:"connected_clients"`
+	ClientLimit     *clientLimitStatus       `json:"client_limit,omitempty"` // server-wide, with -max-clients
+	Channels        map[string]channelStatus `json:"channels"`
+	Timing          *timingStatus            `json:"timing,omitempty"` // lateness of timed events, once one has fired
+}
+
+// Where the replay stands against the incident duration: its state, the
+// virtual offset reached and the percent of the duration that is
+func (rp *replay) progressLocked(duration int) (state string, offset, percent float64) {
+	switch {
+	case rp.completed:
+		return "completed", float64(duration), 100
+	case rp.started && rp.stepping() && !rp.paused:
+		offset = rp.offsetLocked(wallClock.Now())
+		if duration > 0 {
+			percent = math.Min(offset/float64(duration)*100, 100)
+		}
+		return "stepping", offset, percent
+	case rp.started:
+		state = "playing"
+		if rp.paused {
+			state = "paused"
+		}
+		offset = rp.offsetLocked(wallClock.Now())
+		if duration > 0 {
+			offset = math.Min(offset, float64(duration))
+			percent = offset / float64(duration) * 100
+		}
+		return state, offset, percent
+	}
+	return "waiting", 0, 0
+}
+
+// Virtual offset and percent complete, for progress frames on streams
+func (rp *replay) progress(duration int) (offset, percent float64) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	_, offset, percent = rp.progressLocked(duration)
+	return offset, percent
+}
+
+// Report how far along the replay is against the incident duration
+func (rp *replay) status(info IncidentInfo) replayStatus {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	status := replayStatus{
+		Title:           info.Title,
+		DurationSeconds: info.DurationSeconds,
+		Speed:           rp.speed.get(""),
+		ClientLimit:     streamLimitStatus(),
+		Channels:        make(map[string]channelStatus),
+	}
+
+	status.State, status.OffsetSeconds, status.PercentComplete = rp.progressLocked(info.DurationSeconds)
+	status.Timing = rp.timing.status()
+
+	for channel, ln := range rp.lanes {
+		cs := channelStatus{Emitted: ln.next, Remaining: ln.remaining(), Speed: rp.speed.get(channel), Clients: rp.viewers[channel]}
+		if rp.stepping() && ln.remaining() > 0 {
+			pending := ln.events[ln.next]
+			cs.Pending = &pending
+		}
+		status.Channels[channel] = cs
+	}
+	// Viewers may also be watching channels the timeline has no events for
+	for channel, clients := range rp.viewers {
+		status.Clients += clients
+		if _, ok := status.Channels[channel]; !ok {
+			status.Channels[channel] = channelStatus{Speed: rp.speed.get(channel), Clients: clients}
+		}
+	}
+	return status
+}
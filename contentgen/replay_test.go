@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	tests := []struct {
+		name    string
+		buffer  int
+		want    []messageKind // received before the subscriber was dropped, or all of them
+		dropped bool
+	}{
+		{"unbuffered", 0, nil, true},
+		{"room for one", 1, []messageKind{messageEvent}, true},
+		{"keeping up", 2, []messageKind{messageEvent, messageComplete}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := newReplay([]Event{{TimeOffset: 0, Channel: "team", Message: "Paging on-call"}}, newSpeedControl(1), false)
+			sub := &subscriber{channels: []string{"team"}, ch: make(chan replayMessage, tt.buffer)}
+			rp.mu.Lock()
+			rp.subscribers[sub] = struct{}{}
+			rp.mu.Unlock()
+
+			// A channel's last event is followed by its completion in the same
+			// broadcast, so a client dropped by the first send is sent to again;
+			// that must neither panic on the closed channel nor close it twice
+			rp.mu.Lock()
+			rp.sendLocked(sub, replayMessage{Kind: messageEvent, Event: rp.events[0]})
+			rp.sendLocked(sub, replayMessage{Kind: messageComplete})
+			rp.mu.Unlock()
+
+			var got []messageKind
+			for len(sub.ch) > 0 {
+				got = append(got, (<-sub.ch).Kind)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("received %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("message %d kind %d, want %d", i, got[i], tt.want[i])
+				}
+			}
+
+			rp.mu.Lock()
+			_, subscribed := rp.subscribers[sub]
+			rp.mu.Unlock()
+			if subscribed == tt.dropped {
+				t.Errorf("still subscribed: %v, want %v", subscribed, !tt.dropped)
+			}
+			if tt.dropped {
+				if _, ok := <-sub.ch; ok {
+					t.Error("dropped subscriber's channel still open")
+				}
+			}
+		})
+	}
+}
+
+func TestSeekRacingFire(t *testing.T) {
+	slack := useFakeSlack(t)
+	var events []Event
+	for offset := 0; offset < 40; offset++ {
+		events = append(events, Event{TimeOffset: offset, Channel: "team", Message: fmt.Sprintf("Update %d", offset)})
+	}
+	rp := newReplay(events, newSpeedControl(400), true)
+	rp.ctx, rp.cancel = context.WithCancel(context.Background())
+	t.Cleanup(eventOutbox.wait)
+	t.Cleanup(rp.wait)
+	t.Cleanup(rp.stop)
+	sub, _ := rp.subscribeWithBacklog([]string{"team"})
+
+	// Seek back and forth while the clock fires, then let it play out
+	seeking := make(chan struct{})
+	go func() {
+		defer close(seeking)
+		for i := 0; i < 50; i++ {
+			rp.seek((i * 7) % 30)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	seen := make(map[string]bool)
+	deadline := time.After(10 * time.Second)
+	for done := false; !done; {
+		select {
+		case msg, ok := <-sub.ch:
+			if !ok {
+				t.Fatal("subscriber dropped while seeking")
+			}
+			if msg.Kind == messageEvent {
+				seen[msg.Event.Message] = true
+			}
+			if msg.Kind == messageComplete {
+				select {
+				case <-seeking:
+					done = true
+				default:
+				}
+			}
+		case <-deadline:
+			t.Fatal("replay never completed after seeking stopped")
+		}
+	}
+	<-seeking
+
+	// However often a stretch replayed, each event reached Slack at most once
+	eventOutbox.wait()
+	posted := make(map[string]int)
+	for _, post := range slack.received() {
+		posted[post.Text]++
+		if posted[post.Text] > 1 {
+			t.Errorf("%q posted to Slack %d times", post.Text, posted[post.Text])
+		}
+	}
+	if last := events[len(events)-1].Message; !seen[last] || posted[last] != 1 {
+		t.Errorf("last event seen %v and posted %d times, want once", seen[last], posted[last])
+	}
+}
+
+func TestPrivateReplayTiming(t *testing.T) {
+	events := fixtureTranscript().Events
+	at := func(message string, seconds float64) timedEvent {
+		return timedEvent{message, time.Duration(seconds * float64(time.Second))}
+	}
+
+	tests := []struct {
+		name    string
+		speed   float64
+		reverse bool
+		start   int
+		want    []timedEvent
+	}{
+		{"forward", 1, false, -1, []timedEvent{
+			at("Paging on-call", 0), at("CPU 92%", 1), at("CPU 99%", 3), at("Rolling back", 10), at("Resolved", 20)}},
+		{"reverse", 1, true, -1, []timedEvent{
+			at("Resolved", 0), at("Rolling back", 10), at("CPU 99%", 17), at("CPU 92%", 19), at("Paging on-call", 20)}},
+		{"reverse at double speed", 2, true, -1, []timedEvent{
+			at("Resolved", 0), at("Rolling back", 5), at("CPU 99%", 8.5), at("CPU 92%", 9.5), at("Paging on-call", 10)}},
+		{"start offset", 1, false, 3, []timedEvent{
+			at("CPU 99%", 0), at("Rolling back", 7), at("Resolved", 17)}},
+		{"start between events", 1, false, 5, []timedEvent{
+			at("Rolling back", 5), at("Resolved", 15)}},
+		{"reverse from start offset", 1, true, 15, []timedEvent{
+			at("Rolling back", 5), at("CPU 99%", 12), at("CPU 92%", 14), at("Paging on-call", 15)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			rp := newPrivateReplay(events, newSpeedControl(tt.speed), tt.reverse, tt.start)
+			t.Cleanup(rp.wait)
+			sub, _ := rp.subscribeWithBacklog([]string{"team", "metrics"})
+
+			got := driveTimed(t, clock, sub)
+			if len(got) != len(tt.want) {
+				t.Fatalf("received %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("event %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// Fan one event out to a growing number of subscribers, each keeping up
+func BenchmarkBroadcast(b *testing.B) {
+	for _, n := range []int{1, 10, 100, 1000} {
+		b.Run(fmt.Sprintf("%d subscribers", n), func(b *testing.B) {
+			rp := newReplay(nil, newSpeedControl(1), false)
+			subs := make([]*subscriber, n)
+			for i := range subs {
+				subs[i] = &subscriber{channels: []string{"team"}, ch: make(chan replayMessage, subscriberBuffer)}
+				rp.subscribers[subs[i]] = struct{}{}
+			}
+			msg := replayMessage{Kind: messageEvent, Event: Event{Channel: "team", Message: "Paging on-call"}}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				rp.mu.Lock()
+				rp.broadcastLocked("team", msg)
+				rp.mu.Unlock()
+				for _, sub := range subs {
+					<-sub.ch
+				}
+			}
+		})
+	}
+}
+
+func TestQuietSkip(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxQuiet time.Duration
+		events   []Event
+		want     []string // events as message@fake seconds, and skip markers as ⏩ seconds
+	}{
+		{"no cap", 0, []Event{
+			{TimeOffset: 0, Channel: "team", Message: "Waiting on vendor"},
+			{TimeOffset: 600, Channel: "team", Message: "Vendor replied"},
+		}, []string{"Waiting on vendor@0", "Vendor replied@600"}},
+		{"long gap capped", 2 * time.Second, []Event{
+			{TimeOffset: 0, Channel: "team", Message: "Waiting on vendor"},
+			{TimeOffset: 600, Channel: "team", Message: "Vendor replied"},
+		}, []string{"Waiting on vendor@0", "⏩ 598", "Vendor replied@2"}},
+		{"short gaps untouched", 2 * time.Second, []Event{
+			{TimeOffset: 0, Channel: "team", Message: "Paging on-call"},
+			{TimeOffset: 1, Channel: "team", Message: "Acked"},
+			{TimeOffset: 3, Channel: "team", Message: "Rolling back"},
+		}, []string{"Paging on-call@0", "Acked@1", "Rolling back@3"}},
+		// Channels keep their order, and a stream following both hears of
+		// each skip once
+		{"channels stay in order", 2 * time.Second, []Event{
+			{TimeOffset: 0, Channel: "team", Message: "Waiting on vendor"},
+			{TimeOffset: 300, Channel: "metrics", Message: "error_rate=12%"},
+			{TimeOffset: 600, Channel: "team", Message: "Vendor replied"},
+		}, []string{
+			"Waiting on vendor@0", "⏩ 298", "error_rate=12%@2", "⏩ 298", "Vendor replied@4",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := maxQuietWait
+			maxQuietWait = tt.maxQuiet
+			t.Cleanup(func() { maxQuietWait = previous })
+			clock := useFakeClock(t)
+			start := clock.Now()
+
+			rp := newReplay(tt.events, newSpeedControl(1), false)
+			t.Cleanup(rp.wait)
+			sub, _ := rp.subscribeWithBacklog([]string{"team", "metrics"})
+
+			received := drive(t, clock, sub)
+			emitted := rp.emittedLog()
+			var got []string
+			for _, msg := range received {
+				switch msg.Kind {
+				case messageEvent:
+					fired := emitted[0].Time.Sub(start).Seconds()
+					emitted = emitted[1:]
+					got = append(got, fmt.Sprintf("%s@%g", msg.Event.Message, fired))
+				case messageQuietSkip:
+					got = append(got, fmt.Sprintf("⏩ %d", msg.Skipped))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("received %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Events the lateness histogram has counted, as /metrics reports them
+func latenessCount(t *testing.T) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	promhttp.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, "contentgen_event_lateness_seconds_count "); ok {
+			count, err := strconv.Atoi(value)
+			if err != nil {
+				t.Fatalf("histogram count %q: %v", value, err)
+			}
+			return count
+		}
+	}
+	return 0
+}
+
+func TestEventLateness(t *testing.T) {
+	// At 200x the clock waits 50ms for each of the later two events
+	events := []Event{
+		{TimeOffset: 0, Channel: "team", Message: "Paging on-call"},
+		{TimeOffset: 10, Channel: "team", Message: "Rolling back"},
+		{TimeOffset: 20, Channel: "team", Message: "Resolved"},
+	}
+	const wait = 50 * time.Millisecond
+
+	tests := []struct {
+		name  string
+		late  []time.Duration // how far past each due time the clock is moved
+		avgMs float64
+		maxMs float64
+	}{
+		{"on time", []time.Duration{0, 0}, 0, 0},
+		{"one late event", []time.Duration{30 * time.Millisecond, 0}, 10, 30},
+		{"both late", []time.Duration{15 * time.Millisecond, 45 * time.Millisecond}, 20, 45},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			counted := latenessCount(t)
+			rp := newReplay(events, newSpeedControl(200), false)
+			t.Cleanup(rp.wait)
+			sub, _ := rp.subscribeWithBacklog([]string{"team"})
+
+			if status := rp.status(IncidentInfo{DurationSeconds: 20}); status.Timing != nil {
+				t.Errorf("timing %+v before any event fired, want none", status.Timing)
+			}
+
+			// Let the replay fall behind by moving the clock past each due
+			// time; the schedule doesn't slip, so each is late on its own
+			start := clock.Now()
+			for i, late := range tt.late {
+				for clock.Pending() == 0 {
+					<-clock.set
+				}
+				clock.Advance(start.Add(time.Duration(i+1)*wait + late).Sub(clock.Now()))
+			}
+			for msg := range sub.ch {
+				if msg.Kind == messageComplete {
+					break
+				}
+			}
+
+			timing := rp.status(IncidentInfo{DurationSeconds: 20}).Timing
+			if timing == nil {
+				t.Fatal("no timing reported after the replay completed")
+			}
+			want := timingStatus{Events: 3, AverageLatenessMs: tt.avgMs, MaxLatenessMs: tt.maxMs}
+			if *timing != want {
+				t.Errorf("timing %+v, want %+v", *timing, want)
+			}
+			if got := latenessCount(t) - counted; got != 3 {
+				t.Errorf("histogram counted %d events, want 3", got)
+			}
+		})
+	}
+}
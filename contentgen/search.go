@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Matches returned by the search endpoint when no limit is given
+const defaultSearchLimit = 50
+
+// One transcript event matching a search
+type searchMatch struct {
+	Index   int    `json:"index"`
+	Channel string `json:"channel"`
+	Offset  int    `json:"offset"`
+	Message string `json:"message"`
+	Level   string `json:"level"`
+}
+
+// Search endpoint response; Total counts every match, even beyond the limit
+type searchResponse struct {
+	Query   string        `json:"query"`
+	Total   int           `json:"total"`
+	Results []searchMatch `json:"results"`
+}
+
+// Find events whose message contains the query, case-insensitively,
+// optionally on a single channel
+func searchEvents(t *IncidentTranscript, query, channel string, limit int) searchResponse {
+	response := searchResponse{Query: query, Results: []searchMatch{}}
+	needle := strings.ToLower(query)
+	for i, event := range t.Events {
+		if channel != "" && event.Channel != channel {
+			continue
+		}
+		event = redactor.event(event)
+		if !strings.Contains(strings.ToLower(event.Message), needle) {
+			continue
+		}
+		response.Total++
+		if len(response.Results) < limit {
+			response.Results = append(response.Results, searchMatch{
+				Index:   i,
+				Channel: event.Channel,
+				Offset:  event.TimeOffset,
+				Message: event.Message,
+				Level:   event.level(),
+			})
+		}
+	}
+	return response
+}
+
+// Handler for searching the loaded transcript, e.g. to pick a seek offset
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		http.Error(w, "Missing q parameter", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultSearchLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Invalid limit value", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(searchEvents(primaryIncident().Transcript, q, query.Get("channel"), limit))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	srv := startServer(t, fixtureTranscript())
+
+	tests := []struct {
+		name    string
+		query   string
+		status  int
+		total   int
+		indexes []int // indexes of the events returned, in order
+	}{
+		{"case-insensitive", "?q=cpu", http.StatusOK, 2, []int{1, 2}},
+		{"channel filter", "?q=ing&channel=team", http.StatusOK, 2, []int{0, 3}},
+		{"limit keeps the total", "?q=cpu&limit=1", http.StatusOK, 2, []int{1}},
+		{"no match", "?q=database", http.StatusOK, 0, []int{}},
+		{"missing query", "?channel=team", http.StatusBadRequest, 0, nil},
+		{"blank query", "?q=%20", http.StatusBadRequest, 0, nil},
+		{"bad limit", "?q=cpu&limit=0", http.StatusBadRequest, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := control(t, http.MethodGet, srv.URL+"/search"+tt.query, "")
+			if status != tt.status {
+				t.Fatalf("GET /search%s: status %d, want %d: %s", tt.query, status, tt.status, body)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			var got searchResponse
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatalf("decode %q: %v", body, err)
+			}
+			if got.Total != tt.total {
+				t.Errorf("total %d, want %d", got.Total, tt.total)
+			}
+			indexes := []int{}
+			for _, match := range got.Results {
+				indexes = append(indexes, match.Index)
+				if event := fixtureTranscript().Events[match.Index]; match.Message != event.Message || match.Offset != event.TimeOffset || match.Channel != event.Channel {
+					t.Errorf("match %+v, want event %d %+v", match, match.Index, event)
+				}
+			}
+			if !slices.Equal(indexes, tt.indexes) {
+				t.Errorf("matched events %v, want %v", indexes, tt.indexes)
+			}
+		})
+	}
+}
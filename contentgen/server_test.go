@@ -0,0 +1,654 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Playback speed for end-to-end tests, fast enough that a 20 second
+// fixture plays out in a tenth of a second
+const testSpeed = 200
+
+// Small two-channel incident shared by the end-to-end tests
+func fixtureTranscript() *IncidentTranscript {
+	return &IncidentTranscript{
+		Incident: IncidentInfo{Title: "Checkout outage", Description: "Payments failing in us-east-1", DurationSeconds: 20},
+		Events: []Event{
+			{TimeOffset: 0, Channel: "team", Message: "Paging on-call"},
+			{TimeOffset: 1, Channel: "metrics", Message: "CPU 92%"},
+			{TimeOffset: 3, Channel: "metrics", Message: "CPU 99%"},
+			{TimeOffset: 10, Channel: "team", Message: "Rolling back"},
+			{TimeOffset: 20, Channel: "team", Message: "Resolved"},
+		},
+	}
+}
+
+// Post received by the fake Slack API
+type slackPost struct {
+	Channel string
+	Text    string
+}
+
+// Stand-in for the Slack Web API recording every chat.postMessage. Responses
+// are taken from respond when set, otherwise every post succeeds.
+type fakeSlack struct {
+	*httptest.Server
+	mu      sync.Mutex
+	posts   []slackPost
+	calls   int
+	respond func(call int, w http.ResponseWriter) bool // reports whether it answered
+	posted  chan struct{}
+}
+
+func newFakeSlack(t *testing.T) *fakeSlack {
+	t.Helper()
+	fs := &fakeSlack{posted: make(chan struct{}, 64)}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fs.mu.Lock()
+		fs.calls++
+		call, respond := fs.calls, fs.respond
+		fs.mu.Unlock()
+		if respond != nil && respond(call, w) {
+			return
+		}
+
+		var payload struct {
+			Channel string `json:"channel"`
+			Text    string `json:"text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fs.mu.Lock()
+		fs.posts = append(fs.posts, slackPost{Channel: payload.Channel, Text: payload.Text})
+		fs.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"ok":true,"ts":"1700000000.000100"}`)
+		select {
+		case fs.posted <- struct{}{}:
+		default:
+		}
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+// Posts received so far, in arrival order
+func (fs *fakeSlack) received() []slackPost {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return append([]slackPost(nil), fs.posts...)
+}
+
+// Wait until the fake has received n posts, failing the test after a second
+func (fs *fakeSlack) waitForPosts(t *testing.T, n int) []slackPost {
+	t.Helper()
+	deadline := time.After(time.Second)
+	for {
+		if posts := fs.received(); len(posts) >= n {
+			return posts
+		}
+		select {
+		case <-fs.posted:
+		case <-deadline:
+			t.Fatalf("fake Slack received %d posts, want %d", len(fs.received()), n)
+		}
+	}
+}
+
+// Publish to a fake Slack for the rest of the test, team events only
+func useFakeSlack(t *testing.T) *fakeSlack {
+	t.Helper()
+	fs := newFakeSlack(t)
+	client := NewSlackClient("xoxb-test", map[string]string{"team": "C0123ABCD"})
+	client.BaseURL = fs.URL
+	client.BlockKit = false
+	client.HTTPClient = fs.Client()
+
+	previousClient, previousNotifier := slackClient, chatNotifier
+	slackClient, chatNotifier = client, client
+	t.Cleanup(func() { slackClient, chatNotifier = previousClient, previousNotifier })
+	return fs
+}
+
+// Make a transcript the primary incident, replayed at testSpeed, and serve
+// it through the real route table
+func startServer(t *testing.T, tr *IncidentTranscript) *httptest.Server {
+	t.Helper()
+	activeMu.Lock()
+	previousTranscript, previousReplay, previousPlayback, previousDefault, previousID := transcript, incidentReplay, playback, defaultTranscript, activeID
+	playback = newSpeedControl(testSpeed)
+	transcript, defaultTranscript = tr, tr
+	rp := newReplay(tr.Events, playback, true)
+	rp.title = tr.Incident.Title
+	// Cancelable, so a test that ends mid-replay doesn't leave the clock running
+	rp.ctx, rp.cancel = context.WithCancel(context.Background())
+	incidentReplay = rp
+	activeMu.Unlock()
+	t.Cleanup(func() {
+		// Stop the clock and drain the outbox before anything they read is
+		// restored. A replay swapped in by a reload or switch can't be
+		// stopped, so let it finish its pass.
+		rp.stop()
+		rp.wait()
+		primaryIncident().Replay.wait()
+		eventOutbox.wait()
+
+		activeMu.Lock()
+		defer activeMu.Unlock()
+		transcript, incidentReplay, playback, defaultTranscript, activeID = previousTranscript, previousReplay, previousPlayback, previousDefault, previousID
+	})
+
+	srv := httptest.NewServer(newServer())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// One server-sent event: its type, empty for plain messages, and its data
+// lines joined back together
+type sseFrame struct {
+	Event string
+	Data  string
+}
+
+// Read server-sent events until the stream ends, failing the test if it
+// stays open past a few seconds
+func readSSE(t *testing.T, url string) []sseFrame {
+	t.Helper()
+	resp := getStream(context.Background(), t, url)
+	defer resp.Body.Close()
+
+	frames := make(chan []sseFrame, 1)
+	go func() { frames <- parseSSE(resp.Body) }()
+	select {
+	case got := <-frames:
+		return got
+	case <-time.After(5 * time.Second):
+		t.Fatalf("stream %s still open", url)
+		return nil
+	}
+}
+
+// Open a stream, failing the test unless it answers 200
+func getStream(ctx context.Context, t *testing.T, url string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		t.Fatalf("GET %s: %s: %s", url, resp.Status, body)
+	}
+	return resp
+}
+
+// Split a server-sent event stream into frames
+func parseSSE(r io.Reader) []sseFrame {
+	var frames []sseFrame
+	scanSSE(r, func(frame sseFrame) { frames = append(frames, frame) })
+	return frames
+}
+
+// Hand each frame of a server-sent event stream to emit as it arrives
+func scanSSE(r io.Reader, emit func(sseFrame)) {
+	var frame sseFrame
+	var data []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data != nil {
+				frame.Data = strings.Join(data, "\n")
+				emit(frame)
+			}
+			frame, data = sseFrame{}, nil
+		case strings.HasPrefix(line, "event: "):
+			frame.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = append(data, strings.TrimPrefix(line, "data: "))
+		}
+	}
+}
+
+// Open stream read a frame at a time, for tests that act mid-replay
+type sseStream struct {
+	url    string
+	frames chan sseFrame // closed when the stream ends
+	cancel context.CancelFunc
+}
+
+// Open a server-sent event stream, closed again when the test ends
+func openSSE(t *testing.T, url string) *sseStream {
+	t.Helper()
+	// Hanging up cancels the request rather than closing the body, which
+	// for a gzipped stream blocks until the pending read returns
+	ctx, cancel := context.WithCancel(context.Background())
+	resp := getStream(ctx, t, url)
+	t.Cleanup(func() {
+		cancel()
+		resp.Body.Close()
+	})
+
+	s := &sseStream{url: url, frames: make(chan sseFrame, 256), cancel: cancel}
+	go func() {
+		defer close(s.frames)
+		scanSSE(resp.Body, func(frame sseFrame) { s.frames <- frame })
+	}()
+	return s
+}
+
+// Next frame, failing the test if none arrives within a few seconds
+func (s *sseStream) next(t *testing.T) (sseFrame, bool) {
+	t.Helper()
+	select {
+	case frame, ok := <-s.frames:
+		return frame, ok
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no frame from %s", s.url)
+		return sseFrame{}, false
+	}
+}
+
+// Hang up, as a viewer closing the page would
+func (s *sseStream) close() {
+	s.cancel()
+}
+
+// Read frames up to and including the first whose data contains text,
+// returning them all
+func (s *sseStream) until(t *testing.T, text string) []sseFrame {
+	t.Helper()
+	var frames []sseFrame
+	for {
+		frame, ok := s.next(t)
+		if !ok {
+			t.Fatalf("stream %s ended waiting for %q after %q", s.url, text, frames)
+		}
+		frames = append(frames, frame)
+		if strings.Contains(frame.Data, text) {
+			return frames
+		}
+	}
+}
+
+// Report whether a stream ends within d, discarding any frames before that
+func (s *sseStream) endsWithin(d time.Duration) bool {
+	timeout := time.After(d)
+	for {
+		select {
+		case _, ok := <-s.frames:
+			if !ok {
+				return true
+			}
+		case <-timeout:
+			return false
+		}
+	}
+}
+
+// Send a control request and return the response status and body
+func control(t *testing.T, method, url string, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+// Data of the frames without an event type, i.e. what the web UI shows
+func sseData(frames []sseFrame) []string {
+	var lines []string
+	for _, frame := range frames {
+		if frame.Event == "" {
+			lines = append(lines, frame.Data)
+		}
+	}
+	return lines
+}
+
+// Report whether lines contains each of want, in that order, as substrings
+func containsInOrder(lines []string, want ...string) bool {
+	i := 0
+	for _, line := range lines {
+		if i < len(want) && strings.Contains(line, want[i]) {
+			i++
+		}
+	}
+	return i == len(want)
+}
+
+func TestServerStreamsAndPublishes(t *testing.T) {
+	slack := useFakeSlack(t)
+	srv := startServer(t, fixtureTranscript())
+
+	lines := sseData(readSSE(t, srv.URL+"/stream/team?oncomplete=close"))
+
+	want := []string{
+		"🔗 Connected to",
+		"📋 Incident: Checkout outage",
+		"Paging on-call",
+		"Rolling back",
+		"Resolved",
+		"✅ Incident replay completed",
+	}
+	if !containsInOrder(lines, want...) {
+		t.Fatalf("stream lines %q, want in order %q", lines, want)
+	}
+	for _, line := range lines {
+		if strings.Contains(line, "CPU") {
+			t.Errorf("team stream carried metrics event %q", line)
+		}
+	}
+
+	posts := slack.waitForPosts(t, 3)
+	var texts []string
+	for _, post := range posts {
+		if post.Channel != "C0123ABCD" {
+			t.Errorf("posted to %q, want the mapped team channel", post.Channel)
+		}
+		texts = append(texts, post.Text)
+	}
+	if want := []string{"Paging on-call", "Rolling back", "Resolved"}; strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("Slack posts %q, want %q", texts, want)
+	}
+}
+
+func TestServerCombinedStreamOrder(t *testing.T) {
+	useFakeSlack(t)
+	srv := startServer(t, fixtureTranscript())
+
+	lines := sseData(readSSE(t, srv.URL+"/stream?channels=team,metrics&oncomplete=close"))
+	want := []string{"Paging on-call", "CPU 92%", "CPU 99%", "Rolling back", "Resolved", "✅ Incident replay completed"}
+	if !containsInOrder(lines, want...) {
+		t.Fatalf("stream lines %q, want in order %q", lines, want)
+	}
+}
+
+func TestServerSeek(t *testing.T) {
+	srv := startServer(t, fixtureTranscript())
+
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"", http.StatusBadRequest},
+		{"?offset=soon", http.StatusBadRequest},
+		{"?offset=-1", http.StatusBadRequest},
+		{"?offset=21", http.StatusBadRequest},
+		{"?offset=20", http.StatusOK},
+		{"?offset=0", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if status, body := control(t, http.MethodPost, srv.URL+"/seek"+tt.query, ""); status != tt.status {
+			t.Errorf("POST /seek%s: status %d, want %d: %s", tt.query, status, tt.status, body)
+		}
+	}
+}
+
+func TestServerSeekBackPublishesOnce(t *testing.T) {
+	slack := useFakeSlack(t)
+	srv := startServer(t, fixtureTranscript())
+	stream := openSSE(t, srv.URL+"/stream/team")
+
+	stream.until(t, "✅ Incident replay completed")
+	expectStatus(t, srv.URL+"/seek?offset=5", http.StatusOK)
+	lines := sseData(stream.until(t, "Resolved"))
+	if want := []string{"⏩ Seeked to T+5s", "Rolling back", "Resolved"}; !containsInOrder(lines, want...) {
+		t.Fatalf("stream after seeking back %q, want in order %q", lines, want)
+	}
+	if containsInOrder(lines, "Paging on-call") {
+		t.Errorf("event before the seek target fired: %q", lines)
+	}
+
+	// The stretch played twice, but Slack saw each event once
+	eventOutbox.wait()
+	var texts []string
+	for _, post := range slack.received() {
+		texts = append(texts, post.Text)
+	}
+	if want := []string{"Paging on-call", "Rolling back", "Resolved"}; strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("Slack posts %q, want %q", texts, want)
+	}
+}
+
+// Texts posted to the fake Slack once the outbox has drained
+func postedTexts(slack *fakeSlack) []string {
+	eventOutbox.wait()
+	var texts []string
+	for _, post := range slack.received() {
+		texts = append(texts, post.Text)
+	}
+	return texts
+}
+
+func TestServerPublishesOncePerRun(t *testing.T) {
+	team := []string{"Paging on-call", "Rolling back", "Resolved"}
+	tests := []struct {
+		name    string
+		restart string // restart query once the first run completes, empty for none
+		want    []string
+	}{
+		{"two viewers", "", team},
+		{"restart publishes again", "/restart", append(append([]string(nil), team...), team...)},
+		{"restart without Slack", "/restart?slack=false", team},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			slack := useFakeSlack(t)
+			srv := startServer(t, fixtureChannels("team"))
+			// Viewers joining after the first event get it as catch-up, so
+			// every viewer sees every event whenever it subscribed
+			viewers := []*sseStream{openSSE(t, srv.URL+"/stream/team?catchup=true"), openSSE(t, srv.URL+"/stream/team?catchup=true")}
+
+			playOut := func() {
+				for i, text := range team {
+					if i > 0 {
+						clock.step(t)
+					}
+					for _, viewer := range viewers {
+						viewer.until(t, text)
+					}
+				}
+			}
+			playOut()
+			// A viewer reconnecting after the run joins the same replay
+			openSSE(t, srv.URL+"/stream/team?catchup=true").until(t, "Resolved")
+			if tt.restart != "" {
+				expectStatus(t, srv.URL+tt.restart, http.StatusOK)
+				playOut()
+			}
+
+			if got := postedTexts(slack); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Slack posts %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestartClientMidFlight(t *testing.T) {
+	clock := useFakeClock(t)
+	srv := startServer(t, fixtureChannels("team"))
+
+	// Each client plays on its own timeline
+	alice := openSSE(t, srv.URL+"/stream/team?client=alice")
+	bob := openSSE(t, srv.URL+"/stream/team?client=bob")
+	alice.until(t, "Paging on-call")
+	bob.until(t, "Paging on-call")
+	clock.stepTogether(t, 2)
+	alice.until(t, "Rolling back")
+	bob.until(t, "Rolling back")
+
+	body := expectStatus(t, srv.URL+"/restart?client=alice", http.StatusOK)
+	if !strings.Contains(body, `"streams":1`) {
+		t.Errorf("restart response %s, want one stream restarted", body)
+	}
+
+	// Alice starts over while Bob plays on undisturbed
+	alice.until(t, "🔁 Restarting incident replay")
+	alice.until(t, "Paging on-call")
+	clock.stepTogether(t, 2)
+	alice.until(t, "Rolling back")
+	for _, line := range sseData(bob.until(t, "Resolved")) {
+		if strings.Contains(line, "Restarting") || strings.Contains(line, "Paging on-call") {
+			t.Errorf("restarting alice reached bob: %q", line)
+		}
+	}
+
+	// Client timelines are private, so nothing reached the shared replay
+	if log := incidentReplay.emittedLog(); len(log) != 0 {
+		t.Errorf("shared replay emitted %d events", len(log))
+	}
+}
+
+func TestRestartClientRejected(t *testing.T) {
+	srv := startServer(t, fixtureTranscript())
+	openSSE(t, srv.URL+"/stream/team?client=alice").until(t, "🔗 Connected to")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"unknown client", http.MethodPost, "/restart?client=carol", http.StatusNotFound},
+		{"blank client id", http.MethodGet, "/stream/team?client=%20", http.StatusBadRequest},
+		{"client id too long", http.MethodGet, "/stream/team?client=" + strings.Repeat("x", maxClientIDLength+1), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := control(t, tt.method, srv.URL+tt.path, ""); status != tt.status {
+				t.Errorf("%s %s: status %d, want %d: %s", tt.method, tt.path, status, tt.status, body)
+			}
+		})
+	}
+}
+
+// Fail the test if the stream shows anything more within d
+func (s *sseStream) quietFor(t *testing.T, d time.Duration) {
+	t.Helper()
+	timeout := time.After(d)
+	for {
+		select {
+		case frame, ok := <-s.frames:
+			if ok && frame.Event == "" {
+				t.Errorf("stream %s showed %q, want nothing until the next advance", s.url, frame.Data)
+			}
+			if !ok {
+				return
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// Turn on -step for the rest of the test
+func useStepMode(t *testing.T) {
+	previous := stepMode
+	stepMode = true
+	t.Cleanup(func() { stepMode = previous })
+}
+
+func TestAdvanceOneEventAtATime(t *testing.T) {
+	useStepMode(t)
+	srv := startServer(t, fixtureChannels("team"))
+	stream := openSSE(t, srv.URL+"/stream/team")
+	stream.until(t, "📋 Incident:")
+	stream.quietFor(t, 100*time.Millisecond)
+
+	for _, want := range []string{"Paging on-call", "Rolling back", "Resolved"} {
+		expectStatus(t, srv.URL+"/advance?channel=team", http.StatusOK)
+		stream.until(t, want)
+		if want != "Resolved" {
+			stream.quietFor(t, 100*time.Millisecond)
+		}
+	}
+	if status, body := control(t, http.MethodPost, srv.URL+"/advance?channel=team", ""); status != http.StatusConflict {
+		t.Errorf("advance past the last event: status %d, want 409: %s", status, body)
+	}
+}
+
+func TestAdvanceClient(t *testing.T) {
+	useStepMode(t)
+	srv := startServer(t, fixtureChannels("team"))
+	alice := openSSE(t, srv.URL+"/stream/team?client=alice")
+	bob := openSSE(t, srv.URL+"/stream/team?client=bob")
+	alice.until(t, "📋 Incident:")
+	bob.until(t, "📋 Incident:")
+
+	// Each advance releases one event to alice alone
+	for _, want := range []string{"Paging on-call", "Rolling back"} {
+		body := expectStatus(t, srv.URL+"/advance?channel=team&client=alice", http.StatusOK)
+		if !strings.Contains(body, "for client alice") {
+			t.Errorf("advance response %s, want it to name alice", body)
+		}
+		alice.until(t, want)
+		alice.quietFor(t, 100*time.Millisecond)
+	}
+	bob.quietFor(t, 100*time.Millisecond)
+
+	// Bob picks up from the start of his own timeline
+	expectStatus(t, srv.URL+"/advance?channel=team&client=bob", http.StatusOK)
+	bob.until(t, "Paging on-call")
+	bob.quietFor(t, 100*time.Millisecond)
+
+	// The shared replay was never advanced
+	if log := incidentReplay.emittedLog(); len(log) != 0 {
+		t.Errorf("shared replay emitted %d events", len(log))
+	}
+}
+
+func TestAdvanceRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		step   bool
+		path   string
+		status int
+	}{
+		{"step mode off", false, "/advance?channel=team", http.StatusConflict},
+		{"missing channel", true, "/advance?client=alice", http.StatusBadRequest},
+		{"unknown client", true, "/advance?channel=team&client=carol", http.StatusNotFound},
+		{"unknown channel", true, "/advance?channel=metrics&client=alice", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Set before the replay starts, since it reads the flag as it plays
+			if tt.step {
+				useStepMode(t)
+			}
+			srv := startServer(t, fixtureChannels("team"))
+			openSSE(t, srv.URL+"/stream/team?client=alice").until(t, "📋 Incident:")
+
+			if status, body := control(t, http.MethodPost, srv.URL+tt.path, ""); status != tt.status {
+				t.Errorf("POST %s: status %d, want %d: %s", tt.path, status, tt.status, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Default Slack Web API endpoint
+const defaultSlackBaseURL = "https://slack.com/api"
+
+// Slack's limits, in characters, on a message's text and a Block Kit section
+const (
+	slackTextLimit    = 40000
+	slackSectionLimit = 3000
+)
+
+// How replayed events are threaded in Slack
+const (
+	slackThreadOff   = "off"   // post every event at the channel's top level
+	slackThreadNew   = "new"   // reply under a new root message for each replay run
+	slackThreadReuse = "reuse" // keep replying under the first run's root message
+)
+
+// Slack channel IDs like C0123ABCD, or channel names like #incident-response
+var slackChannelPattern = regexp.MustCompile(`^([CGDZ][A-Z0-9]{6,}|#?[a-z0-9][a-z0-9._-]{0,79})$`)
+
+// Kind of Slack publish failure
+type SlackErrorKind int
+
+const (
+	SlackErrorOther SlackErrorKind = iota
+	SlackErrorRateLimited
+	SlackErrorAuth
+)
+
+// Typed Slack publish error so callers can decide whether retrying is worthwhile
+type SlackError struct {
+	Kind       SlackErrorKind
+	RetryAfter time.Duration // set when Slack asked us to back off
+	Code       string        // Slack API error code, e.g. channel_not_found
+	Err        error
+	transient  bool // network or server-side failure worth retrying
+}
+
+func (e *SlackError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SlackError) Unwrap() error {
+	return e.Err
+}
+
+// Report whether another attempt could succeed
+func (e *SlackError) Retryable() bool {
+	return e.Kind == SlackErrorRateLimited || (e.Kind == SlackErrorOther && e.transient)
+}
+
+// Delay Slack asked for before the next attempt
+func (e *SlackError) Backoff() time.Duration {
+	return e.RetryAfter
+}
+
+// Emoji leading the Block Kit context line for each event level
+var slackLevelEmoji = map[string]string{
+	levelInfo:  "🕒",
+	levelWarn:  "⚠️",
+	levelError: "🔴",
+}
+
+// Slack API error codes that mean the token itself is bad
+var slackAuthErrors = map[string]bool{
+	"not_authed":       true,
+	"invalid_auth":     true,
+	"account_inactive": true,
+	"token_revoked":    true,
+	"token_expired":    true,
+}
+
+// Slack Web API client for publishing replayed messages
+type SlackClient struct {
+	BaseURL       string
+	Token         string
+	Channels      map[string]string // transcript channel -> Slack channel ID
+	HTTPClient    *http.Client      // shared so connections are reused across posts
+	BlockKit      bool              // rich Block Kit layout instead of plain text
+	IncidentTitle string            // shown in the Block Kit header
+	SplitLong     bool              // post oversized messages as several parts instead of truncating
+	ThreadMode    string            // off, new or reuse; see slackThreadOff and friends
+	Description   string            // incident description shown in a thread's root message
+	TokenFile     string            // re-read for a rotated token when Slack rejects the current one
+
+	tokenMu    sync.RWMutex // guards Token once publishing has started
+	incidentMu sync.RWMutex // guards IncidentTitle and Description once publishing has started
+	threadMu   sync.Mutex
+	threads    map[string]string // Slack channel -> ts of the current root message
+}
+
+// Create a Slack client against the public Slack API
+func NewSlackClient(token string, channels map[string]string) *SlackClient {
+	return &SlackClient{
+		BaseURL:    defaultSlackBaseURL,
+		Token:      token,
+		Channels:   channels,
+		HTTPClient: outboundHTTPClient,
+		BlockKit:   true,
+		SplitLong:  true,
+	}
+}
+
+// Report whether the client has a token to publish with
+func (c *SlackClient) Enabled() bool {
+	return c != nil && c.token() != ""
+}
+
+// Current bot token
+func (c *SlackClient) token() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.Token
+}
+
+// Title and description of the incident being published
+func (c *SlackClient) incident() (title, description string) {
+	c.incidentMu.RLock()
+	defer c.incidentMu.RUnlock()
+	return c.IncidentTitle, c.Description
+}
+
+// Name a different incident in posts from now on, e.g. after a transcript switch
+func (c *SlackClient) setIncident(info IncidentInfo) {
+	c.incidentMu.Lock()
+	defer c.incidentMu.Unlock()
+	c.IncidentTitle, c.Description = info.Title, info.Description
+}
+
+// Read the bot token from TokenFile, reporting whether it differs from the
+// token that just failed. Only the first caller after a rotation reloads;
+// others see the token already changed and simply retry with it.
+func (c *SlackClient) reloadToken(failed string) (bool, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.Token != failed {
+		return true, nil
+	}
+
+	data, err := os.ReadFile(c.TokenFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read Slack token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" || token == failed {
+		return false, nil
+	}
+	c.Token = token
+	return true, nil
+}
+
+// Report whether a transcript channel is mapped to a Slack destination
+func (c *SlackClient) Routes(channel string) bool {
+	if c == nil {
+		return false
+	}
+	_, ok := c.Channels[channel]
+	return ok
+}
+
+// Publish a replayed event to its mapped Slack channel, retrying transient failures.
+// Messages over Slack's length limit are split into parts posted in order, or
+// truncated when splitting is off.
+func (c *SlackClient) PostEvent(event Event) error {
+	if !c.Enabled() {
+		return fmt.Errorf("Slack bot token not configured")
+	}
+	if !c.Routes(event.Channel) {
+		return fmt.Errorf("no Slack channel mapped for %q", event.Channel)
+	}
+	if id := c.Channels[event.Channel]; !slackChannelPattern.MatchString(id) {
+		return fmt.Errorf("invalid Slack channel %q mapped for %q, expected an ID like C0123ABCD or a channel name", id, event.Channel)
+	}
+
+	threadTS, err := c.threadFor(c.Channels[event.Channel])
+	if err != nil {
+		return err
+	}
+
+	event.Message = sanitizeSlackText(event.Message)
+	parts := c.messageParts(event)
+	for i, part := range parts {
+		event.Message = part
+		payload := c.buildPayload(event)
+		if threadTS != "" {
+			payload["thread_ts"] = threadTS
+		}
+		if _, err := c.postMessage(payload); err != nil {
+			if len(parts) > 1 {
+				return fmt.Errorf("failed to post part %d of %d: %w", i+1, len(parts), err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Post plain mrkdwn text to a Slack channel, threaded like events, e.g. a digest
+func (c *SlackClient) PostText(channelID, text string) error {
+	if !c.Enabled() {
+		return fmt.Errorf("Slack bot token not configured")
+	}
+	if !slackChannelPattern.MatchString(channelID) {
+		return fmt.Errorf("invalid Slack channel %q, expected an ID like C0123ABCD or a channel name", channelID)
+	}
+
+	threadTS, err := c.threadFor(channelID)
+	if err != nil {
+		return err
+	}
+	payload := map[string]interface{}{
+		"channel": channelID,
+		"text":    truncateText(sanitizeSlackText(text), slackTextLimit),
+	}
+	if threadTS != "" {
+		payload["thread_ts"] = threadTS
+	}
+	_, err = c.postMessage(payload)
+	return err
+}
+
+// Post a chat.postMessage payload and return the new message's timestamp
+func (c *SlackClient) postMessage(payload map[string]interface{}) (string, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	var result struct {
+		TS string `json:"ts"`
+	}
+	err = withRetry("Slack API", func(ctx context.Context) error {
+		return c.call(ctx, "chat.postMessage", "application/json", jsonData, &result)
+	})
+	return result.TS, err
+}
+
+// Timestamp of the root message to reply under in a Slack channel, posting
+// the incident's root message on first use, or "" when threading is off
+func (c *SlackClient) threadFor(channelID string) (string, error) {
+	if c.ThreadMode == "" || c.ThreadMode == slackThreadOff {
+		return "", nil
+	}
+
+	c.threadMu.Lock()
+	defer c.threadMu.Unlock()
+	if ts, ok := c.threads[channelID]; ok {
+		return ts, nil
+	}
+
+	title, description := c.incident()
+	text := fmt.Sprintf("🚨 *%s*", title)
+	if description != "" {
+		text += "\n" + description
+	}
+	ts, err := c.postMessage(map[string]interface{}{"channel": channelID, "text": text})
+	if err != nil {
+		return "", fmt.Errorf("failed to post thread root message: %w", err)
+	}
+	if ts == "" {
+		return "", fmt.Errorf("Slack returned no ts for the thread root message")
+	}
+	if c.threads == nil {
+		c.threads = make(map[string]string)
+	}
+	c.threads[channelID] = ts
+	return ts, nil
+}
+
+// Mark the start of a new replay run; in new-thread mode the next post in
+// each channel opens a fresh thread
+func (c *SlackClient) ResetThreads() {
+	if c == nil || c.ThreadMode != slackThreadNew {
+		return
+	}
+	c.threadMu.Lock()
+	defer c.threadMu.Unlock()
+	c.threads = nil
+}
+
+// Fit an event's message within Slack's text limit. Only plain text is split;
+// links and images keep a single, truncated caption.
+func (c *SlackClient) messageParts(event Event) []string {
+	limit := slackTextLimit - (len([]rune(event.displayText())) - len([]rune(event.Message)))
+	if len([]rune(event.Message)) <= limit {
+		return []string{event.Message}
+	}
+	if c.SplitLong && event.kind() == eventText {
+		return splitText(event.Message, limit)
+	}
+	return []string{truncateText(event.Message, limit)}
+}
+
+// Drop control characters Slack would reject or render as garbage, keeping
+// newlines and tabs
+func sanitizeSlackText(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// Split text into chunks of at most limit characters, preferring to break
+// after a newline or space in the second half of a chunk
+func splitText(text string, limit int) []string {
+	var chunks []string
+	runes := []rune(text)
+	for len(runes) > limit {
+		cut := limit
+		for _, sep := range []rune{'\n', ' '} {
+			if i := lastIndexRune(runes[limit/2:limit], sep); i >= 0 {
+				cut = limit/2 + i + 1
+				break
+			}
+		}
+		chunks = append(chunks, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	return append(chunks, string(runes))
+}
+
+// Position of the last occurrence of r, or -1
+func lastIndexRune(runes []rune, r rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// Cut text to at most limit characters, marking the cut with an ellipsis
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// Speaker shown after the channel in the Block Kit context line
+func slackSpeaker(event Event) string {
+	if event.Speaker == "" {
+		return ""
+	}
+	return " · 🗣 *" + event.Speaker + "*"
+}
+
+// Build the chat.postMessage body; text is kept as the notification fallback
+func (c *SlackClient) buildPayload(event Event) map[string]interface{} {
+	payload := map[string]interface{}{
+		"channel": c.Channels[event.Channel],
+		"text":    event.displayText(),
+	}
+	if !c.BlockKit {
+		return payload
+	}
+
+	blocks := make([]map[string]interface{}, 0, 3)
+	if title, _ := c.incident(); title != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": title, "emoji": true},
+		})
+	}
+	blocks = append(blocks, map[string]interface{}{
+		"type": "context",
+		"elements": []map[string]interface{}{
+			{"type": "mrkdwn", "text": fmt.Sprintf("%s %s · #%s%s", slackLevelEmoji[event.level()], formatEventTime(event, wallClock.Now()), event.Channel, slackSpeaker(event))},
+		},
+	})
+
+	// Links render as a clickable section, images as an image block
+	switch event.kind() {
+	case eventLink:
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": fmt.Sprintf("🔗 <%s|%s>", event.Meta["url"], event.Message)},
+		})
+	case eventImage:
+		alt := event.Meta["alt"]
+		if alt == "" {
+			alt = event.Message
+		}
+		blocks = append(blocks, map[string]interface{}{
+			"type":      "image",
+			"title":     map[string]interface{}{"type": "plain_text", "text": event.Message, "emoji": true},
+			"image_url": event.Meta["url"],
+			"alt_text":  alt,
+		})
+	default:
+		// Long text spans several sections, each within Slack's section limit
+		for _, text := range splitText(event.Message, slackSectionLimit) {
+			blocks = append(blocks, map[string]interface{}{
+				"type": "section",
+				"text": map[string]interface{}{"type": "mrkdwn", "text": text},
+			})
+		}
+	}
+	payload["blocks"] = blocks
+	return payload
+}
+
+// Make a single Slack Web API call, decoding a successful response into result.
+// When Slack rejects the token and a token file is configured, the file is
+// re-read and the call retried once with the rotated token.
+func (c *SlackClient) call(ctx context.Context, method, contentType string, body []byte, result interface{}) error {
+	token := c.token()
+	err := c.callWithToken(ctx, token, method, contentType, body, result)
+
+	var slackErr *SlackError
+	if c.TokenFile == "" || !errors.As(err, &slackErr) || slackErr.Kind != SlackErrorAuth {
+		return err
+	}
+	reloaded, reloadErr := c.reloadToken(token)
+	if reloadErr != nil {
+		return errors.Join(err, reloadErr)
+	}
+	if !reloaded {
+		return err
+	}
+	slog.Info("🔑 Reloaded Slack token after auth failure", "file", c.TokenFile)
+	return c.callWithToken(ctx, c.token(), method, contentType, body, result)
+}
+
+// Make a single Slack Web API call with the given token
+func (c *SlackClient) callWithToken(ctx context.Context, token, method, contentType string, body []byte, result interface{}) error {
+	// Create HTTP request
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	// Send request
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return &SlackError{Kind: SlackErrorOther, Err: fmt.Errorf("failed to send request: %w", err), transient: true}
+	}
+	defer resp.Body.Close()
+
+	// Check HTTP status
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		retryAfter := time.Second
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return &SlackError{Kind: SlackErrorRateLimited, RetryAfter: retryAfter, Err: fmt.Errorf("Slack API rate limited (retry after %s)", retryAfter)}
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return &SlackError{Kind: SlackErrorAuth, Err: fmt.Errorf("Slack API auth failure: HTTP %d", resp.StatusCode)}
+	case resp.StatusCode >= 500:
+		return &SlackError{Kind: SlackErrorOther, Err: fmt.Errorf("Slack API server error: HTTP %d", resp.StatusCode), transient: true}
+	}
+
+	// Check response
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &SlackError{Kind: SlackErrorOther, Err: fmt.Errorf("failed to read response: %w", err), transient: true}
+	}
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return &SlackError{Kind: SlackErrorOther, Err: fmt.Errorf("failed to decode response: %w", err)}
+	}
+
+	if ok, exists := envelope["ok"].(bool); !exists || !ok {
+		errorMsg := "unknown error"
+		if errStr, exists := envelope["error"].(string); exists {
+			errorMsg = errStr
+		}
+		kind := SlackErrorOther
+		if errorMsg == "ratelimited" {
+			kind = SlackErrorRateLimited
+		} else if slackAuthErrors[errorMsg] {
+			kind = SlackErrorAuth
+		}
+		return &SlackError{Kind: kind, Code: errorMsg, Err: fmt.Errorf("Slack API error: %s", errorMsg)}
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// Parse a transcript-to-Slack channel map, either as "team:C123,metrics:C456"
+// or as a JSON object {"team":"C123","metrics":"C456"}
+func parseSlackChannelMap(value string) (map[string]string, error) {
+	channels := make(map[string]string)
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "{") {
+		if err := json.Unmarshal([]byte(value), &channels); err != nil {
+			return nil, fmt.Errorf("failed to parse channel map JSON: %w", err)
+		}
+		return channels, validateSlackChannels(channels)
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, id, ok := strings.Cut(pair, ":")
+		name, id = strings.TrimSpace(name), strings.TrimSpace(id)
+		if !ok || name == "" || id == "" {
+			return nil, fmt.Errorf("invalid channel mapping %q, expected channel:SLACK_ID", pair)
+		}
+		channels[name] = id
+	}
+	return channels, validateSlackChannels(channels)
+}
+
+// Reject Slack destinations that can't be a channel ID or name
+func validateSlackChannels(channels map[string]string) error {
+	for name, id := range channels {
+		if !slackChannelPattern.MatchString(id) {
+			return fmt.Errorf("invalid Slack channel %q for %q, expected an ID like C0123ABCD or a channel name", id, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestPublishRetriesRateLimit(t *testing.T) {
+	clock := useFakeClock(t)
+	slack := useFakeSlack(t)
+	slack.respond = func(call int, w http.ResponseWriter) bool {
+		if call > 1 {
+			return false
+		}
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return true
+	}
+
+	rp := newReplay([]Event{{TimeOffset: 0, Channel: "team", Message: "Paging on-call"}}, newSpeedControl(1), true)
+	t.Cleanup(eventOutbox.wait)
+	t.Cleanup(rp.wait)
+	sub, _ := rp.subscribeWithBacklog([]string{"team"})
+
+	// The event reaches streams while Slack is still asking the outbox to
+	// back off, so the clock goroutine never waited on the publish
+	select {
+	case msg := <-sub.ch:
+		if msg.Kind != messageEvent || msg.Chat.Status != "queued" {
+			t.Fatalf("first message %+v, want the event queued for chat", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event never reached the subscriber")
+	}
+	if posts := slack.received(); len(posts) != 0 {
+		t.Fatalf("Slack accepted %q before the Retry-After elapsed", posts)
+	}
+
+	clock.step(t)
+	posts := slack.waitForPosts(t, 1)
+	if posts[0].Channel != "C0123ABCD" || posts[0].Text != "Paging on-call" {
+		t.Errorf("posted %+v, want the event on the mapped team channel", posts[0])
+	}
+	slack.mu.Lock()
+	calls := slack.calls
+	slack.mu.Unlock()
+	if calls != 2 {
+		t.Errorf("Slack saw %d calls, want the rate-limited one and its retry", calls)
+	}
+}
+
+// Request as the Slack API received it
+type slackRequest struct {
+	Method, Path, Auth, ContentType string
+	Body                            map[string]interface{}
+}
+
+// Serve one canned chat.postMessage response, recording the requests made
+func captureSlack(t *testing.T, status int, header map[string]string, body string) (*SlackClient, *[]slackRequest) {
+	t.Helper()
+	var requests []slackRequest
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := slackRequest{Method: r.Method, Path: r.URL.Path, Auth: r.Header.Get("Authorization"), ContentType: r.Header.Get("Content-Type")}
+		if err := json.NewDecoder(r.Body).Decode(&req.Body); err != nil {
+			t.Errorf("request body: %v", err)
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+		for key, value := range header {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewSlackClient("xoxb-test", map[string]string{"team": "C0123ABCD"})
+	client.BaseURL = srv.URL
+	client.HTTPClient = srv.Client()
+	client.BlockKit = false
+	return client, &requests
+}
+
+func TestSlackPostEventRequest(t *testing.T) {
+	client, requests := captureSlack(t, http.StatusOK, nil, `{"ok":true,"ts":"1700000000.000100"}`)
+	if err := client.PostEvent(Event{TimeOffset: 5, Channel: "team", Message: "Paging on-call"}); err != nil {
+		t.Fatalf("PostEvent: %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("Slack saw %d requests, want 1", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.Method != http.MethodPost || req.Path != "/chat.postMessage" {
+		t.Errorf("request %s %s, want POST /chat.postMessage", req.Method, req.Path)
+	}
+	if req.Auth != "Bearer xoxb-test" {
+		t.Errorf("Authorization %q, want the bot token as a bearer token", req.Auth)
+	}
+	if req.ContentType != "application/json" {
+		t.Errorf("Content-Type %q, want application/json", req.ContentType)
+	}
+	want := map[string]interface{}{"channel": "C0123ABCD", "text": "Paging on-call"}
+	if !reflect.DeepEqual(req.Body, want) {
+		t.Errorf("body %v, want %v", req.Body, want)
+	}
+}
+
+func TestSlackErrorMapping(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     map[string]string
+		body       string
+		kind       SlackErrorKind
+		code       string
+		retryable  bool
+		retryAfter time.Duration
+	}{
+		{"rate limited", http.StatusTooManyRequests, map[string]string{"Retry-After": "7"}, "", SlackErrorRateLimited, "", true, 7 * time.Second},
+		{"rate limited without Retry-After", http.StatusTooManyRequests, nil, "", SlackErrorRateLimited, "", true, time.Second},
+		{"unauthorized", http.StatusUnauthorized, nil, "", SlackErrorAuth, "", false, 0},
+		{"server error", http.StatusBadGateway, nil, "", SlackErrorOther, "", true, 0},
+		{"rate limited code", http.StatusOK, nil, `{"ok":false,"error":"ratelimited"}`, SlackErrorRateLimited, "ratelimited", true, 0},
+		{"revoked token", http.StatusOK, nil, `{"ok":false,"error":"token_revoked"}`, SlackErrorAuth, "token_revoked", false, 0},
+		{"unknown channel", http.StatusOK, nil, `{"ok":false,"error":"channel_not_found"}`, SlackErrorOther, "channel_not_found", false, 0},
+		{"undecodable response", http.StatusOK, nil, `<html>`, SlackErrorOther, "", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := captureSlack(t, tt.status, tt.header, tt.body)
+			err := client.call(context.Background(), "chat.postMessage", "application/json", []byte(`{}`), nil)
+
+			var slackErr *SlackError
+			if !errors.As(err, &slackErr) {
+				t.Fatalf("error %v, want a *SlackError", err)
+			}
+			if slackErr.Kind != tt.kind || slackErr.Code != tt.code {
+				t.Errorf("kind %d code %q, want kind %d code %q", slackErr.Kind, slackErr.Code, tt.kind, tt.code)
+			}
+			if slackErr.Retryable() != tt.retryable {
+				t.Errorf("Retryable() = %v, want %v", slackErr.Retryable(), tt.retryable)
+			}
+			if slackErr.Backoff() != tt.retryAfter {
+				t.Errorf("Backoff() = %v, want %v", slackErr.Backoff(), tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestSlackBlockKitPayload(t *testing.T) {
+	clock := useFakeClock(t)
+	event := Event{TimeOffset: 90, Channel: "team", Message: "Rolling back", Level: levelWarn}
+	contextLine := fmt.Sprintf("⚠️ %s · #team", formatEventTime(event, clock.Now()))
+
+	tests := []struct {
+		name     string
+		blockKit bool
+		title    string
+		want     string // expected blocks as JSON, empty for none
+	}{
+		{"plain text", false, "Checkout outage", ""},
+		{"with title", true, "Checkout outage", `[
+			{"type":"header","text":{"type":"plain_text","text":"Checkout outage","emoji":true}},
+			{"type":"context","elements":[{"type":"mrkdwn","text":"` + contextLine + `"}]},
+			{"type":"section","text":{"type":"mrkdwn","text":"Rolling back"}}]`},
+		{"without title", true, "", `[
+			{"type":"context","elements":[{"type":"mrkdwn","text":"` + contextLine + `"}]},
+			{"type":"section","text":{"type":"mrkdwn","text":"Rolling back"}}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, requests := captureSlack(t, http.StatusOK, nil, `{"ok":true,"ts":"1700000000.000100"}`)
+			client.BlockKit, client.IncidentTitle = tt.blockKit, tt.title
+			if err := client.PostEvent(event); err != nil {
+				t.Fatalf("PostEvent: %v", err)
+			}
+
+			body := (*requests)[0].Body
+			if body["text"] != "Rolling back" {
+				t.Errorf("text fallback %q, want the message", body["text"])
+			}
+			if tt.want == "" {
+				if blocks, ok := body["blocks"]; ok {
+					t.Errorf("plain text post carried blocks %v", blocks)
+				}
+				return
+			}
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("expected blocks: %v", err)
+			}
+			if !reflect.DeepEqual(body["blocks"], want) {
+				got, _ := json.Marshal(body["blocks"])
+				t.Errorf("blocks %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlackMessageLength(t *testing.T) {
+	a, b := strings.Repeat("a", 30000), strings.Repeat("b", 20000)
+
+	tests := []struct {
+		name    string
+		speaker string
+		message string
+		split   bool
+		want    []int // rune length of each post's text
+	}{
+		{"at the limit", "", strings.Repeat("x", slackTextLimit), true, []int{slackTextLimit}},
+		{"one under", "", strings.Repeat("x", slackTextLimit-1), false, []int{slackTextLimit - 1}},
+		{"one over, split", "", strings.Repeat("x", slackTextLimit+1), true, []int{slackTextLimit, 1}},
+		{"one over, truncated", "", strings.Repeat("x", slackTextLimit+1), false, []int{slackTextLimit}},
+		{"twice over, split", "", strings.Repeat("x", 2*slackTextLimit+1), true, []int{slackTextLimit, slackTextLimit, 1}},
+		// The "[Ana] " prefix displaces 6 characters, which follow in a second
+		// post under the prefix again
+		{"speaker counts toward the limit", "Ana", strings.Repeat("x", slackTextLimit), true, []int{slackTextLimit, 12}},
+		{"split after a space", "", a + " " + b, true, []int{len(a) + 1, len(b)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, requests := captureSlack(t, http.StatusOK, nil, `{"ok":true,"ts":"1700000000.000100"}`)
+			client.SplitLong = tt.split
+			if err := client.PostEvent(Event{Channel: "team", Speaker: tt.speaker, Message: tt.message}); err != nil {
+				t.Fatalf("PostEvent: %v", err)
+			}
+
+			var got []int
+			var joined strings.Builder
+			for _, req := range *requests {
+				text, _ := req.Body["text"].(string)
+				got = append(got, utf8.RuneCountInString(text))
+				joined.WriteString(strings.TrimPrefix(text, "["+tt.speaker+"] "))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("posted texts of %v characters, want %v", got, tt.want)
+			}
+			switch {
+			case tt.split && joined.String() != tt.message:
+				t.Error("split posts don't join back into the message")
+			case !tt.split && len(got) == 1 && got[0] < utf8.RuneCountInString(tt.message) && !strings.HasSuffix(joined.String(), "…"):
+				t.Error("truncated post isn't marked with an ellipsis")
+			}
+		})
+	}
+}
+
+func TestSlackChannelValidation(t *testing.T) {
+	tests := []struct {
+		channel string
+		valid   bool
+	}{
+		{"C0123ABCD", true},
+		{"G0123ABCD", true},
+		{"#incident-response", true},
+		{"incident-response", true},
+		{"", false},
+		{"C01 23ABCD", false},
+		{"#Incident", false},
+		{"https://hooks.slack.com/services/T0/B0/x", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.channel, func(t *testing.T) {
+			client, requests := captureSlack(t, http.StatusOK, nil, `{"ok":true,"ts":"1700000000.000100"}`)
+			client.Channels = map[string]string{"team": tt.channel}
+			err := client.PostEvent(Event{Channel: "team", Message: "Paging on-call"})
+			if tt.valid {
+				if err != nil {
+					t.Errorf("PostEvent to %q: %v", tt.channel, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "invalid Slack channel") {
+				t.Errorf("PostEvent to %q: error %v, want it rejected as invalid", tt.channel, err)
+			}
+			if len(*requests) != 0 {
+				t.Errorf("invalid channel %q still reached Slack", tt.channel)
+			}
+		})
+	}
+}
+
+func TestSanitizeSlackText(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", "Paging on-call", "Paging on-call"},
+		{"control characters dropped", "CPU\x00 92%\x07\x1b", "CPU 92%"},
+		{"newlines and tabs kept", "line one\n\tline two", "line one\n\tline two"},
+		{"carriage return dropped", "ok\r\n", "ok\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeSlackText(tt.in); got != tt.want {
+				t.Errorf("sanitizeSlackText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlackThreading(t *testing.T) {
+	tests := []struct {
+		mode string
+		want []string // per post: "root", or the ts of the root it replies under
+	}{
+		{slackThreadOff, []string{"", "", ""}},
+		{slackThreadNew, []string{"root", "1", "1", "root", "4"}},
+		{slackThreadReuse, []string{"root", "1", "1", "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			// Each post gets the next ts, so replies show which root they follow
+			var mu sync.Mutex
+			var bodies []map[string]interface{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]interface{}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("request body: %v", err)
+				}
+				mu.Lock()
+				bodies = append(bodies, body)
+				ts := len(bodies)
+				mu.Unlock()
+				fmt.Fprintf(w, `{"ok":true,"ts":"%d"}`, ts)
+			}))
+			t.Cleanup(srv.Close)
+			client := NewSlackClient("xoxb-test", map[string]string{"team": "C0123ABCD"})
+			client.BaseURL, client.HTTPClient, client.BlockKit = srv.URL, srv.Client(), false
+			client.ThreadMode = tt.mode
+			client.IncidentTitle, client.Description = "Checkout outage", "Payments failing"
+
+			// Two events, then a restart and one more
+			for _, message := range []string{"Paging on-call", "Rolling back"} {
+				if err := client.PostEvent(Event{Channel: "team", Message: message}); err != nil {
+					t.Fatalf("PostEvent: %v", err)
+				}
+			}
+			client.ResetThreads()
+			if err := client.PostEvent(Event{Channel: "team", Message: "Paging on-call"}); err != nil {
+				t.Fatalf("PostEvent: %v", err)
+			}
+
+			var got []string
+			for _, body := range bodies {
+				ts, threaded := body["thread_ts"].(string)
+				switch {
+				case threaded:
+					got = append(got, ts)
+				case strings.HasPrefix(body["text"].(string), "🚨 *Checkout outage*\nPayments failing"):
+					got = append(got, "root")
+				default:
+					got = append(got, "")
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("posts %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlackTokenReload(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string // what Slack says about a token it doesn't accept
+		file    string // token file contents at the time of the failure, "-" for no file configured
+		wantErr bool
+		auths   []string // tokens Slack saw, in order
+	}{
+		{"rotated token", "invalid_auth", "xoxb-new\n", false, []string{"xoxb-old", "xoxb-new"}},
+		{"revoked token", "token_revoked", "xoxb-new", false, []string{"xoxb-old", "xoxb-new"}},
+		{"file not rotated yet", "invalid_auth", "xoxb-old", true, []string{"xoxb-old"}},
+		{"empty file", "invalid_auth", "", true, []string{"xoxb-old"}},
+		{"no token file", "invalid_auth", "-", true, []string{"xoxb-old"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Slack accepts only the rotated token
+			var mu sync.Mutex
+			var auths []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+				mu.Lock()
+				auths = append(auths, token)
+				mu.Unlock()
+				if token != "xoxb-new" {
+					fmt.Fprintf(w, `{"ok":false,"error":%q}`, tt.code)
+					return
+				}
+				io.WriteString(w, `{"ok":true,"ts":"1"}`)
+			}))
+			t.Cleanup(srv.Close)
+			client := NewSlackClient("xoxb-old", map[string]string{"team": "C0123ABCD"})
+			client.BaseURL, client.HTTPClient, client.BlockKit = srv.URL, srv.Client(), false
+			if tt.file != "-" {
+				client.TokenFile = filepath.Join(t.TempDir(), "slack-token")
+				if err := os.WriteFile(client.TokenFile, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := client.PostEvent(Event{Channel: "team", Message: "Paging on-call"})
+			if tt.wantErr {
+				var slackErr *SlackError
+				if !errors.As(err, &slackErr) || slackErr.Kind != SlackErrorAuth {
+					t.Errorf("PostEvent error %v, want the auth failure", err)
+				}
+			} else if err != nil {
+				t.Errorf("PostEvent: %v", err)
+			}
+			if !slices.Equal(auths, tt.auths) {
+				t.Errorf("Slack saw tokens %q, want %q", auths, tt.auths)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// The reloaded token is cached, so the file isn't read again
+			os.Remove(client.TokenFile)
+			if err := client.PostEvent(Event{Channel: "team", Message: "Rolling back"}); err != nil {
+				t.Errorf("second PostEvent: %v", err)
+			}
+			if got := auths[len(auths)-1]; len(auths) != len(tt.auths)+1 || got != "xoxb-new" {
+				t.Errorf("second post made %d calls ending with %q, want one with the cached token", len(auths)-len(tt.auths), got)
+			}
+		})
+	}
+}
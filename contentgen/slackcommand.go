@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signing secret of the Slack app whose slash command drives the replay,
+// from SLACK_SIGNING_SECRET; without it /slack/command is turned off
+var slackSigningSecret string
+
+// Oldest request timestamp accepted, so a captured command can't be replayed
+const slackCommandMaxAge = 5 * time.Minute
+
+// Largest slash command payload read; Slack's are a few hundred bytes
+const slackCommandMaxBody = 64 << 10
+
+// Reply shown for an empty or unknown command
+const slackCommandUsage = "Usage: `/incident speed <0.1-10>`, `/incident pause`, `/incident resume` or `/incident status`"
+
+// Check a request's Slack signature: an HMAC-SHA256 of "v0:timestamp:body"
+// under the app's signing secret, sent as "v0=<hex>" alongside a timestamp
+// that must be recent
+func verifySlackSignature(secret string, header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing or invalid request timestamp")
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)).Abs(); skew > slackCommandMaxAge {
+		return fmt.Errorf("request timestamp is %s off the server clock", skew.Round(time.Second))
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(header.Get("X-Slack-Signature")), []byte(expected)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// Run a slash command's text against the primary incident and return the
+// confirmation to show the facilitator
+func runSlackCommand(text string) string {
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) == 0 {
+		return slackCommandUsage
+	}
+
+	rp := primaryIncident().Replay
+	switch fields[0] {
+	case "speed":
+		if len(fields) != 2 {
+			return "Usage: `/incident speed <0.1-10>`"
+		}
+		speed, err := strconv.ParseFloat(strings.TrimSuffix(fields[1], "x"), 64)
+		if err != nil {
+			return fmt.Sprintf("❌ Invalid speed %q", fields[1])
+		}
+		playback.set("", speed)
+		return fmt.Sprintf("⚡ Speed set to %.1fx", clampSpeed(speed))
+	case "pause":
+		if !rp.pause(true) {
+			return "⏸️ Replay is already paused"
+		}
+		return "⏸️ Replay paused"
+	case "resume":
+		if !rp.pause(false) {
+			return "▶️ Replay is not paused"
+		}
+		return "▶️ Replay resumed"
+	case "status":
+		status := rp.status(primaryIncident().Transcript.Incident)
+		return fmt.Sprintf("📊 %s: %s at %s, %.0f%% complete, %.1fx", status.Title, status.State,
+			formatOffset(int(status.OffsetSeconds)), status.PercentComplete, status.Speed)
+	}
+	return slackCommandUsage
+}
+
+// Handler for a Slack slash command such as "/incident speed 4", letting
+// facilitators drive the replay from Slack. Only requests signed with the
+// app's signing secret are run; the reply is ephemeral, so only the
+// facilitator who typed the command sees it.
+func slackCommandHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if slackSigningSecret == "" {
+		http.Error(w, "Slack commands are not configured", http.StatusNotFound)
+		return
+	}
+
+	// The signature covers the raw body, so read it before parsing the form
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, slackCommandMaxBody))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if err := verifySlackSignature(slackSigningSecret, r.Header, body, wallClock.Now()); err != nil {
+		slog.Warn("⚠️  Rejected unverified Slack command", "remote_addr", r.RemoteAddr, "err", err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "Invalid command payload", http.StatusBadRequest)
+		return
+	}
+
+	text := form.Get("text")
+	slog.Info("💬 Slack command", "user", form.Get("user_name"), "command", form.Get("command"), "text", text)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"response_type": "ephemeral", "text": runSlackCommand(text)})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Signature Slack would send for a body at a timestamp: "v0=" and the hex
+// HMAC-SHA256 of "v0:timestamp:body" under the signing secret
+func slackSignature(secret, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	io.WriteString(mac, "v0:"+timestamp+":"+body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSlackCommand(t *testing.T) {
+	const secret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+	tests := []struct {
+		name       string
+		configured string        // SLACK_SIGNING_SECRET, empty when unset
+		method     string        // POST unless set
+		text       string        // what followed /incident
+		signedWith string        // secret the request is signed with, unsigned when empty
+		age        time.Duration // how long before the server's clock it was signed
+		status     int
+		reply      string  // substring of the ephemeral reply
+		speed      float64 // default speed afterwards, 0 when unchanged
+	}{
+		{"speed", secret, "", "speed 4", secret, 0, http.StatusOK, "Speed set to 4.0x", 4},
+		{"speed with an x", secret, "", "Speed 2.5x", secret, 0, http.StatusOK, "Speed set to 2.5x", 2.5},
+		{"speed clamped", secret, "", "speed 50", secret, 0, http.StatusOK, "Speed set to 10.0x", 10},
+		{"signed a minute ago", secret, "", "speed 4", secret, time.Minute, http.StatusOK, "Speed set to 4.0x", 4},
+		{"invalid speed", secret, "", "speed fast", secret, 0, http.StatusOK, `Invalid speed "fast"`, 0},
+		{"pause", secret, "", "pause", secret, 0, http.StatusOK, "Replay paused", 0},
+		{"resume while playing", secret, "", "resume", secret, 0, http.StatusOK, "Replay is not paused", 0},
+		{"status", secret, "", "status", secret, 0, http.StatusOK, "Checkout outage", 0},
+		{"unknown command", secret, "", "rewind", secret, 0, http.StatusOK, "Usage:", 0},
+		{"wrong secret", secret, "", "speed 4", "not-the-secret", 0, http.StatusUnauthorized, "", 0},
+		{"stale timestamp", secret, "", "speed 4", secret, 10 * time.Minute, http.StatusUnauthorized, "", 0},
+		{"unsigned", secret, "", "speed 4", "", 0, http.StatusUnauthorized, "", 0},
+		{"not configured", "", "", "speed 4", secret, 0, http.StatusNotFound, "", 0},
+		{"wrong method", secret, http.MethodGet, "speed 4", secret, 0, http.StatusMethodNotAllowed, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			previousSecret := slackSigningSecret
+			slackSigningSecret = tt.configured
+			t.Cleanup(func() { slackSigningSecret = previousSecret })
+			srv := startServer(t, fixtureTranscript())
+			before := playback.get("")
+
+			// The form Slack posts for a slash command
+			body := url.Values{
+				"command":   {"/incident"},
+				"text":      {tt.text},
+				"user_name": {"priya"},
+			}.Encode()
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			req, err := http.NewRequest(method, srv.URL+"/slack/command", strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.signedWith != "" {
+				timestamp := strconv.FormatInt(clock.Now().Add(-tt.age).Unix(), 10)
+				req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+				req.Header.Set("X-Slack-Signature", slackSignature(tt.signedWith, timestamp, body))
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			data, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status %d, want %d: %s", resp.StatusCode, tt.status, data)
+			}
+
+			want := tt.speed
+			if want == 0 {
+				want = before
+			}
+			if got := playback.get(""); got != want {
+				t.Errorf("speed %.1f afterwards, want %.1f", got, want)
+			}
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+
+			var reply struct {
+				ResponseType string `json:"response_type"`
+				Text         string `json:"text"`
+			}
+			if err := json.Unmarshal(data, &reply); err != nil {
+				t.Fatalf("decode %q: %v", data, err)
+			}
+			if reply.ResponseType != "ephemeral" {
+				t.Errorf("response_type %q, want ephemeral", reply.ResponseType)
+			}
+			if !strings.Contains(reply.Text, tt.reply) {
+				t.Errorf("reply %q, want it to mention %q", reply.Text, tt.reply)
+			}
+		})
+	}
+}
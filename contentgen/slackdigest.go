@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Most lines a digest quotes for each channel, the most severe and recent first
+const digestLinesPerChannel = 3
+
+// Longest quoted line in a digest before it is cut short
+const digestLineLimit = 200
+
+// Slack publishing that posts one digest per window of incident time instead
+// of every event: how much each channel said and its most notable lines.
+// A window's digest goes out when the first event past it is published,
+// so quiet stretches post nothing, and a final one when the replay completes.
+type slackDigest struct {
+	client   *SlackClient
+	Interval int // incident seconds covered by each digest
+
+	mu      sync.Mutex
+	window  int     // index of the window being collected, its start offset / Interval
+	pending []Event // published events of that window, in order
+}
+
+// Wrap a Slack client so it posts a digest per interval of incident time
+func newSlackDigest(client *SlackClient, interval time.Duration) *slackDigest {
+	return &slackDigest{client: client, Interval: max(int(interval.Seconds()), 1)}
+}
+
+// Name of the digest backend, which is still Slack
+func (d *slackDigest) Name() string {
+	return d.client.Name()
+}
+
+// Report whether a transcript channel is mapped to a Slack destination
+func (d *slackDigest) Routes(channel string) bool {
+	return d.client.Routes(channel)
+}
+
+// Collect an event for its window's digest, first posting the digest of the
+// previous window if the event starts a new one
+func (d *slackDigest) Publish(event Event) error {
+	d.mu.Lock()
+	window := event.TimeOffset / d.Interval
+	var due []Event
+	if len(d.pending) > 0 && window != d.window {
+		due, d.pending = d.pending, nil
+	}
+	d.window = window
+	d.pending = append(d.pending, event)
+	d.mu.Unlock()
+
+	if due != nil {
+		d.post(due, false)
+	}
+	return errPublishQueued
+}
+
+// Post whatever the current window has collected as the final digest, once
+// the replay completes
+func (d *slackDigest) flush() {
+	d.mu.Lock()
+	due := d.pending
+	d.pending = nil
+	d.mu.Unlock()
+
+	if len(due) > 0 {
+		d.post(due, true)
+	}
+}
+
+// Post a digest of events to each Slack channel they are mapped to, and
+// record the outcome for every event it covers
+func (d *slackDigest) post(events []Event, final bool) {
+	destinations := make(map[string][]Event)
+	var order []string
+	for _, event := range events {
+		id := d.client.Channels[event.Channel]
+		if _, ok := destinations[id]; !ok {
+			order = append(order, id)
+		}
+		destinations[id] = append(destinations[id], event)
+	}
+
+	backend := d.Name()
+	for _, id := range order {
+		covered := destinations[id]
+		if err := d.client.PostText(id, d.text(covered, final)); err != nil {
+			chatPublishCounter.WithLabelValues(backend, "failure").Add(float64(len(covered)))
+			slog.Warn("⚠️  Failed to post Slack digest", "slack_channel", id, "events", len(covered), "result", "failure", "err", err)
+			continue
+		}
+		chatPublishCounter.WithLabelValues(backend, "success").Add(float64(len(covered)))
+		slog.Info("📰 Posted Slack digest", "slack_channel", id, "events", len(covered), "final", final, "result", "success")
+	}
+}
+
+// Digest text: a header naming the stretch of incident time, then each
+// channel's event count, any warnings and errors, and its notable lines
+func (d *slackDigest) text(events []Event, final bool) string {
+	var b strings.Builder
+	first, last := events[0].TimeOffset, events[len(events)-1].TimeOffset
+	heading := "📰 Digest"
+	if final {
+		heading = "📰 Final digest"
+	}
+	if title, _ := d.client.incident(); title != "" {
+		heading += ": " + title
+	}
+	fmt.Fprintf(&b, "*%s*\n_%s to %s_\n", heading, formatOffset(first), formatOffset(last))
+
+	byChannel := make(map[string][]Event)
+	var channels []string
+	for _, event := range events {
+		if _, ok := byChannel[event.Channel]; !ok {
+			channels = append(channels, event.Channel)
+		}
+		byChannel[event.Channel] = append(byChannel[event.Channel], event)
+	}
+
+	for _, channel := range channels {
+		channelEvents := byChannel[channel]
+		levels := make(map[string]int)
+		for _, event := range channelEvents {
+			levels[event.level()]++
+		}
+		fmt.Fprintf(&b, "\n• *#%s*: %d %s", channel, len(channelEvents), pluralize(len(channelEvents), "event"))
+		if levels[levelError] > 0 || levels[levelWarn] > 0 {
+			fmt.Fprintf(&b, " (%d %s, %d %s)", levels[levelError], pluralize(levels[levelError], "error"), levels[levelWarn], pluralize(levels[levelWarn], "warning"))
+		}
+		for _, event := range notableEvents(channelEvents) {
+			line, _, _ := strings.Cut(event.displayText(), "\n")
+			fmt.Fprintf(&b, "\n> `%s` %s", strings.TrimPrefix(formatOffset(event.TimeOffset), "T+"), truncateText(line, digestLineLimit))
+		}
+	}
+	return b.String()
+}
+
+// A channel's lines worth quoting: the most severe, latest first among
+// equals, back in timeline order
+func notableEvents(events []Event) []Event {
+	order := make([]int, len(events))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		sa, sb := levelSeverity[events[order[a]].level()], levelSeverity[events[order[b]].level()]
+		if sa != sb {
+			return sa > sb
+		}
+		return order[a] > order[b]
+	})
+	order = order[:min(len(order), digestLinesPerChannel)]
+	slices.Sort(order)
+
+	notable := make([]Event, len(order))
+	for i, index := range order {
+		notable[i] = events[index]
+	}
+	return notable
+}
+
+// Singular or plural form of a noun for a count
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return noun
+	}
+	return noun + "s"
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlackDigest(t *testing.T) {
+	events := []Event{
+		{TimeOffset: 0, Channel: "team", Message: "Paging on-call"},
+		{TimeOffset: 3, Channel: "metrics", Message: "error_rate=12%", Level: levelError},
+		{TimeOffset: 5, Channel: "team", Message: "Rolling back"},
+		{TimeOffset: 12, Channel: "team", Message: "Found the bad deploy"},
+		{TimeOffset: 35, Channel: "team", Message: "Resolved"},
+	}
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		posted   []int    // digests posted once each event but the last has been published
+		total    int      // digests posted once the replay completes
+		first    []string // substrings of the first digest
+	}{
+		{"10s windows", 10 * time.Second, []int{0, 0, 0, 1}, 3, []string{
+			"*📰 Digest: Checkout outage*", "_T+00:00:00 to T+00:00:05_",
+			"• *#team*: 2 events", "• *#metrics*: 1 event (1 error, 0 warnings)", "`00:00:03` error_rate=12%",
+		}},
+		{"4s windows", 4 * time.Second, []int{0, 0, 1, 2}, 4, []string{
+			"_T+00:00:00 to T+00:00:03_", "• *#team*: 1 event", "• *#metrics*: 1 event",
+		}},
+		// Quiet windows post nothing, so one wide window is just the final digest
+		{"one window", time.Minute, []int{0, 0, 0, 0}, 1, []string{
+			"*📰 Final digest: Checkout outage*", "_T+00:00:00 to T+00:00:35_", "• *#team*: 4 events",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			slack := useFakeSlack(t)
+			// Both channels share one destination, so each window is one post
+			slackClient.Channels["metrics"] = "C0123ABCD"
+			slackClient.IncidentTitle = "Checkout outage"
+			chatNotifier = newSlackDigest(slackClient, tt.interval)
+
+			srv := startServer(t, &IncidentTranscript{Incident: IncidentInfo{Title: "Checkout outage", DurationSeconds: 35}, Events: events})
+			stream := openSSE(t, srv.URL+"/stream?channels=team,metrics")
+			for i, event := range events {
+				if i > 0 {
+					clock.step(t)
+				}
+				stream.until(t, event.Message)
+				// The last event completes the replay, which posts the final digest
+				if i == len(tt.posted) {
+					break
+				}
+				eventOutbox.wait()
+				if got := len(slack.received()); got != tt.posted[i] {
+					t.Errorf("%d digests after T+%d, want %d", got, event.TimeOffset, tt.posted[i])
+				}
+			}
+
+			stream.until(t, "Incident replay completed")
+			posts := slack.waitForPosts(t, tt.total)
+			if len(posts) != tt.total {
+				t.Fatalf("%d digests posted, want %d", len(posts), tt.total)
+			}
+			for _, want := range tt.first {
+				if !strings.Contains(posts[0].Text, want) {
+					t.Errorf("first digest %q, want it to mention %q", posts[0].Text, want)
+				}
+			}
+			if last := posts[len(posts)-1].Text; !strings.Contains(last, "📰 Final digest") || !strings.Contains(last, "Resolved") {
+				t.Errorf("last digest %q, want the final one with the last event", last)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// What Slack pacing does with events that arrive faster than the allowed rate
+const (
+	slackPaceQueue    = "queue"    // hold them and post at the allowed rate
+	slackPaceDrop     = "drop"     // skip them, posting only events spaced far enough apart
+	slackPaceCoalesce = "coalesce" // post a channel's held text messages as one batched post
+)
+
+// Publish outcomes that aren't failures, because the pacer deferred or skipped the post
+var (
+	errPublishQueued  = errors.New("queued for paced delivery")
+	errPublishDropped = errors.New("dropped to stay under the publish rate")
+)
+
+// Proactive pacing in front of Slack, so fast playback of a dense channel
+// stays within Slack's rate limits and readable. Unlike retry with backoff,
+// this keeps posts at least Interval apart before Slack ever complains.
+// Queued posts are delivered in order on a background worker.
+type slackPacer struct {
+	client   *SlackClient
+	Interval time.Duration // minimum time between posts
+	Policy   string        // queue, drop or coalesce; see slackPaceQueue and friends
+
+	mu      sync.Mutex
+	last    time.Time // when the last post was let through, for the drop policy
+	queue   chan pacedEvent
+	pending sync.WaitGroup // queued events not yet posted
+}
+
+// An event held for paced delivery, with the replay it fired from so a
+// failed post reaches that replay's viewers
+type pacedEvent struct {
+	replay *replay // nil when published outside a replay
+	event  Event
+}
+
+// Wrap a Slack client in a pacer and start its delivery worker
+func newSlackPacer(client *SlackClient, interval time.Duration, policy string) *slackPacer {
+	p := &slackPacer{client: client, Interval: interval, Policy: policy}
+	if policy != slackPaceDrop {
+		p.queue = make(chan pacedEvent, webhookQueueSize)
+		go p.run()
+	}
+	return p
+}
+
+// Name of the paced backend, which is still Slack
+func (p *slackPacer) Name() string {
+	return p.client.Name()
+}
+
+// Report whether a transcript channel is mapped to a Slack destination
+func (p *slackPacer) Routes(channel string) bool {
+	return p.client.Routes(channel)
+}
+
+// Post an event now if the rate allows, otherwise queue or drop it per policy
+func (p *slackPacer) Publish(event Event) error {
+	return p.publishFor(nil, event)
+}
+
+// Publish an event fired by rp, which hears about a queued post that fails
+func (p *slackPacer) publishFor(rp *replay, event Event) error {
+	if p.Policy == slackPaceDrop {
+		p.mu.Lock()
+		now := wallClock.Now()
+		if !p.last.IsZero() && now.Sub(p.last) < p.Interval {
+			p.mu.Unlock()
+			return errPublishDropped
+		}
+		p.last = now
+		p.mu.Unlock()
+		return p.client.Publish(event)
+	}
+
+	p.pending.Add(1)
+	select {
+	case p.queue <- pacedEvent{replay: rp, event: event}:
+		return errPublishQueued
+	default:
+		p.pending.Done()
+		return fmt.Errorf("Slack pacing queue full: %w", errPublishDropped)
+	}
+}
+
+// Wait until everything queued so far has been posted
+func (p *slackPacer) wait() {
+	p.pending.Wait()
+}
+
+// Deliver queued events no faster than the interval, merging a channel's
+// consecutive text messages into one post when coalescing
+func (p *slackPacer) run() {
+	var next time.Time
+	var held []pacedEvent
+	for {
+		if len(held) == 0 {
+			held = append(held, <-p.queue)
+		}
+		if wait := next.Sub(wallClock.Now()); wait > 0 {
+			<-wallClock.After(wait)
+		}
+
+		event, n := held[0].event, 1
+		if p.Policy == slackPaceCoalesce {
+			// Everything queued while waiting is eligible for the batch
+			for drained := false; !drained && len(held) < webhookQueueSize; {
+				select {
+				case queued := <-p.queue:
+					held = append(held, queued)
+				default:
+					drained = true
+				}
+			}
+			events := make([]Event, len(held))
+			for i, queued := range held {
+				events[i] = queued.event
+			}
+			event, n = coalesceEvents(events)
+		}
+		p.deliver(event, held[:n])
+		held = held[n:]
+		next = wallClock.Now().Add(p.Interval)
+		p.pending.Add(-n)
+	}
+}
+
+// Post one paced event, standing in for the queued events it covers, and
+// record the result, telling each event's replay when the post failed
+func (p *slackPacer) deliver(event Event, covered []pacedEvent) {
+	backend, n := p.Name(), len(covered)
+	if err := p.client.Publish(event); err != nil {
+		chatPublishCounter.WithLabelValues(backend, "failure").Add(float64(n))
+		slog.Warn("⚠️  Failed to publish to chat", "notifier", backend, "channel", event.Channel, "events", n, "result", "failure", "err", err)
+		for _, queued := range covered {
+			if queued.replay != nil {
+				queued.replay.reportChat(queued.event, chatResult{Backend: backend, Status: "failed", Reason: chatFailureReason(err)})
+			}
+		}
+		return
+	}
+	chatPublishCounter.WithLabelValues(backend, "success").Add(float64(n))
+	slog.Info("Published to chat", "notifier", backend, "channel", event.Channel, "events", n, "result", "success", "message", event.Message)
+}
+
+// Merge the leading run of text events on the same channel into one event,
+// one message per line, returning it and how many events it covers. The
+// batch keeps the first event's timing and the most severe level.
+func coalesceEvents(events []Event) (Event, int) {
+	first := events[0]
+	if first.kind() != eventText {
+		return first, 1
+	}
+
+	n := 1
+	for n < len(events) && events[n].Channel == first.Channel && events[n].kind() == eventText {
+		n++
+	}
+	if n == 1 {
+		return first, 1
+	}
+
+	lines := make([]string, 0, n)
+	merged := first
+	for _, event := range events[:n] {
+		lines = append(lines, event.Message)
+		if levelSeverity[event.level()] > levelSeverity[merged.level()] {
+			merged.Level = event.level()
+		}
+	}
+	merged.Message = strings.Join(lines, "\n")
+	return merged, n
+}
+
+// Ordering of event levels from least to most severe
+var levelSeverity = map[string]int{
+	levelInfo:  0,
+	levelWarn:  1,
+	levelError: 2,
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Post as Slack received it, with how far the fake clock had moved
+type pacedPost struct {
+	Text string
+	At   time.Duration
+}
+
+// Slack stand-in noting the fake time of every post, for a pacer in front of it
+func pacedSlack(t *testing.T, clock *fakeClock, interval time.Duration, policy string) (*slackPacer, func(n int) []pacedPost) {
+	t.Helper()
+	start := clock.Now()
+	var mu sync.Mutex
+	var posts []pacedPost
+	posted := make(chan struct{}, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("request body: %v", err)
+		}
+		mu.Lock()
+		posts = append(posts, pacedPost{payload.Text, clock.Now().Sub(start)})
+		mu.Unlock()
+		w.Write([]byte(`{"ok":true,"ts":"1"}`))
+		posted <- struct{}{}
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewSlackClient("xoxb-test", map[string]string{"team": "C0123ABCD"})
+	client.BaseURL, client.HTTPClient, client.BlockKit = srv.URL, srv.Client(), false
+
+	// Wait until Slack has n posts, stepping the clock whenever the pacer
+	// holds one back
+	wait := func(n int) []pacedPost {
+		t.Helper()
+		for {
+			mu.Lock()
+			got := append([]pacedPost(nil), posts...)
+			mu.Unlock()
+			if len(got) >= n {
+				return got
+			}
+			select {
+			case <-posted:
+			case <-clock.set:
+				clock.AdvanceToNext()
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Slack received %d posts, want %d", len(got), n)
+			}
+		}
+	}
+	pacer := newSlackPacer(client, interval, policy)
+	// Let the worker finish with the fake clock before it goes
+	t.Cleanup(pacer.wait)
+	return pacer, wait
+}
+
+func TestSlackPacing(t *testing.T) {
+	burst := []string{"e1", "e2", "e3", "e4"}
+	tests := []struct {
+		name   string
+		policy string
+		want   []pacedPost
+	}{
+		{"queue", slackPaceQueue, []pacedPost{
+			{"e0", 0}, {"e1", time.Second}, {"e2", 2 * time.Second}, {"e3", 3 * time.Second}, {"e4", 4 * time.Second},
+		}},
+		{"coalesce", slackPaceCoalesce, []pacedPost{
+			{"e0", 0}, {"e1\ne2\ne3\ne4", time.Second},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			pacer, wait := pacedSlack(t, clock, time.Second, tt.policy)
+
+			// One post goes straight out, then a burst arrives while the
+			// pacer holds off until the interval has passed
+			if err := pacer.Publish(Event{Channel: "team", Message: "e0"}); !errors.Is(err, errPublishQueued) {
+				t.Fatalf("Publish: %v, want it queued", err)
+			}
+			wait(1)
+			for _, message := range burst {
+				if err := pacer.Publish(Event{Channel: "team", Message: message}); !errors.Is(err, errPublishQueued) {
+					t.Fatalf("Publish %s: %v, want it queued", message, err)
+				}
+			}
+			if got := wait(len(tt.want)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Slack received %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlackPacingDrop(t *testing.T) {
+	clock := useFakeClock(t)
+	pacer, wait := pacedSlack(t, clock, time.Second, slackPaceDrop)
+
+	// Events arrive every 400ms; only those a full second after the last
+	// post go through
+	sends := []struct {
+		message string
+		dropped bool
+	}{
+		{"e0", false}, {"e1", true}, {"e2", true}, {"e3", false}, {"e4", true}, {"e5", true}, {"e6", false},
+	}
+	for i, send := range sends {
+		if i > 0 {
+			clock.Advance(400 * time.Millisecond)
+		}
+		err := pacer.Publish(Event{Channel: "team", Message: send.message})
+		if dropped := errors.Is(err, errPublishDropped); dropped != send.dropped || (!dropped && err != nil) {
+			t.Errorf("Publish %s: %v, want dropped %v", send.message, err, send.dropped)
+		}
+	}
+	want := []pacedPost{{"e0", 0}, {"e3", 1200 * time.Millisecond}, {"e6", 2400 * time.Millisecond}}
+	if got := wait(len(want)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Slack received %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,902 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returned by feedChannel when a client is dropped for falling behind
+var errSlowClient = errors.New("client fell too far behind the replay")
+
+// Returned by a delivery callback to end the stream once the channel completes
+var errStreamComplete = errors.New("stream closed after completion")
+
+// JSON frame for an event, used by JSON SSE streams and WebSockets
+type eventFrame struct {
+	Time    string            `json:"time"`
+	Offset  int               `json:"offset"`
+	Channel string            `json:"channel"`
+	Speaker string            `json:"speaker,omitempty"`
+	Message string            `json:"message"`
+	Type    string            `json:"type,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+	Level   string            `json:"level"`
+	Catchup bool              `json:"backfill,omitempty"` // emitted before the client joined, sent with ?catchup=true
+	Chat    *chatResult       `json:"chat,omitempty"`     // what became of the event's chat publish, when it was sent
+	Repeats int               `json:"repeats,omitempty"`  // with ?dedupe, the similar lines this one rolls up
+}
+
+// Build the JSON frame for an event emitted at the given wall time
+func newEventFrame(event Event, at time.Time) eventFrame {
+	return eventFrame{
+		Time:    formatEventTime(event, at),
+		Offset:  event.TimeOffset,
+		Channel: event.Channel,
+		Speaker: event.Speaker,
+		Message: event.Message,
+		Type:    event.Type,
+		Meta:    event.Meta,
+		Level:   event.level(),
+	}
+}
+
+// Build the JSON frame for a live event, with the outcome of its chat publish
+func newLiveEventFrame(msg replayMessage, at time.Time) eventFrame {
+	frame := newEventFrame(msg.Event, at)
+	if msg.Chat.Backend != "" {
+		chat := msg.Chat
+		frame.Chat = &chat
+	}
+	frame.Repeats = msg.Repeats
+	return frame
+}
+
+// Banner for incident time skipped by -max-quiet, in minutes once it's that long
+func describeQuietSkip(seconds int) string {
+	amount, unit := seconds, "second"
+	if seconds >= 60 {
+		amount, unit = int(math.Round(float64(seconds)/60)), "minute"
+	}
+	if amount != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("⏩ (skipped %d %s of quiet)", amount, unit)
+}
+
+// Most recent past events sent to a client joining with ?catchup=true
+const catchupLimit = 100
+
+// JSON frame for connection banners and replay markers
+type markerFrame struct {
+	Event   string `json:"event"` // connected, incident, reverse, start, chaos, scheduled, empty, catchup, channel_complete, complete, restarted, seeked, annotation, telemetry_gap_start, telemetry_gap_end, quiet_skipped, chat_failed or timeout
+	Channel string `json:"channel,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Offset  *int   `json:"offset,omitempty"`
+	Message string `json:"message,omitempty"`
+	Note    string `json:"note,omitempty"`
+}
+
+// JSON frame setting the scene before the timeline starts, with ?intro=full
+type introFrame struct {
+	Event           string `json:"event"` // always "intro"
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+	DurationSeconds int    `json:"duration_seconds"`
+}
+
+// Build the intro frame for an incident
+func newIntroFrame(info IncidentInfo) introFrame {
+	return introFrame{Event: "intro", Title: info.Title, Description: info.Description, DurationSeconds: info.DurationSeconds}
+}
+
+// Plain-text intro banners: the description, if there is one, then the duration
+func (f introFrame) lines() []string {
+	var lines []string
+	if f.Description != "" {
+		lines = append(lines, "📝 "+f.Description)
+	}
+	return append(lines, "⏱ Duration: "+strings.TrimPrefix(formatOffset(f.DurationSeconds), "T+"))
+}
+
+// Incident time between progress frames on JSON streams; zero sends none
+var progressInterval = 5 * time.Second
+
+// How often a stream checks the timeline for progress to report
+const progressPoll = 250 * time.Millisecond
+
+// JSON frame reporting how far the replay has played, so clients can draw a
+// progress bar without tracking offsets themselves
+type progressFrame struct {
+	Event    string  `json:"event"` // always "progress"
+	Offset   int     `json:"offset"`
+	Duration int     `json:"duration"`
+	Percent  float64 `json:"percent"`
+}
+
+// Decides when a stream is due its next progress frame: whenever the
+// timeline crosses into another -progress-interval of incident time, and
+// once more on reaching 100%. A replay that isn't moving, waiting to start
+// or stepping, sends nothing until it does.
+type progressTracker struct {
+	mark    int // interval the last frame was sent in, or -1 before the first
+	percent float64
+}
+
+// Return the frame to send for the timeline's current position, if one is due
+func (p *progressTracker) next(offset, percent float64, duration int) (progressFrame, bool) {
+	mark := int(offset / progressInterval.Seconds())
+	if mark == p.mark && (percent < 100 || p.percent >= 100) {
+		return progressFrame{}, false
+	}
+	p.mark, p.percent = mark, percent
+	return progressFrame{Event: "progress", Offset: int(offset), Duration: duration, Percent: math.Round(percent*10) / 10}, true
+}
+
+// JSON frame summarizing what a stream delivered once its channel completes
+type summaryFrame struct {
+	Event        string  `json:"event"` // always "summary"
+	Channel      string  `json:"channel"`
+	Events       int     `json:"events"`
+	WallSeconds  float64 `json:"wall_seconds"`
+	AverageSpeed float64 `json:"average_speed"`
+	Published    int     `json:"published"`
+}
+
+// Running tally of one stream's events, for the completion summary
+type streamTally struct {
+	connected   time.Time
+	events      int
+	published   int
+	first, last time.Time // wall time of the first and last event
+	firstOffset int
+	lastOffset  int
+}
+
+// Count a delivered event
+func (t *streamTally) add(msg replayMessage, at time.Time) {
+	if t.events == 0 {
+		t.first, t.firstOffset = at, msg.Event.TimeOffset
+	}
+	t.last, t.lastOffset = at, msg.Event.TimeOffset
+	t.events++
+	if msg.Published {
+		t.published++
+	}
+}
+
+// Summarize the run so far. The average speed is incident time covered
+// between the first and last event over the wall time that took.
+func (t *streamTally) summary(channel string, now time.Time) summaryFrame {
+	frame := summaryFrame{
+		Event:       "summary",
+		Channel:     channel,
+		Events:      t.events,
+		WallSeconds: math.Round(now.Sub(t.connected).Seconds()*10) / 10,
+		Published:   t.published,
+	}
+	if elapsed := t.last.Sub(t.first).Seconds(); elapsed > 0 {
+		covered := math.Abs(float64(t.lastOffset - t.firstOffset))
+		frame.AverageSpeed = math.Round(covered/elapsed*100) / 100
+	}
+	return frame
+}
+
+// Human-readable form of the summary
+func (f summaryFrame) String() string {
+	return fmt.Sprintf("📊 Summary: %d events in %.1fs at %.2fx average speed, %d published to chat", f.Events, f.WallSeconds, f.AverageSpeed, f.Published)
+}
+
+// How long a stream with ?oncomplete=grace stays open after completing, so
+// clients can read the summary before the connection closes
+var completionGrace = 5 * time.Second
+
+// Longest a stream connection may stay open; zero means no limit
+var maxStreamDuration time.Duration
+
+// Bound a stream's lifetime by -max-stream-duration, if set
+func streamContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if maxStreamDuration <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, maxStreamDuration)
+}
+
+// Longest an SSE event may wait to be flushed together with the events after
+// it; zero flushes every event as soon as it is written
+var batchInterval time.Duration
+
+// Longest one write or flush to an SSE client may block before the client
+// counts as wedged and is dropped
+var streamWriteTimeout = 10 * time.Second
+
+// SSE response writer that bounds every write and flush with a deadline, so
+// a client that stops reading is detected rather than blocking the handler
+// forever. The first failure sticks: later writes fail fast with it.
+type deadlineWriter struct {
+	http.ResponseWriter
+	rc  *http.ResponseController
+	err error
+}
+
+// Wrap an SSE response in write deadlines
+func newDeadlineWriter(w http.ResponseWriter) *deadlineWriter {
+	return &deadlineWriter{ResponseWriter: w, rc: http.NewResponseController(w)}
+}
+
+// Give the next write a fresh deadline; network deadlines follow the real clock
+func (d *deadlineWriter) extend() {
+	d.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	d.extend()
+	n, err := d.ResponseWriter.Write(p)
+	if err != nil {
+		d.err = err
+	}
+	return n, err
+}
+
+func (d *deadlineWriter) Flush() {
+	if d.err != nil {
+		return
+	}
+	d.extend()
+	if err := d.rc.Flush(); err != nil {
+		d.err = err
+	}
+}
+
+// First write or flush failure, if any
+func (d *deadlineWriter) Err() error {
+	return d.err
+}
+
+// Lift the deadline so the server can finish the response once the handler returns
+func (d *deadlineWriter) clear() {
+	d.rc.SetWriteDeadline(time.Time{})
+}
+
+// Server-wide cap on open stream connections, one slot per connection; nil
+// when -max-clients is unset
+var streamSlots chan struct{}
+
+// Seconds a client turned away at the connection limit should wait
+const streamRetryAfter = 5
+
+// Claim a stream slot, or turn the client away with 503 and Retry-After
+// when the server is at -max-clients. Callers that get true must release
+// the slot when their handler returns.
+func acquireStreamSlot(w http.ResponseWriter, r *http.Request) bool {
+	if streamSlots == nil {
+		return true
+	}
+	select {
+	case streamSlots <- struct{}{}:
+		return true
+	default:
+		slog.Warn("⚠️  Rejected stream at client limit", "path", r.URL.Path, "remote_addr", r.RemoteAddr, "max_clients", cap(streamSlots))
+		w.Header().Set("Retry-After", strconv.Itoa(streamRetryAfter))
+		http.Error(w, "Too many clients, try again later", http.StatusServiceUnavailable)
+		return false
+	}
+}
+
+// Give a stream slot back
+func releaseStreamSlot() {
+	if streamSlots != nil {
+		<-streamSlots
+	}
+}
+
+// Open stream connections against -max-clients, for the status endpoint
+type clientLimitStatus struct {
+	Open int `json:"open"`
+	Max  int `json:"max"`
+}
+
+// Current use of the connection limit, or nil when there is none
+func streamLimitStatus() *clientLimitStatus {
+	if streamSlots == nil {
+		return nil
+	}
+	return &clientLimitStatus{Open: len(streamSlots), Max: cap(streamSlots)}
+}
+
+// Returned by waitUntil when its context ends before the target time
+var errWaitCanceled = errors.New("wait canceled")
+
+// Block until a wall-clock time, or fail with errWaitCanceled if the context
+// ends first. The timer is stopped on every path, so a canceled wait leaves
+// nothing pending on the clock.
+func waitUntil(ctx context.Context, target time.Time) error {
+	wait := target.Sub(wallClock.Now())
+	if wait <= 0 {
+		return nil
+	}
+	timer := wallClock.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return errWaitCanceled
+	case <-timer.C():
+		return nil
+	}
+}
+
+// Hold a connection until the scheduled replay start, reporting false if
+// the client went away first
+func waitForStart(ctx context.Context) bool {
+	return replayStartAt.IsZero() || waitUntil(ctx, replayStartAt) == nil
+}
+
+// Write a value as a single SSE data line of JSON
+func writeJSONData(w io.Writer, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal frame: %w", err)
+	}
+	return writeSSEData(w, string(payload))
+}
+
+// Write text as one SSE event. Each line of the text gets its own data line,
+// as the SSE spec requires, so a message with newlines such as a pasted stack
+// trace arrives intact instead of breaking the stream's blank-line framing.
+func writeSSEData(w io.Writer, text string) error {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString("data: ")
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Per-connection stream options shared by every transport
+type streamOptions struct {
+	lifecycle bool   // emit machine-parseable lifecycle signals
+	filter    string // lowercase keyword an event must contain
+	reverse   bool   // play the channel backward on a private timeline
+	start     int    // incident offset to begin at on a private timeline, or -1
+	json      bool   // send events and banners as JSON objects instead of plain text
+	close     bool   // end the response once the channel completes instead of keeping it open
+	grace     bool   // with close, first wait -completion-grace and send the summary again
+	catchup   bool   // first send the channel's events emitted before the client joined
+	intro     bool   // open with the incident description and duration, not just the title
+	progress  bool   // send progress frames every -progress-interval of incident time
+	dedupe    string // collapse repeated metric lines: dedupeExact or dedupePrefix, empty for off
+	client    string // id for per-client controls such as /restart?client=<id>, on a private timeline
+}
+
+// Read stream options from the request query
+func parseStreamOptions(r *http.Request, info IncidentInfo) (streamOptions, error) {
+	query := r.URL.Query()
+	opts := streamOptions{
+		lifecycle: query.Get("lifecycle") == "true",
+		catchup:   query.Get("catchup") == "true",
+		filter:    strings.ToLower(query.Get("filter")),
+		start:     -1,
+	}
+
+	switch format := query.Get("format"); format {
+	case "", "text":
+	case "json":
+		opts.json = true
+		opts.progress = progressInterval > 0
+	default:
+		return opts, fmt.Errorf("invalid format %q, expected text or json", format)
+	}
+
+	switch dedupe := query.Get("dedupe"); dedupe {
+	case "", "false":
+	case "true", dedupeExact:
+		opts.dedupe = dedupeExact
+	case dedupePrefix:
+		opts.dedupe = dedupePrefix
+	default:
+		return opts, fmt.Errorf("invalid dedupe %q, expected true or prefix", dedupe)
+	}
+
+	switch intro := query.Get("intro"); intro {
+	case "":
+	case "full":
+		opts.intro = true
+	default:
+		return opts, fmt.Errorf("invalid intro %q, expected full", intro)
+	}
+
+	switch onComplete := query.Get("oncomplete"); onComplete {
+	case "", "keepopen":
+	case "close":
+		opts.close = true
+	case "grace":
+		opts.close, opts.grace = true, true
+	default:
+		return opts, fmt.Errorf("invalid oncomplete %q, expected close, grace or keepopen", onComplete)
+	}
+
+	switch direction := query.Get("direction"); direction {
+	case "", "forward":
+	case "reverse":
+		opts.reverse = true
+	default:
+		return opts, fmt.Errorf("invalid direction %q, expected forward or reverse", direction)
+	}
+
+	if value := query.Get("start"); value != "" {
+		start, err := strconv.Atoi(value)
+		if err != nil || start < 0 || start > info.DurationSeconds {
+			return opts, fmt.Errorf("start must be between 0 and %d seconds", info.DurationSeconds)
+		}
+		opts.start = start
+	}
+
+	if query.Has("client") {
+		opts.client = strings.TrimSpace(query.Get("client"))
+		if opts.client == "" || len(opts.client) > maxClientIDLength {
+			return opts, fmt.Errorf("client must be 1 to %d characters", maxClientIDLength)
+		}
+	}
+	return opts, nil
+}
+
+// Longest client id a stream may register under
+const maxClientIDLength = 64
+
+// Report whether the connection needs its own timeline instead of the
+// shared replay: for reverse or offset playback, or so a client can be
+// controlled on its own
+func (o streamOptions) private() bool {
+	return o.reverse || o.start >= 0 || o.client != ""
+}
+
+// Events of one or more transcript channels, in timeline order
+func channelEvents(t *IncidentTranscript, channels ...string) []Event {
+	var events []Event
+	for _, event := range t.Events {
+		if slices.Contains(channels, event.Channel) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Report whether an event passes the keyword filter. Matching is a
+// case-insensitive substring check on the message for now; events keep their
+// place on the shared timeline, so filtered events still appear at their
+// correct virtual moments.
+func (o streamOptions) matches(event Event) bool {
+	return o.filter == "" || strings.Contains(strings.ToLower(event.Message), o.filter)
+}
+
+// Transport-agnostic event feed: subscribe to channels of the shared replay,
+// or of a private one for reverse, offset or per-client playback, and hand each message to deliver
+// until the context ends, delivery fails, or the client falls too far behind.
+// Transports that buffer pass flush, which runs after each event or, with
+// -batch-interval, at most one interval after the first unflushed event; a
+// failed flush ends the feed like a failed delivery.
+func feedChannel(ctx context.Context, inc *incident, channels []string, opts streamOptions, deliver func(replayMessage) error, flush func() error) error {
+	source := inc.Replay
+	if opts.private() {
+		source = newPrivateReplay(channelEvents(inc.Transcript, channels...), inc.Replay.speed, opts.reverse, opts.start)
+		// Wait for its timeline to stop too, so none outlives the stream
+		defer func() {
+			source.stop()
+			source.wait()
+		}()
+		if opts.client != "" {
+			source.client = opts.client
+			inc.Replay.addClient(opts.client, source)
+			defer inc.Replay.removeClient(opts.client, source)
+		}
+	}
+
+	sub, backlog := source.subscribeWithBacklog(channels)
+	defer source.unsubscribe(sub)
+
+	// Latecomers get the recent past in one burst before the live feed;
+	// private timelines start fresh, so they have none
+	if opts.catchup && !opts.private() {
+		skipped := max(len(backlog)-catchupLimit, 0)
+		for _, event := range backlog[skipped:] {
+			if ctx.Err() != nil {
+				return nil
+			}
+			event = redactor.event(event)
+			if !opts.matches(event) || telemetryGap.hides(event) {
+				continue
+			}
+			if err := deliver(replayMessage{Kind: messageBackfill, Event: event, Skipped: skipped}); err != nil {
+				return err
+			}
+			skipped = 0 // reported with the first event sent
+		}
+		if flush != nil {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Fires once the oldest unflushed event has waited a full interval
+	var due <-chan time.Time
+	// Fires when it's time to check whether a progress frame is due
+	var poll <-chan time.Time
+	progress := &progressTracker{mark: -1}
+	dedupe := newLineDeduper(opts.dedupe)
+	if opts.progress {
+		poll = wallClock.After(0)
+	}
+	gapped := false // inside the telemetry gap, with its banner sent
+	defer func() {
+		if due != nil {
+			flush()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-due:
+			due = nil
+			if err := flush(); err != nil {
+				return err
+			}
+		case <-poll:
+			poll = wallClock.After(progressPoll)
+			duration := inc.Transcript.Incident.DurationSeconds
+			offset, percent := source.progress(duration)
+			frame, ok := progress.next(offset, percent, duration)
+			if !ok {
+				continue
+			}
+			if err := deliver(replayMessage{Kind: messageProgress, Progress: frame}); err != nil {
+				return err
+			}
+		case msg, ok := <-sub.ch:
+			if !ok {
+				return errSlowClient
+			}
+
+			// Metrics inside the telemetry gap never arrive; the stream is
+			// told once when they go dark and once when they come back
+			switch {
+			case msg.Kind == messageRestarted || msg.Kind == messageSeeked:
+				gapped = false
+			case msg.Kind != messageEvent:
+			case telemetryGap.hides(msg.Event):
+				if !gapped {
+					gapped = true
+					if err := deliver(replayMessage{Kind: messageGapBegan, Event: msg.Event}); err != nil {
+						return err
+					}
+				}
+				continue
+			case gapped && msg.Event.Channel == telemetryChannel:
+				gapped = false
+				if err := deliver(replayMessage{Kind: messageGapEnded, Event: msg.Event}); err != nil {
+					return err
+				}
+			}
+
+			if (msg.Kind == messageEvent || msg.Kind == messageChatFailed) && !opts.matches(msg.Event) {
+				continue
+			}
+			if dedupe != nil {
+				rollups, show := dedupe.offer(msg)
+				for _, rollup := range rollups {
+					if err := deliver(rollup); err != nil {
+						return err
+					}
+				}
+				if !show {
+					continue
+				}
+			}
+			if err := deliver(msg); err != nil {
+				return err
+			}
+			switch {
+			case flush == nil:
+			case msg.Kind != messageEvent:
+				// Markers flush themselves, taking any batched events with them
+				due = nil
+			case batchInterval <= 0:
+				if err := flush(); err != nil {
+					return err
+				}
+			case due == nil:
+				due = wallClock.After(batchInterval)
+			}
+		}
+	}
+}
+
+// Stream one transcript channel of an incident's shared replay to an SSE client
+func streamChannel(w http.ResponseWriter, r *http.Request, inc *incident, channel, name string) {
+	streamChannels(w, r, inc, []string{channel}, name)
+}
+
+// Stream transcript channels of an incident's shared replay to an SSE client.
+// Several channels share the one connection, interleaved in timeline order,
+// with each event line prefixed by its channel.
+func streamChannels(w http.ResponseWriter, r *http.Request, inc *incident, channels []string, name string) {
+	if !acquireStreamSlot(w, r) {
+		return
+	}
+	defer releaseStreamSlot()
+
+	// Markers and logs name the channels together, e.g. metrics,team
+	channel := strings.Join(channels, ",")
+	combined := len(channels) > 1
+
+	// Count the viewer for as long as the handler runs, whatever path it returns by
+	clients := 0
+	for _, c := range channels {
+		clients = max(clients, inc.Replay.connect(c))
+	}
+	defer func() {
+		clients := 0
+		for _, c := range channels {
+			clients = max(clients, inc.Replay.disconnect(c))
+		}
+		slog.Info("👥 Viewer left stream", "incident", inc.ID, "channel", channel, "clients", clients)
+	}()
+
+	// Abandoned connections end on their own after the time limit
+	ctx, cancel := streamContext(r.Context())
+	defer cancel()
+
+	opts, err := parseStreamOptions(r, inc.Transcript.Incident)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Set headers for SSE
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// Bound every write so a client that stops reading can't wedge the handler
+	dw := newDeadlineWriter(w)
+	defer dw.clear()
+	w, flusher = dw, dw
+
+	// Compress the stream for clients that accept gzip
+	if acceptsGzip(r) {
+		gzw := newGzipResponseWriter(w, flusher)
+		defer gzw.Close()
+		w, flusher = gzw, gzw
+	}
+
+	slog.Info("Client connected to stream", "incident", inc.ID, "channel", channel, "remote_addr", r.RemoteAddr, "reverse", opts.reverse, "start", opts.start, "clients", clients)
+
+	// Banners and markers are plain text, or JSON objects with ?format=json
+	banner := func(text string, marker markerFrame) {
+		if opts.json {
+			writeJSONData(w, marker)
+		} else {
+			writeSSEData(w, text)
+		}
+		flusher.Flush()
+	}
+
+	// Tell the client the stream hit its time limit, as opposed to going away
+	limitReached := func() bool {
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return false
+		}
+		banner("⏲ Stream time limit reached", markerFrame{Event: "timeout", Channel: channel})
+		slog.Info("⏲ Stream time limit reached", "incident", inc.ID, "channel", channel, "remote_addr", r.RemoteAddr, "limit", maxStreamDuration)
+		return true
+	}
+
+	// Send initial connection message
+	banner(fmt.Sprintf("🔗 Connected to %s stream", name), markerFrame{Event: "connected", Channel: channel})
+	banner(fmt.Sprintf("📋 Incident: %s", inc.Transcript.Incident.Title), markerFrame{Event: "incident", Title: inc.Transcript.Incident.Title})
+	if opts.intro {
+		intro := newIntroFrame(inc.Transcript.Incident)
+		if opts.json {
+			writeJSONData(w, intro)
+		} else {
+			for _, line := range intro.lines() {
+				writeSSEData(w, line)
+			}
+		}
+		flusher.Flush()
+	}
+	if opts.reverse {
+		banner("⏪ Playing in reverse, from resolution back to root cause", markerFrame{Event: "reverse", Channel: channel})
+	}
+	if opts.start >= 0 {
+		start := opts.start
+		banner(fmt.Sprintf("⏱ Starting at T+%ds", start), markerFrame{Event: "start", Channel: channel, Offset: &start})
+	}
+	if chaos != nil {
+		banner(chaos.describe(), markerFrame{Event: "chaos", Channel: channel, Message: chaos.describe()})
+	}
+
+	// Early viewers wait for a scheduled start together
+	if wallClock.Now().Before(replayStartAt) {
+		at := replayStartAt.In(timestampLocation).Format(time.RFC3339)
+		banner(fmt.Sprintf("⏳ Replay starts at %s", at), markerFrame{Event: "scheduled", Channel: channel, Message: at})
+		if !waitForStart(ctx) {
+			if !limitReached() {
+				slog.Info("Client disconnected from stream", "incident", inc.ID, "channel", channel, "remote_addr", r.RemoteAddr)
+			}
+			return
+		}
+	}
+
+	// Say so when the channel has nothing to replay, rather than completing silently
+	if len(channelEvents(inc.Transcript, channels...)) == 0 {
+		slog.Info("ℹ️  No events for channel", "incident", inc.ID, "channel", channel)
+		banner(fmt.Sprintf("ℹ️ No events for channel '%s'", channel), markerFrame{Event: "empty", Channel: channel})
+	}
+
+	// Opt-in lifecycle signals for clients that don't want to parse banners
+	if opts.lifecycle {
+		sendSystemEvent(w, flusher, lifecycleStart, channel)
+	}
+
+	tally := &streamTally{connected: wallClock.Now()}
+	// Combined streams say which channel each event line came from
+	prefix := func(event Event) string {
+		if !combined {
+			return ""
+		}
+		return fmt.Sprintf("[%s] ", strings.ToUpper(event.Channel))
+	}
+
+	err = feedChannel(ctx, inc, channels, opts, func(msg replayMessage) error {
+		switch msg.Kind {
+		case messageComplete:
+			// Send completion message
+			banner("✅ Incident replay completed", markerFrame{Event: lifecycleComplete, Channel: channel})
+			if opts.lifecycle {
+				sendSystemEvent(w, flusher, lifecycleComplete, channel)
+			}
+
+			// Then a readout of what this stream delivered
+			summary := tally.summary(channel, wallClock.Now())
+			if opts.json {
+				writeJSONData(w, summary)
+			} else {
+				writeSSEData(w, summary.String())
+			}
+			flusher.Flush()
+			if opts.grace {
+				// Give the client a moment to read the summary, then repeat
+				// it in case it came in with a burst of final events
+				if waitUntil(ctx, wallClock.Now().Add(completionGrace)) != nil {
+					return nil
+				}
+				if opts.json {
+					writeJSONData(w, summary)
+				} else {
+					writeSSEData(w, summary.String())
+				}
+				flusher.Flush()
+			}
+			if opts.close {
+				return errStreamComplete
+			}
+		case messageChannelComplete:
+			// One channel of a combined stream is done; the others play on
+			done := msg.Event.Channel
+			banner(fmt.Sprintf("✅ Channel '%s' complete", done), markerFrame{Event: lifecycleChannelComplete, Channel: done})
+			if opts.lifecycle {
+				sendSystemEvent(w, flusher, lifecycleChannelComplete, done)
+			}
+		case messageRestarted:
+			tally = &streamTally{connected: wallClock.Now()}
+			banner("🔁 Restarting incident replay", markerFrame{Event: lifecycleRestarted, Channel: channel})
+			if opts.lifecycle {
+				sendSystemEvent(w, flusher, lifecycleRestarted, channel)
+			}
+		case messagePaused:
+			banner("⏸️ Replay paused", markerFrame{Event: lifecyclePaused, Channel: channel})
+			if opts.lifecycle {
+				sendSystemEvent(w, flusher, lifecyclePaused, channel)
+			}
+		case messageResumed:
+			banner("▶️ Replay resumed", markerFrame{Event: lifecycleResumed, Channel: channel})
+			if opts.lifecycle {
+				sendSystemEvent(w, flusher, lifecycleResumed, channel)
+			}
+		case messageSeeked:
+			offset := msg.Event.TimeOffset
+			banner(fmt.Sprintf("⏩ Seeked to T+%ds", offset), markerFrame{Event: "seeked", Channel: channel, Offset: &offset})
+		case messageGapBegan:
+			start := telemetryGap.Start
+			banner("📉 Telemetry gap: metrics are unavailable", markerFrame{Event: "telemetry_gap_start", Channel: channel, Offset: &start})
+		case messageGapEnded:
+			end := telemetryGap.End
+			banner("📈 Telemetry restored: metrics are flowing again", markerFrame{Event: "telemetry_gap_end", Channel: channel, Offset: &end})
+		case messageProgress:
+			writeJSONData(w, msg.Progress)
+			flusher.Flush()
+		case messageQuietSkip:
+			banner(describeQuietSkip(msg.Skipped), markerFrame{Event: "quiet_skipped", Channel: channel, Message: strconv.Itoa(msg.Skipped)})
+		case messageAnnotated:
+			offset := msg.Mark.Offset
+			banner("📌 "+msg.Mark.Label, markerFrame{Event: "annotation", Channel: channel, Offset: &offset, Message: msg.Mark.Label, Note: msg.Mark.Note})
+		case messageChatFailed:
+			offset := msg.Event.TimeOffset
+			banner(fmt.Sprintf("📮 %s%s%s", prefix(msg.Event), msg.Event.displayText(), msg.Chat.suffix()), markerFrame{Event: "chat_failed", Channel: msg.Event.Channel, Offset: &offset, Message: msg.Event.Message, Note: msg.Chat.outcome()})
+		case messageBackfill:
+			if msg.Skipped > 0 {
+				banner(fmt.Sprintf("⏪ Catching up: %d earlier events not shown", msg.Skipped), markerFrame{Event: "catchup", Channel: channel, Message: strconv.Itoa(msg.Skipped)})
+			}
+			now := wallClock.Now()
+			if opts.json {
+				frame := newEventFrame(msg.Event, now)
+				frame.Catchup = true
+				if err := writeJSONData(w, frame); err != nil {
+					return err
+				}
+			} else {
+				writeSSEData(w, fmt.Sprintf("[catch-up] %s[%s] %s", prefix(msg.Event), formatEventTime(msg.Event, now), msg.Event.displayText()))
+			}
+		default:
+			// Format and send the event
+			now := wallClock.Now()
+			tally.add(msg, now)
+			if opts.json {
+				if err := writeJSONData(w, newLiveEventFrame(msg, now)); err != nil {
+					return err
+				}
+			} else {
+				writeSSEData(w, fmt.Sprintf("%s[%s] %s%s%s", prefix(msg.Event), formatEventTime(msg.Event, now), msg.Event.displayText(), describeRepeats(msg.Repeats), msg.Chat.suffix()))
+			}
+		}
+		return dw.Err()
+	}, func() error {
+		flusher.Flush()
+		return dw.Err()
+	})
+
+	if errors.Is(err, errSlowClient) {
+		slog.Warn("⚠️  Dropped slow client from stream", "incident", inc.ID, "channel", channel, "remote_addr", r.RemoteAddr)
+		return
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		slog.Warn("⚠️  Dropped slow client from stream", "incident", inc.ID, "channel", channel, "remote_addr", r.RemoteAddr, "write_timeout", streamWriteTimeout)
+		return
+	}
+	if errors.Is(err, errStreamComplete) {
+		slog.Info("Closed stream after completion", "incident", inc.ID, "channel", channel, "remote_addr", r.RemoteAddr)
+		return
+	}
+	if limitReached() {
+		return
+	}
+	slog.Info("Client disconnected from stream", "incident", inc.ID, "channel", channel, "remote_addr", r.RemoteAddr)
+}
@@ -0,0 +1,1296 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Lifecycle signal types among frames, in order, as "type:channel"
+func systemEvents(t *testing.T, frames []sseFrame) []string {
+	t.Helper()
+	var signals []string
+	for _, frame := range frames {
+		if frame.Event != "system" {
+			continue
+		}
+		var payload struct {
+			Type    string `json:"type"`
+			Channel string `json:"channel"`
+		}
+		if err := json.Unmarshal([]byte(frame.Data), &payload); err != nil {
+			t.Fatalf("system event %q: %v", frame.Data, err)
+		}
+		signals = append(signals, payload.Type+":"+payload.Channel)
+	}
+	return signals
+}
+
+// Fixture transcript cut down to the channels given, so each step of the
+// fake clock fires an event the test is watching
+func fixtureChannels(channels ...string) *IncidentTranscript {
+	tr := fixtureTranscript()
+	tr.Events = channelEvents(tr, channels...)
+	return tr
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	clock := useFakeClock(t)
+	srv := startServer(t, fixtureChannels("team"))
+	stream := openSSE(t, srv.URL+"/stream/team?lifecycle=true")
+
+	// Each step acts on the replay, then reads up to the frame that shows
+	// the effect, expecting exactly these signals on the way
+	steps := []struct {
+		name  string
+		act   func()
+		until string
+		want  []string
+	}{
+		{"connect", func() {}, "Paging on-call", []string{"start:team"}},
+		{"pause", func() { expectStatus(t, srv.URL+"/pause", http.StatusOK) }, "⏸️ Replay paused", nil},
+		{"paused signal", func() {}, "paused", []string{"paused:team"}},
+		{"resume", func() { expectStatus(t, srv.URL+"/resume", http.StatusOK) }, `"resumed"`, []string{"resumed:team"}},
+		{"next event", func() { clock.step(t) }, "Rolling back", nil},
+		{"restart", func() { expectStatus(t, srv.URL+"/restart", http.StatusOK) }, `"restarted"`, []string{"restarted:team"}},
+		{"replay again", func() {}, "Paging on-call", nil},
+		{"play out", func() { clock.step(t); clock.step(t) }, "✅ Incident replay completed", nil},
+		{"complete signal", func() {}, `"complete"`, []string{"complete:team"}},
+	}
+	for _, step := range steps {
+		step.act()
+		got := systemEvents(t, stream.until(t, step.until))
+		if strings.Join(got, ",") != strings.Join(step.want, ",") {
+			t.Errorf("%s: lifecycle signals %q, want %q", step.name, got, step.want)
+		}
+	}
+}
+
+// POST to a control endpoint, failing the test unless it answers status
+func expectStatus(t *testing.T, url string, status int) string {
+	t.Helper()
+	got, body := control(t, http.MethodPost, url, "")
+	if got != status {
+		t.Fatalf("POST %s: status %d, want %d: %s", url, got, status, body)
+	}
+	return body
+}
+
+func TestLifecycleEventsOptIn(t *testing.T) {
+	useFakeClock(t)
+	srv := startServer(t, fixtureTranscript())
+	stream := openSSE(t, srv.URL+"/stream/team")
+
+	frames := stream.until(t, "Paging on-call")
+	expectStatus(t, srv.URL+"/pause", http.StatusOK)
+	frames = append(frames, stream.until(t, "⏸️ Replay paused")...)
+	if got := systemEvents(t, frames); len(got) != 0 {
+		t.Errorf("stream without ?lifecycle=true got lifecycle signals %q", got)
+	}
+}
+
+func TestStreamFilter(t *testing.T) {
+	tr := fixtureTranscript()
+	tr.Events = []Event{
+		{TimeOffset: 0, Channel: "team", Message: "Paging on-call"},
+		{TimeOffset: 4, Channel: "team", Message: "ERROR: checkout returning 500s"},
+		{TimeOffset: 10, Channel: "team", Message: "Rolling back"},
+		{TimeOffset: 12, Channel: "team", Message: "Error rate back to baseline"},
+	}
+
+	tests := []struct {
+		name   string
+		filter string
+		want   []string
+	}{
+		{"case-insensitive substring", "error", []string{"ERROR: checkout returning 500s", "Error rate back to baseline"}},
+		{"no match", "latency", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startServer(t, tr)
+			var got []string
+			for _, line := range sseData(readSSE(t, srv.URL+"/stream/team?oncomplete=close&filter="+tt.filter)) {
+				for _, event := range tr.Events {
+					if strings.HasSuffix(line, event.Message) {
+						got = append(got, event.Message)
+					}
+				}
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("events %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamDirectionAndStart(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    []string // in order
+		missing []string
+	}{
+		{"reverse", "direction=reverse", []string{"⏪ Playing in reverse", "Resolved", "Rolling back", "Paging on-call"}, nil},
+		{"start offset", "start=5", []string{"⏱ Starting at T+5s", "Rolling back", "Resolved"}, []string{"Paging on-call"}},
+		{"reverse from start", "direction=reverse&start=15", []string{"⏪ Playing in reverse", "Rolling back", "Paging on-call"}, []string{"Resolved"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startServer(t, fixtureTranscript())
+			lines := sseData(readSSE(t, srv.URL+"/stream/team?oncomplete=close&"+tt.query))
+			if !containsInOrder(lines, tt.want...) {
+				t.Errorf("stream %q, want in order %q", lines, tt.want)
+			}
+			for _, skipped := range tt.missing {
+				if containsInOrder(lines, skipped) {
+					t.Errorf("skipped event %q was streamed", skipped)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamDirectionAndStartRejected(t *testing.T) {
+	srv := startServer(t, fixtureTranscript())
+	for _, query := range []string{"direction=sideways", "start=-5", "start=21", "start=soon"} {
+		if status, body := control(t, http.MethodGet, srv.URL+"/stream/team?"+query, ""); status != http.StatusBadRequest {
+			t.Errorf("?%s: status %d, want 400: %s", query, status, body)
+		}
+	}
+}
+
+func TestStreamJSONFrames(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want []string // "event" for markers, "channel@offset message" for events
+	}{
+		{"one channel", "/stream/team", []string{
+			"connected", "team@0 Paging on-call", "team@10 Rolling back", "team@20 Resolved", "complete",
+		}},
+		{"combined", "/stream?channels=team,metrics", []string{
+			"connected", "team@0 Paging on-call", "metrics@1 CPU 92%", "metrics@3 CPU 99%", "team@10 Rolling back", "team@20 Resolved", "complete",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startServer(t, fixtureTranscript())
+			sep := "?"
+			if strings.Contains(tt.path, "?") {
+				sep = "&"
+			}
+
+			var got []string
+			for _, frame := range readSSE(t, srv.URL+tt.path+sep+"format=json&oncomplete=close") {
+				var payload struct {
+					Event   string `json:"event"`
+					Time    string `json:"time"`
+					Offset  *int   `json:"offset"`
+					Channel string `json:"channel"`
+					Message string `json:"message"`
+				}
+				if err := json.Unmarshal([]byte(frame.Data), &payload); err != nil {
+					t.Fatalf("frame %q is not JSON: %v", frame.Data, err)
+				}
+				switch {
+				case payload.Event == "connected" || payload.Event == "complete":
+					got = append(got, payload.Event)
+				case payload.Event == "":
+					if payload.Time == "" || payload.Offset == nil {
+						t.Errorf("event frame %q is missing its time or offset", frame.Data)
+						continue
+					}
+					got = append(got, fmt.Sprintf("%s@%d %s", payload.Channel, *payload.Offset, payload.Message))
+				}
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("JSON frames %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompletionSummary(t *testing.T) {
+	tests := []struct {
+		name  string
+		slack bool
+		query string
+		until string // what marks the summary frame
+		want  summaryFrame
+	}{
+		// The team fixture covers 20 seconds of incident in a tenth of a
+		// second at testSpeed
+		{"json with Slack", true, "format=json", `"event":"summary"`, summaryFrame{Event: "summary", Channel: "team", Events: 3, WallSeconds: 0.1, AverageSpeed: testSpeed, Published: 3}},
+		{"json without Slack", false, "format=json", `"event":"summary"`, summaryFrame{Event: "summary", Channel: "team", Events: 3, WallSeconds: 0.1, AverageSpeed: testSpeed}},
+		{"text", true, "format=text", "📊 Summary", summaryFrame{Event: "summary", Channel: "team", Events: 3, WallSeconds: 0.1, AverageSpeed: testSpeed, Published: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			if tt.slack {
+				useFakeSlack(t)
+			}
+			srv := startServer(t, fixtureChannels("team"))
+			stream := openSSE(t, srv.URL+"/stream/team?oncomplete=close&"+tt.query)
+
+			stream.until(t, "Paging on-call")
+			clock.step(t)
+			stream.until(t, "Rolling back")
+			clock.step(t)
+			frames := stream.until(t, tt.until)
+			last := frames[len(frames)-1].Data
+
+			if tt.query == "format=text" {
+				if last != tt.want.String() {
+					t.Errorf("summary %q, want %q", last, tt.want.String())
+				}
+				return
+			}
+			var got summaryFrame
+			if err := json.Unmarshal([]byte(last), &got); err != nil {
+				t.Fatalf("summary %q: %v", last, err)
+			}
+			if got != tt.want {
+				t.Errorf("summary %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduledStart(t *testing.T) {
+	tests := []struct {
+		name    string
+		startIn time.Duration // from connecting until -start-at
+		banner  bool          // whether the viewer is told to wait
+		first   string        // first event the viewer sees
+	}{
+		{"early viewer waits", 30 * time.Second, true, "Paging on-call"},
+		// 50ms late at testSpeed is 10 seconds into the incident
+		{"late viewer joins mid-replay", -50 * time.Millisecond, false, "Rolling back"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			previous := replayStartAt
+			replayStartAt = clock.Now().Add(tt.startIn)
+			t.Cleanup(func() { replayStartAt = previous })
+			srv := startServer(t, fixtureChannels("team"))
+			stream := openSSE(t, srv.URL+"/stream/team")
+
+			var frames []sseFrame
+			if tt.banner {
+				want := "⏳ Replay starts at " + replayStartAt.In(timestampLocation).Format(time.RFC3339)
+				frames = stream.until(t, want)
+				clock.step(t)
+			}
+			frames = append(frames, stream.until(t, tt.first)...)
+			for _, line := range sseData(frames) {
+				if strings.Contains(line, "Paging on-call") && tt.first != "Paging on-call" {
+					t.Errorf("late viewer saw %q from before it joined", line)
+				}
+				if strings.Contains(line, "⏳") && !tt.banner {
+					t.Errorf("viewer after the start was told to wait: %q", line)
+				}
+			}
+
+			// The timeline is anchored to the scheduled start, not to whoever
+			// connected first
+			log := incidentReplay.emittedLog()
+			if len(log) == 0 {
+				t.Fatal("nothing emitted")
+			}
+			due := replayStartAt.Add(time.Duration(float64(log[0].Offset) / testSpeed * float64(time.Second)))
+			if !log[0].Time.Equal(due) {
+				t.Errorf("%q fired at %v, want %v", log[0].Message, log[0].Time, due)
+			}
+		})
+	}
+}
+
+func TestEmptyChannel(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		want   []string // in order, ending with completion
+		absent []string
+	}{
+		{"text", "/stream/zoom?oncomplete=close", []string{
+			"🔗 Connected to", "ℹ️ No events for channel 'zoom'", "✅ Incident replay completed",
+		}, []string{"Paging on-call", "CPU"}},
+		{"json", "/stream/zoom?oncomplete=close&format=json", []string{
+			`"event":"connected"`, `"event":"empty"`, `"event":"complete"`,
+		}, []string{"Paging on-call", "CPU"}},
+		{"channel with events", "/stream/team?oncomplete=close", []string{
+			"Paging on-call", "✅ Incident replay completed",
+		}, []string{"No events"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startServer(t, fixtureTranscript())
+			lines := sseData(readSSE(t, srv.URL+tt.path))
+			if !containsInOrder(lines, tt.want...) {
+				t.Errorf("stream lines %q, want in order %q", lines, tt.want)
+			}
+			for _, line := range lines {
+				for _, absent := range tt.absent {
+					if strings.Contains(line, absent) {
+						t.Errorf("stream carried %q", line)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOnComplete(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		closes     bool // whether the body ends once the replay completes
+		afterGrace bool // whether it only ends after completionGrace
+	}{
+		{"keep open by default", "", false, false},
+		{"keepopen", "?oncomplete=keepopen", false, false},
+		{"close", "?oncomplete=close", true, false},
+		{"grace", "?oncomplete=grace", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			srv := startServer(t, fixtureChannels("team"))
+			stream := openSSE(t, srv.URL+"/stream/team"+tt.query)
+
+			stream.until(t, "Paging on-call")
+			clock.step(t)
+			stream.until(t, "Rolling back")
+			clock.step(t)
+			stream.until(t, "✅ Incident replay completed")
+
+			if tt.afterGrace {
+				if stream.endsWithin(100 * time.Millisecond) {
+					t.Fatal("stream ended before the grace period")
+				}
+				clock.step(t)
+			}
+			if !tt.closes {
+				if stream.endsWithin(100 * time.Millisecond) {
+					t.Error("stream ended after completion")
+				}
+				return
+			}
+			if !stream.endsWithin(5 * time.Second) {
+				t.Error("stream still open after completion")
+			}
+		})
+	}
+
+	srv := startServer(t, fixtureTranscript())
+	if status, body := control(t, http.MethodGet, srv.URL+"/stream/team?oncomplete=never", ""); status != http.StatusBadRequest {
+		t.Errorf("oncomplete=never: status %d, want 400: %s", status, body)
+	}
+}
+
+// Connected clients per channel as /status reports them
+func statusClients(t *testing.T, url string) (int, map[string]int) {
+	t.Helper()
+	status, body := control(t, http.MethodGet, url+"/status", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET /status: status %d: %s", status, body)
+	}
+	var got replayStatus
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatalf("decode %q: %v", body, err)
+	}
+	clients := make(map[string]int)
+	for channel, cs := range got.Channels {
+		if cs.Clients > 0 {
+			clients[channel] = cs.Clients
+		}
+	}
+	return got.Clients, clients
+}
+
+func TestClientCount(t *testing.T) {
+	srv := startServer(t, fixtureTranscript())
+	streams := map[string]*sseStream{}
+
+	steps := []struct {
+		name    string
+		open    []string // streams to open, by name
+		close   []string // streams to hang up, by name
+		total   int
+		clients map[string]int
+	}{
+		{"nobody watching", nil, nil, 0, map[string]int{}},
+		{"two team viewers", []string{"team1", "team2"}, nil, 2, map[string]int{"team": 2}},
+		{"metrics viewer", []string{"metrics"}, nil, 3, map[string]int{"team": 2, "metrics": 1}},
+		{"team viewer leaves", nil, []string{"team1"}, 2, map[string]int{"team": 1, "metrics": 1}},
+		{"everyone leaves", nil, []string{"team2", "metrics"}, 0, map[string]int{}},
+	}
+	paths := map[string]string{"team1": "/stream/team", "team2": "/stream/team", "metrics": "/stream/incidents"}
+	for _, step := range steps {
+		for _, name := range step.open {
+			streams[name] = openSSE(t, srv.URL+paths[name])
+			streams[name].until(t, "🔗 Connected to")
+		}
+		for _, name := range step.close {
+			streams[name].close()
+		}
+
+		// Handlers notice a hang-up on their next write or context check,
+		// so give the count a moment to settle
+		var total int
+		var clients map[string]int
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			total, clients = statusClients(t, srv.URL)
+			if total == step.total && reflect.DeepEqual(clients, step.clients) {
+				break
+			}
+		}
+		if total != step.total || !reflect.DeepEqual(clients, step.clients) {
+			t.Errorf("%s: %d clients %v, want %d %v", step.name, total, clients, step.total, step.clients)
+		}
+	}
+}
+
+func TestMaxStreamDuration(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit time.Duration
+		query string
+		last  string // final frame once the limit ends the stream, empty if it stays open
+	}{
+		{"text", 50 * time.Millisecond, "", "⏲ Stream time limit reached"},
+		{"json", 50 * time.Millisecond, "?format=json", `{"event":"timeout","channel":"team"}`},
+		{"no limit", 0, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := maxStreamDuration
+			maxStreamDuration = tt.limit
+			t.Cleanup(func() { maxStreamDuration = previous })
+			srv := startServer(t, fixtureTranscript())
+
+			if tt.last == "" {
+				stream := openSSE(t, srv.URL+"/stream/team"+tt.query)
+				stream.until(t, "✅ Incident replay completed")
+				if stream.endsWithin(200 * time.Millisecond) {
+					t.Error("stream without a limit ended on its own")
+				}
+				return
+			}
+			frames := readSSE(t, srv.URL+"/stream/team"+tt.query)
+			if len(frames) == 0 || frames[len(frames)-1].Data != tt.last {
+				t.Errorf("stream frames %q, want the last to be %q", sseData(frames), tt.last)
+			}
+		})
+	}
+}
+
+// Fixture with a burst of metrics firing together at T+5, the kind of dense
+// stretch -batch-interval is for
+func burstTranscript(n int) (*IncidentTranscript, []string) {
+	tr := fixtureTranscript()
+	var burst []string
+	for i := range n {
+		message := fmt.Sprintf("rps=%d", i)
+		tr.Events = append(tr.Events, Event{TimeOffset: 5, Channel: "metrics", Message: message})
+		burst = append(burst, message)
+	}
+	sort.SliceStable(tr.Events, func(i, j int) bool { return tr.Events[i].TimeOffset < tr.Events[j].TimeOffset })
+	return tr, burst
+}
+
+func TestBatchedStreamOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"flush every event", 0},
+		{"short batches", 20 * time.Millisecond},
+		// Longer than the whole replay: completion still flushes at once
+		{"batch longer than the replay", time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := batchInterval
+			batchInterval = tt.interval
+			t.Cleanup(func() { batchInterval = previous })
+			tr, burst := burstTranscript(50)
+			srv := startServer(t, tr)
+
+			start := time.Now()
+			lines := sseData(readSSE(t, srv.URL+"/stream?channels=team,metrics&oncomplete=close"))
+			want := append([]string{"Paging on-call", "CPU 92%", "CPU 99%"}, burst...)
+			want = append(want, "Rolling back", "Resolved", "✅ Incident replay completed")
+			if !containsInOrder(lines, want...) {
+				t.Errorf("stream lines %q, want in order %q", lines, want)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second/2+tt.interval {
+				t.Errorf("stream took %v, batching held events back", elapsed)
+			}
+		})
+	}
+}
+
+// Response recorder counting flushes
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushCounter) Flush() {
+	f.flushes++
+	f.ResponseRecorder.Flush()
+}
+
+// Flushes per replay of a burst of 50 events at 10x, with and without
+// batching
+func BenchmarkStreamFlushes(b *testing.B) {
+	tr, _ := burstTranscript(50)
+	tr.Events = channelEvents(tr, "metrics")
+	for _, interval := range []time.Duration{0, 50 * time.Millisecond} {
+		b.Run(fmt.Sprintf("batch %v", interval), func(b *testing.B) {
+			previous := batchInterval
+			batchInterval = interval
+			b.Cleanup(func() { batchInterval = previous })
+
+			flushes := 0
+			for range b.N {
+				inc := &incident{Transcript: tr, Replay: newReplay(tr.Events, newSpeedControl(10), false)}
+				w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+				r := httptest.NewRequest(http.MethodGet, "/stream/metrics?oncomplete=close", nil)
+				streamChannel(w, r, inc, "metrics", "Metrics")
+				inc.Replay.wait()
+				flushes += w.flushes
+			}
+			b.ReportMetric(float64(flushes)/float64(b.N), "flushes/op")
+		})
+	}
+}
+
+func TestWaitUntil(t *testing.T) {
+	tests := []struct {
+		name   string
+		after  time.Duration // target, from now on the fake clock
+		cancel bool
+		want   error
+	}{
+		{"target passed", -time.Second, false, nil},
+		{"target reached", time.Second, false, nil},
+		{"canceled first", time.Second, true, errWaitCanceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() { done <- waitUntil(ctx, clock.Now().Add(tt.after)) }()
+			if tt.after > 0 {
+				if tt.cancel {
+					<-clock.set
+					cancel()
+				} else {
+					clock.step(t)
+				}
+			}
+			select {
+			case err := <-done:
+				if err != tt.want {
+					t.Errorf("waitUntil returned %v, want %v", err, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("waitUntil still blocked")
+			}
+			// Every path stops its timer
+			if pending := clock.Pending(); pending != 0 {
+				t.Errorf("%d timers left on the clock", pending)
+			}
+		})
+	}
+}
+
+func TestStreamsLeaveNoGoroutines(t *testing.T) {
+	tr := fixtureTranscript()
+	tr.Events = nil
+	for i := range 50 {
+		tr.Events = append(tr.Events, Event{TimeOffset: i, Channel: "team", Message: fmt.Sprintf("step %d", i)})
+	}
+	tr.Incident.DurationSeconds = 49
+	srv := startServer(t, tr)
+
+	// Private timelines each run their own timers, so they're the ones that
+	// could leak; half the viewers hang up midway
+	round := func() {
+		var streams []*sseStream
+		for i := range 20 {
+			streams = append(streams, openSSE(t, fmt.Sprintf("%s/stream/team?client=viewer%d&oncomplete=close", srv.URL, i)))
+		}
+		for i, s := range streams {
+			if i%2 == 0 {
+				s.until(t, "✅ Incident replay completed")
+				if !s.endsWithin(5 * time.Second) {
+					t.Fatalf("stream %s still open", s.url)
+				}
+				continue
+			}
+			s.until(t, "step 10")
+			s.close()
+		}
+		http.DefaultClient.CloseIdleConnections()
+	}
+	// Goroutines once the count stops falling, or by the deadline no more
+	// than limit
+	settle := func(limit int) int {
+		deadline := time.Now().Add(5 * time.Second)
+		previous := runtime.NumGoroutine()
+		for {
+			time.Sleep(50 * time.Millisecond)
+			n := runtime.NumGoroutine()
+			if n <= limit || (limit < 0 && n >= previous) || time.Now().After(deadline) {
+				return n
+			}
+			previous = n
+		}
+	}
+
+	round()
+	baseline := settle(-1)
+	for range 3 {
+		round()
+	}
+	if n := settle(baseline); n > baseline {
+		t.Errorf("%d goroutines after 4 rounds of streams, %d after the first", n, baseline)
+	}
+}
+
+// Open and maximum stream connections as /status reports them
+func statusClientLimit(t *testing.T, url string) clientLimitStatus {
+	t.Helper()
+	status, body := control(t, http.MethodGet, url+"/status", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET /status: status %d: %s", status, body)
+	}
+	var got replayStatus
+	if err := json.Unmarshal([]byte(body), &got); err != nil {
+		t.Fatalf("decode %q: %v", body, err)
+	}
+	if got.ClientLimit == nil {
+		t.Fatal("status has no client_limit")
+	}
+	return *got.ClientLimit
+}
+
+func TestMaxClients(t *testing.T) {
+	previous := streamSlots
+	streamSlots = make(chan struct{}, 2)
+	t.Cleanup(func() { streamSlots = previous })
+	srv := startServer(t, fixtureTranscript())
+	streams := map[string]*sseStream{}
+
+	// Each step then probes with a malformed stream request: one that gets
+	// a slot answers 400 and gives it straight back, and one past the limit
+	// is turned away first
+	steps := []struct {
+		name  string
+		act   func()
+		open  int
+		probe int
+	}{
+		{"nobody watching", func() {}, 0, http.StatusBadRequest},
+		{"one viewer", func() { streams["team"] = openSSE(t, srv.URL+"/stream/team") }, 1, http.StatusBadRequest},
+		{"at the limit", func() { streams["combined"] = openSSE(t, srv.URL+"/stream?channels=team,metrics") }, 2, http.StatusServiceUnavailable},
+		{"a viewer leaves", func() { streams["team"].close() }, 1, http.StatusBadRequest},
+	}
+	for _, step := range steps {
+		step.act()
+
+		// Hang-ups release their slot once the handler notices
+		var limit clientLimitStatus
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if limit = statusClientLimit(t, srv.URL); limit.Open == step.open {
+				break
+			}
+		}
+		if limit != (clientLimitStatus{Open: step.open, Max: 2}) {
+			t.Errorf("%s: client limit %+v, want %d of 2 open", step.name, limit, step.open)
+		}
+
+		resp, err := http.Get(srv.URL + "/stream/incidents?client=%20")
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != step.probe {
+			t.Errorf("%s: next connection got %d, want %d", step.name, resp.StatusCode, step.probe)
+		}
+		if retry := resp.Header.Get("Retry-After"); (retry == "5") != (step.probe == http.StatusServiceUnavailable) {
+			t.Errorf("%s: Retry-After %q", step.name, retry)
+		}
+		if got := statusClientLimit(t, srv.URL).Open; got != step.open {
+			t.Errorf("%s: %d slots taken after the probe, want %d", step.name, got, step.open)
+		}
+	}
+}
+
+func TestCatchup(t *testing.T) {
+	tests := []struct {
+		name    string
+		past    int // events fired before the latecomer joins
+		catchup bool
+		skipped int // oldest past events left out of the catch-up
+	}{
+		{"live only", 3, false, 0},
+		{"whole backlog", 3, true, 0},
+		{"backlog over the limit", catchupLimit + 20, true, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			tr := fixtureChannels("team")
+			tr.Events = nil
+			for i := range tt.past {
+				tr.Events = append(tr.Events, Event{TimeOffset: i, Channel: "team", Message: fmt.Sprintf("past %d", i)})
+			}
+			tr.Events = append(tr.Events, Event{TimeOffset: tt.past + 10, Channel: "team", Message: "live"})
+			srv := startServer(t, tr)
+
+			// The first viewer starts the replay and watches the past go by,
+			// keeping up so it isn't dropped as a slow subscriber
+			first := openSSE(t, srv.URL+"/stream/team")
+			first.until(t, "past 0")
+			for i := 1; i < tt.past; i++ {
+				clock.step(t)
+				first.until(t, fmt.Sprintf("past %d", i))
+			}
+
+			url := srv.URL + "/stream/team"
+			if tt.catchup {
+				url += "?catchup=true"
+			}
+			late := openSSE(t, url)
+			late.until(t, "📋 Incident:")
+			clock.step(t)
+			lines := sseData(late.until(t, "live"))
+
+			// Past events arrive at once, oldest first, ahead of the live one
+			var want []string
+			if tt.skipped > 0 {
+				want = append(want, fmt.Sprintf("⏪ Catching up: %d earlier events not shown", tt.skipped))
+			}
+			if tt.catchup {
+				for i := tt.skipped; i < tt.past; i++ {
+					want = append(want, fmt.Sprintf("past %d", i))
+				}
+			}
+			want = append(want, "live")
+			if len(lines) != len(want) {
+				t.Fatalf("latecomer got %d lines %q, want %d", len(lines), lines, len(want))
+			}
+			for i, line := range lines {
+				if !strings.HasSuffix(line, want[i]) {
+					t.Errorf("line %d = %q, want it to end with %q", i, line, want[i])
+				}
+				if backfill := strings.HasPrefix(line, "[catch-up] "); backfill != strings.HasPrefix(want[i], "past") {
+					t.Errorf("line %d = %q, marked as catch-up: %v", i, line, backfill)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamWriteTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		reading bool
+		clients int // still connected once the replay has been written out
+	}{
+		{"client keeps reading", true, 1},
+		{"client stopped reading", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := streamWriteTimeout
+			streamWriteTimeout = 100 * time.Millisecond
+			t.Cleanup(func() { streamWriteTimeout = previous })
+
+			// Far more than the socket buffers hold, but few enough events
+			// that the subscriber buffer never overflows
+			tr := fixtureChannels("team")
+			tr.Events = nil
+			for i := range 32 {
+				tr.Events = append(tr.Events, Event{TimeOffset: 0, Channel: "team", Message: fmt.Sprintf("%d %s", i, strings.Repeat("x", 1<<20))})
+			}
+			srv := startServer(t, tr)
+
+			conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			t.Cleanup(func() { conn.Close() })
+			fmt.Fprintf(conn, "GET /stream/team HTTP/1.1\r\nHost: %s\r\n\r\n", srv.Listener.Addr())
+
+			if tt.reading {
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						t.Fatalf("stream ended before the replay completed: %v", err)
+					}
+					if strings.Contains(line, "✅ Incident replay completed") {
+						break
+					}
+				}
+			}
+
+			// A wedged client is dropped once a write blocks past the timeout
+			var clients int
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+				if clients, _ = statusClients(t, srv.URL); clients == tt.clients {
+					break
+				}
+			}
+			if clients != tt.clients {
+				t.Errorf("%d clients connected, want %d", clients, tt.clients)
+			}
+		})
+	}
+}
+
+func TestMultiLineMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string // the message as the stream reconstructs it
+	}{
+		{"single line", "Paging on-call", "Paging on-call"},
+		{"stack trace", "panic: nil map\ngoroutine 1 [running]:\nmain.main()", "panic: nil map\ngoroutine 1 [running]:\nmain.main()"},
+		{"CRLF line breaks", "first\r\nsecond", "first\nsecond"},
+		{"bare CR line breaks", "first\rsecond", "first\nsecond"},
+		{"blank line inside", "before\n\nafter", "before\n\nafter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := fixtureChannels("team")
+			tr.Events = []Event{
+				{TimeOffset: 0, Channel: "team", Message: tt.message},
+				{TimeOffset: 1, Channel: "team", Message: "Rolling back"},
+			}
+			// The message comes back whole and the event after it is intact,
+			// in either stream format
+			var text []string
+			for _, line := range sseData(readSSE(t, startServer(t, tr).URL+"/stream/team?oncomplete=close")) {
+				if strings.HasPrefix(line, "[") {
+					_, message, _ := strings.Cut(line, "] ")
+					text = append(text, message)
+				}
+			}
+			if want := []string{tt.want, "Rolling back"}; !reflect.DeepEqual(text, want) {
+				t.Errorf("text stream events %q, want %q", text, want)
+			}
+
+			// JSON escapes line breaks, so the message arrives exactly as written
+			var messages []string
+			for _, line := range sseData(readSSE(t, startServer(t, tr).URL+"/stream/team?format=json&oncomplete=close")) {
+				var frame eventFrame
+				if err := json.Unmarshal([]byte(line), &frame); err == nil && frame.Message != "" {
+					messages = append(messages, frame.Message)
+				}
+			}
+			if want := []string{tt.message, "Rolling back"}; !reflect.DeepEqual(messages, want) {
+				t.Errorf("JSON stream events %q, want %q", messages, want)
+			}
+		})
+	}
+}
+
+func TestDescribeQuietSkip(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    string
+	}{
+		{1, "⏩ (skipped 1 second of quiet)"},
+		{45, "⏩ (skipped 45 seconds of quiet)"},
+		{60, "⏩ (skipped 1 minute of quiet)"},
+		{598, "⏩ (skipped 10 minutes of quiet)"},
+		{89, "⏩ (skipped 1 minute of quiet)"},
+		{90, "⏩ (skipped 2 minutes of quiet)"},
+	}
+	for _, tt := range tests {
+		if got := describeQuietSkip(tt.seconds); got != tt.want {
+			t.Errorf("describeQuietSkip(%d) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestCombinedStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		events []string // event lines as "[CHANNEL] message", in stream order
+	}{
+		{"team and metrics", "?channels=team,metrics", http.StatusOK, []string{
+			"[TEAM] Paging on-call", "[METRICS] CPU 92%", "[METRICS] CPU 99%", "[TEAM] Rolling back", "[TEAM] Resolved",
+		}},
+		{"listed order doesn't matter", "?channels=metrics,team", http.StatusOK, []string{
+			"[TEAM] Paging on-call", "[METRICS] CPU 92%", "[METRICS] CPU 99%", "[TEAM] Rolling back", "[TEAM] Resolved",
+		}},
+		{"repeats and spaces ignored", "?channels=metrics,+team,metrics,", http.StatusOK, []string{
+			"[TEAM] Paging on-call", "[METRICS] CPU 92%", "[METRICS] CPU 99%", "[TEAM] Rolling back", "[TEAM] Resolved",
+		}},
+		{"reverse", "?channels=team,metrics&direction=reverse", http.StatusOK, []string{
+			"[TEAM] Resolved", "[TEAM] Rolling back", "[METRICS] CPU 99%", "[METRICS] CPU 92%", "[TEAM] Paging on-call",
+		}},
+		{"unknown channel", "?channels=team,billing", http.StatusBadRequest, nil},
+		{"no channels", "?channels=,", http.StatusBadRequest, nil},
+		{"missing parameter", "", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startServer(t, fixtureTranscript())
+			url := srv.URL + "/stream" + tt.query
+			if tt.status != http.StatusOK {
+				status, body := control(t, http.MethodGet, url, "")
+				if status != tt.status {
+					t.Errorf("GET %s: status %d, want %d: %s", url, status, tt.status, body)
+				}
+				return
+			}
+
+			// Events interleave by offset, and the stream completes once
+			var events []string
+			completions, summaries := 0, 0
+			for _, line := range sseData(readSSE(t, url+"&oncomplete=close")) {
+				switch {
+				case strings.HasPrefix(line, "✅ Incident replay completed"):
+					completions++
+				case strings.HasPrefix(line, "📊 Summary"):
+					summaries++
+				case strings.HasPrefix(line, "[TEAM] ") || strings.HasPrefix(line, "[METRICS] "):
+					channel, rest, _ := strings.Cut(line, " ")
+					_, message, _ := strings.Cut(rest, "] ")
+					events = append(events, channel+" "+message)
+				}
+			}
+			if !reflect.DeepEqual(events, tt.events) {
+				t.Errorf("events %q, want %q", events, tt.events)
+			}
+			if completions != 1 || summaries != 1 {
+				t.Errorf("%d completions and %d summaries, want one of each", completions, summaries)
+			}
+		})
+	}
+}
+
+func TestStreamIntro(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		description string
+		status      int
+		want        []string // data between the incident banner and the first event
+	}{
+		{"no intro by default", "", "Payments failing in us-east-1", http.StatusOK, nil},
+		{"text", "?intro=full", "Payments failing in us-east-1", http.StatusOK, []string{
+			"📝 Payments failing in us-east-1", "⏱ Duration: 00:00:20",
+		}},
+		{"text without a description", "?intro=full", "", http.StatusOK, []string{"⏱ Duration: 00:00:20"}},
+		{"json", "?intro=full&format=json", "Payments failing in us-east-1", http.StatusOK, []string{
+			`{"event":"intro","title":"Checkout outage","description":"Payments failing in us-east-1","duration_seconds":20}`,
+		}},
+		{"unknown intro", "?intro=short", "", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := fixtureChannels("team")
+			tr.Incident.Description = tt.description
+			srv := startServer(t, tr)
+			url := srv.URL + "/stream/team" + tt.query
+			if tt.status != http.StatusOK {
+				if status, body := control(t, http.MethodGet, url, ""); status != tt.status {
+					t.Errorf("GET %s: status %d, want %d: %s", url, status, tt.status, body)
+				}
+				return
+			}
+
+			sep := "?"
+			if tt.query != "" {
+				sep = "&"
+			}
+			var got []string
+			seenTitle := false
+			for _, frame := range readSSE(t, url+sep+"oncomplete=close") {
+				if strings.Contains(frame.Data, "Paging on-call") {
+					break
+				}
+				// Progress frames follow the clock, not the connection
+				if strings.Contains(frame.Data, `"event":"progress"`) {
+					continue
+				}
+				if seenTitle {
+					got = append(got, frame.Data)
+				}
+				seenTitle = seenTitle || strings.Contains(frame.Data, "Incident: Checkout outage") || strings.Contains(frame.Data, `"event":"incident"`)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("after the incident banner %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgressTracker(t *testing.T) {
+	// A 20s incident with the default 5s interval
+	tests := []struct {
+		name    string
+		offsets []float64 // timeline positions seen on successive polls
+		sent    []int     // offsets of the frames sent
+	}{
+		{"first poll always sends", []float64{0}, []int{0}},
+		{"one frame per interval", []float64{0, 1, 4.9, 5, 7, 10.2}, []int{0, 5, 10}},
+		{"skipped intervals send once", []float64{0, 16}, []int{0, 16}},
+		{"standing still sends nothing", []float64{5, 5, 5}, []int{5}},
+		{"one more at 100%", []float64{15, 19.9, 20, 20}, []int{15, 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progress := &progressTracker{mark: -1}
+			sent := []int{}
+			for _, offset := range tt.offsets {
+				if frame, ok := progress.next(offset, offset/20*100, 20); ok {
+					if frame.Event != "progress" || frame.Duration != 20 {
+						t.Errorf("frame %+v, want a progress frame for a 20s incident", frame)
+					}
+					sent = append(sent, frame.Offset)
+				}
+			}
+			if !reflect.DeepEqual(sent, tt.sent) {
+				t.Errorf("frames at %v, want %v", sent, tt.sent)
+			}
+		})
+	}
+}
+
+func TestProgressFrames(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		frames bool
+	}{
+		{"json stream", "json", true},
+		{"text stream", "text", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			// Long enough at testSpeed to span several progress polls
+			srv := startServer(t, &IncidentTranscript{
+				Incident: IncidentInfo{Title: "Checkout outage", DurationSeconds: 300},
+				Events: []Event{
+					{TimeOffset: 0, Channel: "team", Message: "Paging on-call"},
+					{TimeOffset: 150, Channel: "team", Message: "Rolling back"},
+					{TimeOffset: 300, Channel: "team", Message: "Resolved"},
+				},
+			})
+			stream := openSSE(t, srv.URL+"/stream/team?format="+tt.format)
+
+			// Keep the fake clock moving, replay timers and progress polls
+			// alike, including a poll set before the replay's last timer
+			done := make(chan struct{})
+			t.Cleanup(func() { close(done) })
+			go func() {
+				for {
+					select {
+					case <-clock.set:
+					case <-time.After(10 * time.Millisecond):
+					case <-done:
+						return
+					}
+					clock.AdvanceToNext()
+				}
+			}()
+
+			if !tt.frames {
+				for _, frame := range stream.until(t, "Incident replay completed") {
+					if strings.Contains(frame.Data, `"event":"progress"`) {
+						t.Errorf("text stream sent %q", frame.Data)
+					}
+				}
+				return
+			}
+
+			var got []progressFrame
+			for len(got) == 0 || got[len(got)-1].Percent < 100 {
+				frame, ok := stream.next(t)
+				if !ok {
+					t.Fatalf("stream ended after progress %+v", got)
+				}
+				if !strings.Contains(frame.Data, `"event":"progress"`) {
+					continue
+				}
+				var progress progressFrame
+				if err := json.Unmarshal([]byte(frame.Data), &progress); err != nil {
+					t.Fatalf("decode %q: %v", frame.Data, err)
+				}
+				got = append(got, progress)
+			}
+
+			if len(got) < 3 || got[0].Percent != 0 {
+				t.Errorf("progress %+v, want it to start at 0%% and step toward 100%%", got)
+			}
+			for i, frame := range got {
+				if frame.Duration != 300 {
+					t.Errorf("frame %d duration %d, want 300", i, frame.Duration)
+				}
+				if i > 0 && (frame.Percent <= got[i-1].Percent || frame.Offset < got[i-1].Offset) {
+					t.Errorf("frame %d %+v doesn't move on from %+v", i, frame, got[i-1])
+				}
+			}
+		})
+	}
+}
+
+func TestCompletionGrace(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		summary   string // text identifying the summary frame
+		summaries int    // summaries sent before the stream ends
+		closes    bool   // the stream ends by itself
+	}{
+		{"close at once", "?oncomplete=close", "📊 Summary", 1, true},
+		{"grace", "?oncomplete=grace", "📊 Summary", 2, true},
+		{"grace in JSON", "?oncomplete=grace&format=json", `"event":"summary"`, 2, true},
+		{"keep open", "", "📊 Summary", 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useFakeClock(t)
+			// Only the grace timer may wait on the clock once the replay completes
+			previous := progressInterval
+			progressInterval = 0
+			t.Cleanup(func() { progressInterval = previous })
+			srv := startServer(t, fixtureChannels("team"))
+			stream := openSSE(t, srv.URL+"/stream/team"+tt.query)
+			stream.until(t, "Paging on-call")
+			clock.step(t)
+			stream.until(t, "Rolling back")
+			clock.step(t)
+			stream.until(t, tt.summary)
+
+			if tt.summaries == 2 {
+				// Held open, with nothing more sent, until the grace runs out
+				for clock.Pending() == 0 {
+					<-clock.set
+				}
+				clock.Advance(completionGrace - time.Millisecond)
+				stream.quietFor(t, 100*time.Millisecond)
+				clock.Advance(time.Millisecond)
+				stream.until(t, tt.summary)
+			} else {
+				clock.Advance(2 * completionGrace)
+			}
+
+			if closed := stream.endsWithin(time.Second); closed != tt.closes {
+				t.Errorf("stream closed %v after completing, want %v", closed, tt.closes)
+			}
+		})
+	}
+}
+
+func TestChannelComplete(t *testing.T) {
+	// Metrics ends at T+3, team at T+20
+	tests := []struct {
+		name    string
+		path    string
+		want    []string // in order, each frame holding the next
+		markers int      // per-channel markers among the frames
+		signals []string // lifecycle system events, with ?lifecycle=true
+	}{
+		{"text", "/stream?channels=team,metrics", []string{
+			"CPU 99%", "✅ Channel 'metrics' complete", "Rolling back", "Resolved",
+			"✅ Channel 'team' complete", "✅ Incident replay completed",
+		}, 2, nil},
+		{"json", "/stream?channels=team,metrics&format=json", []string{
+			"CPU 99%", `{"event":"channel_complete","channel":"metrics"}`, "Rolling back", "Resolved",
+			`{"event":"channel_complete","channel":"team"}`, `{"event":"complete"`,
+		}, 2, nil},
+		{"lifecycle", "/stream?channels=team,metrics&lifecycle=true", []string{
+			"✅ Channel 'metrics' complete", "✅ Channel 'team' complete", "✅ Incident replay completed",
+		}, 2, []string{"start:team,metrics", "channel_complete:metrics", "channel_complete:team", "complete:team,metrics"}},
+		{"single channel", "/stream/team", []string{"Resolved", "✅ Incident replay completed"}, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startServer(t, fixtureTranscript())
+			sep := "?"
+			if strings.Contains(tt.path, "?") {
+				sep = "&"
+			}
+			frames := readSSE(t, srv.URL+tt.path+sep+"oncomplete=close")
+
+			var lines []string
+			markers := 0
+			for _, frame := range frames {
+				if frame.Event != "" || strings.Contains(frame.Data, `"event":"progress"`) || strings.Contains(frame.Data, `"event":"summary"`) || strings.Contains(frame.Data, "📊 Summary") {
+					continue
+				}
+				lines = append(lines, frame.Data)
+				if strings.Contains(frame.Data, "Channel '") || strings.Contains(frame.Data, `"channel_complete"`) {
+					markers++
+				}
+			}
+			if !containsInOrder(lines, tt.want...) {
+				t.Errorf("frames %q, want %q in order", lines, tt.want)
+			}
+			if markers != tt.markers {
+				t.Errorf("%d channel markers, want %d", markers, tt.markers)
+			}
+			// The overall completion comes after every channel's marker
+			if last := lines[len(lines)-1]; !strings.Contains(last, tt.want[len(tt.want)-1]) {
+				t.Errorf("last frame %q, want %q", last, tt.want[len(tt.want)-1])
+			}
+			if tt.signals != nil {
+				if got := systemEvents(t, frames); !reflect.DeepEqual(got, tt.signals) {
+					t.Errorf("system events %q, want %q", got, tt.signals)
+				}
+			}
+		})
+	}
+}
+
+func TestChannelCompleteLateJoin(t *testing.T) {
+	clock := useFakeClock(t)
+	srv := startServer(t, fixtureTranscript())
+	first := openSSE(t, srv.URL+"/stream?channels=team,metrics")
+	first.until(t, "Paging on-call")
+	clock.step(t)
+	first.until(t, "CPU 92%")
+	clock.step(t)
+	first.until(t, "✅ Channel 'metrics' complete")
+
+	// Metrics already finished, so a client joining now hears so at once
+	late := openSSE(t, srv.URL+"/stream?channels=team,metrics")
+	late.until(t, "✅ Channel 'metrics' complete")
+	clock.step(t)
+	frames := late.until(t, "Rolling back")
+	for _, frame := range frames {
+		if strings.Contains(frame.Data, "Channel 'team'") {
+			t.Errorf("team marked complete before its last event: %q", frame.Data)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Time allowed for the model to summarize an incident
+const summaryTimeout = 90 * time.Second
+
+// Channels whose events are sent to the model for a summary
+var summaryChannels = map[string]bool{"team": true, "metrics": true}
+
+// Instructions for the summary model
+const summaryPrompt = `You are an experienced incident commander reviewing a production incident.
+Reply with a JSON object with exactly these fields:
+  "root_cause": a short statement of the most likely root cause,
+  "timeline": an array of {"offset": "T+HH:MM:SS", "description": "..."} for the key moments, in order,
+  "next_actions": an array of short suggested follow-up actions.`
+
+// A key moment in the incident timeline
+type summaryMoment struct {
+	Offset      string `json:"offset"`
+	Description string `json:"description"`
+}
+
+// Structured AI summary of an incident
+type incidentSummary struct {
+	RootCause   string          `json:"root_cause"`
+	Timeline    []summaryMoment `json:"timeline"`
+	NextActions []string        `json:"next_actions"`
+}
+
+// LLM client shared by the AI features; disabled when no API key is set
+var llmClient *LLMClient
+
+// Summaries already generated, keyed by transcript content so repeated
+// requests don't re-bill. Held across generation so concurrent requests
+// for the same transcript wait for one call instead of each making their own.
+var (
+	summaryMutex sync.Mutex
+	summaryCache = make(map[string]*incidentSummary)
+)
+
+// Build the model input: incident details and the ordered team and metrics events
+func buildSummaryInput(t *IncidentTranscript) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Incident: %s\n", t.Incident.Title)
+	fmt.Fprintf(&b, "Description: %s\n\nEvents:\n", t.Incident.Description)
+	for _, event := range t.Events {
+		if summaryChannels[event.Channel] {
+			fmt.Fprintf(&b, "%s [%s] %s\n", formatOffset(event.TimeOffset), event.Channel, redactor.event(event).displayText())
+		}
+	}
+	return b.String()
+}
+
+// Summarize the transcript with the LLM, reusing an earlier result for the same transcript
+func summarizeIncident(ctx context.Context, t *IncidentTranscript) (*incidentSummary, error) {
+	input := buildSummaryInput(t)
+	digest := sha256.Sum256([]byte(input))
+	key := hex.EncodeToString(digest[:])
+
+	summaryMutex.Lock()
+	defer summaryMutex.Unlock()
+	if summary, ok := summaryCache[key]; ok {
+		return summary, nil
+	}
+
+	slog.Info("🧠 Generating AI incident summary", "model", llmClient.Model)
+	reply, err := llmClient.Complete(ctx, summaryPrompt, input, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var summary incidentSummary
+	if err := json.Unmarshal([]byte(reply), &summary); err != nil {
+		return nil, fmt.Errorf("failed to parse model summary: %w", err)
+	}
+	summaryCache[key] = &summary
+	return &summary, nil
+}
+
+// Handler for the AI incident summary
+func summaryHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !llmClient.Enabled() {
+		http.Error(w, "AI summary not configured: set LLM_API_KEY", http.StatusNotImplemented)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), summaryTimeout)
+	defer cancel()
+	summary, err := summarizeIncident(ctx, primaryIncident().Transcript)
+	if err != nil {
+		slog.Warn("⚠️  Failed to generate AI summary", "err", err)
+		http.Error(w, "Failed to generate summary", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
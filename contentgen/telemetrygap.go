@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Channel that goes dark during a telemetry gap
+const telemetryChannel = "metrics"
+
+// Stretch of incident time with no metrics, to train responders on missing
+// observability; nil unless -telemetry-gap is set
+var telemetryGap *gapWindow
+
+// Window of virtual incident seconds, from Start up to but not including End
+type gapWindow struct {
+	Start int
+	End   int
+}
+
+// Parse a telemetry gap window written as START-END in seconds, e.g. 300-600
+func parseGapWindow(value string) (*gapWindow, error) {
+	startText, endText, ok := strings.Cut(value, "-")
+	if !ok {
+		return nil, fmt.Errorf("telemetry gap %q must be START-END in seconds, e.g. 300-600", value)
+	}
+	start, err := strconv.Atoi(strings.TrimSpace(startText))
+	if err != nil {
+		return nil, fmt.Errorf("invalid telemetry gap start %q: %w", startText, err)
+	}
+	end, err := strconv.Atoi(strings.TrimSpace(endText))
+	if err != nil {
+		return nil, fmt.Errorf("invalid telemetry gap end %q: %w", endText, err)
+	}
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("telemetry gap %d-%d must start at 0 or later and end after it starts", start, end)
+	}
+	return &gapWindow{Start: start, End: end}, nil
+}
+
+// Report whether the gap withholds an event from streams. Only the metrics
+// channel is affected; team and zoom carry on as normal.
+func (g *gapWindow) hides(event Event) bool {
+	return g != nil && event.Channel == telemetryChannel && event.TimeOffset >= g.Start && event.TimeOffset < g.End
+}
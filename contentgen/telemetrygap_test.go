@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGapWindow(t *testing.T) {
+	tests := []struct {
+		value string
+		want  *gapWindow
+		err   string
+	}{
+		{"300-600", &gapWindow{Start: 300, End: 600}, ""},
+		{" 0 - 5 ", &gapWindow{Start: 0, End: 5}, ""},
+		{"300", nil, "must be START-END"},
+		{"a-600", nil, `invalid telemetry gap start "a"`},
+		{"300-b", nil, `invalid telemetry gap end "b"`},
+		{"600-300", nil, "end after it starts"},
+		{"5-5", nil, "end after it starts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseGapWindow(tt.value)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to mention %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.value, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("window %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTelemetryGap(t *testing.T) {
+	tr := fixtureChannels("team")
+	tr.Events = []Event{
+		{TimeOffset: 0, Channel: "team", Message: "Paging on-call"},
+		{TimeOffset: 1, Channel: "metrics", Message: "CPU at T+1"},
+		{TimeOffset: 3, Channel: "metrics", Message: "CPU at T+3"},
+		{TimeOffset: 4, Channel: "team", Message: "Dashboards are blank"},
+		{TimeOffset: 5, Channel: "metrics", Message: "CPU at T+5"},
+		{TimeOffset: 7, Channel: "metrics", Message: "CPU at T+7"},
+		{TimeOffset: 9, Channel: "metrics", Message: "CPU at T+9"},
+	}
+
+	tests := []struct {
+		name string
+		gap  *gapWindow
+		want []string // event and gap lines in the order the stream shows them
+	}{
+		{"no gap", nil, []string{
+			"Paging on-call", "CPU at T+1", "CPU at T+3", "Dashboards are blank", "CPU at T+5", "CPU at T+7", "CPU at T+9",
+		}},
+		// Team events carry on while metrics are dark
+		{"gap mid-incident", &gapWindow{Start: 2, End: 6}, []string{
+			"Paging on-call", "CPU at T+1", "📉 Telemetry gap", "Dashboards are blank", "📈 Telemetry restored", "CPU at T+7", "CPU at T+9",
+		}},
+		{"end is exclusive", &gapWindow{Start: 3, End: 7}, []string{
+			"Paging on-call", "CPU at T+1", "📉 Telemetry gap", "Dashboards are blank", "📈 Telemetry restored", "CPU at T+7", "CPU at T+9",
+		}},
+		{"never restored", &gapWindow{Start: 6, End: 60}, []string{
+			"Paging on-call", "CPU at T+1", "CPU at T+3", "Dashboards are blank", "CPU at T+5", "📉 Telemetry gap",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := telemetryGap
+			telemetryGap = tt.gap
+			t.Cleanup(func() { telemetryGap = previous })
+			srv := startServer(t, tr)
+
+			var got []string
+			for _, line := range sseData(readSSE(t, srv.URL+"/stream?channels=team,metrics&oncomplete=close")) {
+				for _, want := range []string{"Paging on-call", "CPU at", "Dashboards are blank", "📉 Telemetry gap", "📈 Telemetry restored"} {
+					if strings.Contains(line, want) {
+						got = append(got, line)
+					}
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("stream lines %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if !strings.Contains(got[i], tt.want[i]) {
+					t.Errorf("line %d = %q, want it to mention %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// One scripted event in the timeline preview
+type timelineEntry struct {
+	Index         int    `json:"index"`
+	Offset        string `json:"offset"` // HH:MM:SS into the incident
+	OffsetSeconds int    `json:"offset_seconds"`
+	Channel       string `json:"channel"`
+	Speaker       string `json:"speaker,omitempty"`
+	Message       string `json:"message"`
+	Level         string `json:"level"`
+}
+
+// Timeline endpoint response
+type timelineResponse struct {
+	Title  string          `json:"title"`
+	Events []timelineEntry `json:"events"`
+}
+
+// List every transcript event in order, optionally on a single channel.
+// Indexes are positions in the whole transcript, as /search reports them.
+func buildTimeline(t *IncidentTranscript, channel string) timelineResponse {
+	timeline := timelineResponse{Title: t.Incident.Title, Events: []timelineEntry{}}
+	for i, event := range t.Events {
+		if channel != "" && event.Channel != channel {
+			continue
+		}
+		event = redactor.event(event)
+		timeline.Events = append(timeline.Events, timelineEntry{
+			Index:         i,
+			Offset:        strings.TrimPrefix(formatOffset(event.TimeOffset), "T+"),
+			OffsetSeconds: event.TimeOffset,
+			Channel:       event.Channel,
+			Speaker:       event.Speaker,
+			Message:       event.Message,
+			Level:         event.level(),
+		})
+	}
+	return timeline
+}
+
+// Write the timeline as a run sheet: one line per event with channels in a
+// column, and the lines of multi-line messages indented under the first
+func writeTimelineText(w io.Writer, timeline timelineResponse) {
+	width := 0
+	for _, entry := range timeline.Events {
+		width = max(width, len(entry.Channel))
+	}
+
+	fmt.Fprintf(w, "%s\n\n", timeline.Title)
+	for _, entry := range timeline.Events {
+		lead := fmt.Sprintf("#%-4d %s  %-*s  ", entry.Index, entry.Offset, width, entry.Channel)
+		message := entry.Message
+		if entry.Speaker != "" {
+			message = fmt.Sprintf("[%s] %s", entry.Speaker, message)
+		}
+		lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+		fmt.Fprintf(w, "%s%s\n", lead, strings.Join(lines, "\n"+strings.Repeat(" ", len(lead))))
+	}
+}
+
+// Handler previewing the whole scripted timeline, as JSON or a text outline
+func timelineHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "text" {
+		http.Error(w, "Invalid format, expected json or text", http.StatusBadRequest)
+		return
+	}
+
+	timeline := buildTimeline(primaryIncident().Transcript, query.Get("channel"))
+	if format == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writeTimelineText(w, timeline)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timeline)
+}
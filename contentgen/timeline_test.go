@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestTimeline(t *testing.T) {
+	tr, err := loadFixture(t, "checkout_outage.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := startServer(t, tr)
+
+	tests := []struct {
+		name    string
+		query   string
+		status  int
+		indexes []int    // JSON: indexes listed, in order
+		offsets []string // JSON: their HH:MM:SS offsets
+		text    string   // text: the whole run sheet
+	}{
+		{"every event", "", http.StatusOK, []int{0, 1, 2, 3, 4, 5},
+			[]string{"00:00:00", "00:00:05", "00:00:30", "00:00:45", "00:01:30", "00:02:00"}, ""},
+		// Indexes stay positions in the whole transcript, as /search reports them
+		{"one channel", "?channel=metrics", http.StatusOK, []int{1, 4}, []string{"00:00:05", "00:01:30"}, ""},
+		{"unknown channel", "?channel=billing", http.StatusOK, []int{}, []string{}, ""},
+		{"run sheet", "?format=text", http.StatusOK, nil, nil, "Checkout outage\n\n" +
+			"#0    00:00:00  team     [Priya] Paging on-call, checkout error rate is climbing\n" +
+			"#1    00:00:05  metrics  payments-api error_rate=12% p99_latency=4.1s\n" +
+			"#2    00:00:30  team     Deploy \"v4.2.0\" went out at 09:58, rolling it back\n" +
+			"#3    00:00:45  team     Rollback pipeline\n" +
+			"#4    00:01:30  metrics  payments-api error_rate=0.2% p99_latency=310ms\n" +
+			"#5    00:02:00  team     Resolved.\n" +
+			"                         Postmortem to follow.\n"},
+		{"run sheet for one channel", "?format=text&channel=metrics", http.StatusOK, nil, nil, "Checkout outage\n\n" +
+			"#1    00:00:05  metrics  payments-api error_rate=12% p99_latency=4.1s\n" +
+			"#4    00:01:30  metrics  payments-api error_rate=0.2% p99_latency=310ms\n"},
+		{"unknown format", "?format=csv", http.StatusBadRequest, nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := control(t, http.MethodGet, srv.URL+"/timeline"+tt.query, "")
+			if status != tt.status {
+				t.Fatalf("GET /timeline%s: status %d, want %d: %s", tt.query, status, tt.status, body)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			if tt.indexes == nil {
+				if body != tt.text {
+					t.Errorf("run sheet\n%s\nwant\n%s", body, tt.text)
+				}
+				return
+			}
+
+			var got timelineResponse
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatalf("decode %q: %v", body, err)
+			}
+			if got.Title != "Checkout outage" {
+				t.Errorf("title %q, want %q", got.Title, "Checkout outage")
+			}
+			indexes, offsets := []int{}, []string{}
+			for _, entry := range got.Events {
+				indexes = append(indexes, entry.Index)
+				offsets = append(offsets, entry.Offset)
+			}
+			if !reflect.DeepEqual(indexes, tt.indexes) || !reflect.DeepEqual(offsets, tt.offsets) {
+				t.Errorf("events %v at %v, want %v at %v", indexes, offsets, tt.indexes, tt.offsets)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// How long fetching a transcript by URL may take, body included, unless
+// -load-timeout says otherwise
+const transcriptFetchTimeout = 30 * time.Second
+
+// Client for fetching transcripts from object storage or any other HTTP host
+var transcriptHTTPClient = &http.Client{Timeout: transcriptFetchTimeout}
+
+// Check whether a transcript source is an http(s) URL rather than a path
+func isTranscriptURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// Read a transcript from a file path or, when the source has an http(s)
+// scheme, fetch it
+func readTranscriptSource(source string) ([]byte, error) {
+	if isTranscriptURL(source) {
+		return fetchTranscript(source)
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript file: %w", err)
+	}
+	return data, nil
+}
+
+// Fetch a transcript over HTTP, treating anything but 200 as a failure
+func fetchTranscript(source string) ([]byte, error) {
+	resp, err := transcriptHTTPClient.Get(source)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = redactURL(source)
+		}
+		return nil, fmt.Errorf("failed to fetch transcript: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch transcript from %s: unexpected status %s", redactURL(source), resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript from %s: %w", redactURL(source), err)
+	}
+	slog.Info("🌐 Fetched transcript", "url", redactURL(source), "bytes", len(data))
+	return data, nil
+}
+
+// Name used to pick a transcript's format. For URLs that is the path alone,
+// so a signed object URL's query string doesn't hide its extension.
+func transcriptFormatSource(source string) string {
+	if !isTranscriptURL(source) {
+		return source
+	}
+	u, err := url.Parse(source)
+	if err != nil {
+		return source
+	}
+	return u.Path
+}
+
+// Drop a URL's query string, which for signed object URLs carries credentials
+func redactURL(source string) string {
+	u, err := url.Parse(source)
+	if err != nil {
+		return source
+	}
+	u.RawQuery = ""
+	u.User = nil
+	return u.String()
+}
+
+// Report whether a transcript source is YAML by its extension; anything
+// that isn't .yaml or .yml is treated as JSON
+func isYAMLTranscript(source string) bool {
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// Decode a transcript exactly as authored, choosing the format from the
+// source file extension
+func decodeTranscript(data []byte, source string) (*IncidentTranscript, error) {
+	var t IncidentTranscript
+	if isYAMLTranscript(source) {
+		if err := yaml.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML transcript: %w", err)
+		}
+		return &t, nil
+	}
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse transcript: %w", err)
+	}
+	return &t, nil
+}
+
+// Parse and validate a transcript, then resolve it into timeline order
+func parseTranscript(data []byte, source string) (*IncidentTranscript, error) {
+	t, err := decodeTranscript(data, source)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateTranscript(t); err != nil {
+		return nil, err
+	}
+	resolveEventDelays(t)
+	resolveEventTimestamps(t)
+	normalizeTranscript(t)
+	if inferLevels {
+		inferEventLevels(t)
+	}
+	if inferSpeakers {
+		inferEventSpeakers(t)
+	}
+	return t, nil
+}
+
+// Give events authored with delay_after their absolute offset. delay_after
+// takes precedence over time_offset and counts from the previous event on
+// the same channel in transcript order, itself resolved first; the first
+// event of a channel counts from T+0. Events without it keep time_offset.
+func resolveEventDelays(t *IncidentTranscript) {
+	previous := make(map[string]int)
+	for i, event := range t.Events {
+		if event.DelayAfter != nil {
+			t.Events[i].TimeOffset = previous[event.Channel] + *event.DelayAfter
+		}
+		previous[event.Channel] = t.Events[i].TimeOffset
+	}
+}
+
+// Give events of a transcript authored with wall-clock timestamps their
+// offset from the earliest one, in whole seconds. Timestamps take precedence
+// over delay_after and time_offset, and are set on every event or none, as
+// validation enforces. A transcript without duration_seconds runs until its
+// last event.
+func resolveEventTimestamps(t *IncidentTranscript) {
+	if len(t.Events) == 0 || t.Events[0].Timestamp == "" {
+		return
+	}
+
+	times := make([]time.Time, len(t.Events))
+	for i, event := range t.Events {
+		times[i], _ = time.Parse(time.RFC3339, event.Timestamp)
+	}
+	earliest := slices.MinFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+
+	last := 0
+	for i := range t.Events {
+		t.Events[i].TimeOffset = int(times[i].Sub(earliest).Seconds())
+		last = max(last, t.Events[i].TimeOffset)
+	}
+	if t.Incident.DurationSeconds == 0 {
+		t.Incident.DurationSeconds = last
+	}
+}
+
+// Stretch or compress the canonical timeline by a factor, rounding offsets
+// to whole seconds, e.g. 0.5 to store a 2x-authored incident at real length
+func scaleTranscript(t *IncidentTranscript, factor float64) {
+	for i := range t.Events {
+		t.Events[i].TimeOffset = int(math.Round(float64(t.Events[i].TimeOffset) * factor))
+	}
+	t.Incident.DurationSeconds = int(math.Round(float64(t.Incident.DurationSeconds) * factor))
+}
+
+// Message prefixes that imply a level, after any leading emoji or brackets
+var levelPrefix = regexp.MustCompile(`^[^\pL\pN]*(ERROR|CRITICAL|FATAL|WARN|WARNING)\b`)
+
+// Fill in the level of events that don't set one from their message prefix,
+// e.g. "ERROR: upstream timeout" or "[WARN] disk 85% full"
+func inferEventLevels(t *IncidentTranscript) {
+	for i, event := range t.Events {
+		if event.Level != "" {
+			continue
+		}
+		match := levelPrefix.FindStringSubmatch(event.Message)
+		if match == nil {
+			continue
+		}
+		switch match[1] {
+		case "WARN", "WARNING":
+			t.Events[i].Level = levelWarn
+		default:
+			t.Events[i].Level = levelError
+		}
+	}
+}
+
+// Leading "[Name] " attribution, as written by -mode record and most
+// hand-authored transcripts
+var speakerPrefix = regexp.MustCompile(`^\[([^\[\]\n]+)\]\s+`)
+
+// Fill in the speaker of events that don't name one from a leading
+// "[Name] " in the message, which moves into the speaker field. Metrics
+// aren't spoken, and bracketed levels such as "[WARN]" aren't names.
+func inferEventSpeakers(t *IncidentTranscript) {
+	for i, event := range t.Events {
+		if event.Speaker != "" || event.Channel == "metrics" {
+			continue
+		}
+		match := speakerPrefix.FindStringSubmatch(event.Message)
+		if match == nil || levelPrefix.MatchString(match[1]) || strings.TrimSpace(match[1]) == "" {
+			continue
+		}
+		t.Events[i].Speaker = strings.TrimSpace(match[1])
+		t.Events[i].Message = event.Message[len(match[0]):]
+	}
+}
+
+// Put events in timeline order. The replay assumes offsets never decrease
+// within a channel, and validation rejects transcripts where they do rather
+// than sorting them silently, so this only interleaves channels written one
+// after another; events sharing an offset keep their file order.
+func normalizeTranscript(t *IncidentTranscript) {
+	if !sort.SliceIsSorted(t.Events, func(i, j int) bool {
+		return t.Events[i].TimeOffset < t.Events[j].TimeOffset
+	}) {
+		sort.SliceStable(t.Events, func(i, j int) bool {
+			return t.Events[i].TimeOffset < t.Events[j].TimeOffset
+		})
+		slog.Info("↕️  Interleaved transcript channels by time offset")
+	}
+
+	warnDuplicateOffsets(t.Events)
+}
+
+// Same-channel events at one offset fire together, which is usually a typo
+func warnDuplicateOffsets(events []Event) {
+	seen := make(map[string]bool)
+	duplicates := 0
+	for _, event := range events {
+		key := fmt.Sprintf("%s|%d", event.Channel, event.TimeOffset)
+		if seen[key] {
+			duplicates++
+		}
+		seen[key] = true
+	}
+	if duplicates > 0 {
+		slog.Warn("⚠️  Transcript has events sharing a time offset on the same channel", "count", duplicates)
+	}
+}
+
+// Report events whose offset goes back from the previous event on the same
+// channel. The replay plays a channel's events in file order, so a decrease
+// would fire in a burst. Offsets are compared as delay_after and timestamps
+// resolve them. The last error says whether -mode fmt can sort the file, so
+// the advice matches what fmt then does.
+func channelOrderErrors(t *IncidentTranscript) []error {
+	resolved := &IncidentTranscript{Events: slices.Clone(t.Events)}
+	resolveEventDelays(resolved)
+	resolveEventTimestamps(resolved)
+
+	var errs []error
+	previous := make(map[string]int) // channel -> index of its latest event
+	for i, event := range resolved.Events {
+		if last, ok := previous[event.Channel]; ok && event.TimeOffset < resolved.Events[last].TimeOffset {
+			errs = append(errs, fmt.Errorf("event %d: time_offset %d on channel %q is earlier than event %d at %d, offsets must not decrease within a channel", i, event.TimeOffset, event.Channel, last, resolved.Events[last].TimeOffset))
+		}
+		previous[event.Channel] = i
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	if _, err := sortTranscriptEvents(&IncidentTranscript{Events: slices.Clone(t.Events)}); err != nil {
+		return append(errs, fmt.Errorf("-mode fmt can't sort them: %w", err))
+	}
+	return append(errs, errors.New("-mode fmt sorts them"))
+}
+
+// Check the transcript is structurally sound, reporting every problem found
+func validateTranscript(t *IncidentTranscript) error {
+	var errs []error
+	if len(t.Events) == 0 {
+		errs = append(errs, errors.New("transcript has no events"))
+	}
+	timestamped := 0
+	for i, event := range t.Events {
+		if event.Timestamp != "" {
+			timestamped++
+			if _, err := time.Parse(time.RFC3339, event.Timestamp); err != nil {
+				errs = append(errs, fmt.Errorf("event %d: timestamp %q is not RFC3339, e.g. 2024-03-01T14:05:00Z", i, event.Timestamp))
+			}
+		}
+		if event.TimeOffset < 0 {
+			errs = append(errs, fmt.Errorf("event %d: time_offset %d is negative", i, event.TimeOffset))
+		}
+		if event.DelayAfter != nil && *event.DelayAfter < 0 {
+			errs = append(errs, fmt.Errorf("event %d: delay_after %d is negative", i, *event.DelayAfter))
+		}
+		if strings.TrimSpace(event.Channel) == "" {
+			errs = append(errs, fmt.Errorf("event %d: channel is empty", i))
+		}
+		if strings.TrimSpace(event.Message) == "" {
+			errs = append(errs, fmt.Errorf("event %d: message is empty", i))
+		}
+		if strings.ContainsAny(event.Speaker, "\r\n") {
+			errs = append(errs, fmt.Errorf("event %d: speaker must be a single line", i))
+		}
+		switch event.kind() {
+		case eventText:
+		case eventLink, eventImage:
+			if event.Meta["url"] == "" {
+				errs = append(errs, fmt.Errorf("event %d: %s event needs meta.url", i, event.Type))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("event %d: unknown type %q, expected text, link or image", i, event.Type))
+		}
+		switch event.Level {
+		case "", levelInfo, levelWarn, levelError:
+		default:
+			errs = append(errs, fmt.Errorf("event %d: unknown level %q, expected info, warn or error", i, event.Level))
+		}
+	}
+	if timestamped > 0 && timestamped < len(t.Events) {
+		errs = append(errs, fmt.Errorf("timestamp is set on %d of %d events, set it on every event or none", timestamped, len(t.Events)))
+	}
+	if len(errs) == 0 {
+		// Offsets can only be resolved once every event is sound
+		errs = channelOrderErrors(t)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid transcript: %w", errors.Join(errs...))
+	}
+	return nil
+}
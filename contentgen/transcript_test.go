@@ -0,0 +1,484 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Parse a transcript fixture from testdata the way -transcript loads it
+func loadFixture(t *testing.T, name string) (*IncidentTranscript, error) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return parseTranscript(data, path)
+}
+
+func TestYAMLTranscriptMatchesJSON(t *testing.T) {
+	fromYAML, err := loadFixture(t, "checkout_outage.yaml")
+	if err != nil {
+		t.Fatalf("YAML transcript: %v", err)
+	}
+	fromJSON, err := loadFixture(t, "checkout_outage.json")
+	if err != nil {
+		t.Fatalf("JSON transcript: %v", err)
+	}
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Errorf("YAML transcript\n%+v\nwant the JSON equivalent\n%+v", fromYAML, fromJSON)
+	}
+	if len(fromYAML.Events) != 6 {
+		t.Errorf("loaded %d events, want 6", len(fromYAML.Events))
+	}
+}
+
+func TestValidateTranscriptOrder(t *testing.T) {
+	delay := func(seconds int) *int { return &seconds }
+
+	tests := []struct {
+		name   string
+		events []Event
+		errs   []string // substrings of the expected error, none when valid
+	}{
+		{"in order", []Event{
+			{TimeOffset: 0, Channel: "team", Message: "a"},
+			{TimeOffset: 5, Channel: "team", Message: "b"},
+		}, nil},
+		{"same offset", []Event{
+			{TimeOffset: 5, Channel: "team", Message: "a"},
+			{TimeOffset: 5, Channel: "team", Message: "b"},
+		}, nil},
+		{"channels written one after another", []Event{
+			{TimeOffset: 0, Channel: "team", Message: "a"},
+			{TimeOffset: 30, Channel: "team", Message: "b"},
+			{TimeOffset: 10, Channel: "metrics", Message: "c"},
+			{TimeOffset: 20, Channel: "metrics", Message: "d"},
+		}, nil},
+		{"decreasing within a channel", []Event{
+			{TimeOffset: 0, Channel: "team", Message: "a"},
+			{TimeOffset: 40, Channel: "team", Message: "b"},
+			{TimeOffset: 30, Channel: "team", Message: "c"},
+		}, []string{`event 2: time_offset 30 on channel "team" is earlier than event 1 at 40`}},
+		{"each decrease reported", []Event{
+			{TimeOffset: 10, Channel: "team", Message: "a"},
+			{TimeOffset: 5, Channel: "team", Message: "b"},
+			{TimeOffset: 20, Channel: "metrics", Message: "c"},
+			{TimeOffset: 15, Channel: "metrics", Message: "d"},
+		}, []string{"event 1:", "event 3:"}},
+		{"delay_after resolved first", []Event{
+			{TimeOffset: 0, Channel: "team", Message: "a"},
+			{TimeOffset: 0, DelayAfter: delay(30), Channel: "team", Message: "b"},
+			{TimeOffset: 45, Channel: "team", Message: "c"},
+		}, nil},
+		{"timestamps decreasing", []Event{
+			{Timestamp: "2024-03-14T09:00:00Z", Channel: "team", Message: "a"},
+			{Timestamp: "2024-03-14T09:05:00Z", Channel: "team", Message: "b"},
+			{Timestamp: "2024-03-14T09:02:00Z", Channel: "team", Message: "c"},
+		}, []string{"event 2: time_offset 120", "earlier than event 1 at 300"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTranscript(&IncidentTranscript{Events: tt.events})
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Fatalf("valid transcript rejected: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("transcript accepted, want errors %q", tt.errs)
+			}
+			for _, want := range tt.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestOffsetFixtures(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		err     string   // substring of the load error, empty when it loads
+		order   []string // messages in timeline order once loaded
+	}{
+		{"decreasing within a channel", "decreasing_offsets.yaml", "offsets must not decrease within a channel", nil},
+		{"negative offset", "negative_offset.json", "event 0: time_offset -5 is negative", nil},
+		{"channels written one after another", "interleaved_channels.yaml", "", []string{
+			"Paging on-call", "error_rate=12%", "Rolling back", "error_rate=0%",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := loadFixture(t, tt.fixture)
+			if tt.err != "" {
+				if err == nil {
+					t.Fatalf("%s loaded, want an error mentioning %q", tt.fixture, tt.err)
+				}
+				if !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error %q, want it to mention %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", tt.fixture, err)
+			}
+			var got []string
+			for _, event := range tr.Events {
+				got = append(got, event.Message)
+			}
+			if !reflect.DeepEqual(got, tt.order) {
+				t.Errorf("events in order %q, want %q", got, tt.order)
+			}
+		})
+	}
+}
+
+func TestEventTimestamps(t *testing.T) {
+	tests := []struct {
+		name     string
+		fixture  string // testdata file, or inline YAML in data
+		data     string
+		err      string   // substring of the load error, empty when it loads
+		order    []string // messages in timeline order once loaded
+		offsets  []int
+		duration int
+	}{
+		{"mixed zones and channels", "timestamped.yaml", "", "", []string{
+			"Paging on-call", "error_rate=12%", "error_rate=0%", "Resolved",
+		}, []int{0, 3, 5, 7}, 7},
+		{"explicit duration kept", "", `
+incident: {title: T, duration_seconds: 60}
+events:
+  - {timestamp: "2024-03-01T14:05:00Z", channel: team, message: Paging on-call}
+  - {timestamp: "2024-03-01T14:06:30Z", channel: team, message: Resolved}
+`, "", []string{"Paging on-call", "Resolved"}, []int{0, 90}, 60},
+		{"timestamp beats delay_after", "", `
+incident: {title: T}
+events:
+  - {timestamp: "2024-03-01T14:05:00Z", channel: team, message: Paging on-call}
+  - {timestamp: "2024-03-01T14:05:02Z", delay_after: 30, channel: team, message: Resolved}
+`, "", []string{"Paging on-call", "Resolved"}, []int{0, 2}, 2},
+		{"not RFC3339", "", `
+incident: {title: T}
+events:
+  - {timestamp: "2024-03-01 14:05:00", channel: team, message: Paging on-call}
+`, `event 0: timestamp "2024-03-01 14:05:00" is not RFC3339`, nil, nil, 0},
+		{"set on only some events", "", `
+incident: {title: T}
+events:
+  - {timestamp: "2024-03-01T14:05:00Z", channel: team, message: Paging on-call}
+  - {time_offset: 10, channel: team, message: Resolved}
+`, "timestamp is set on 1 of 2 events", nil, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tr *IncidentTranscript
+			var err error
+			if tt.fixture != "" {
+				tr, err = loadFixture(t, tt.fixture)
+			} else {
+				tr, err = parseTranscript([]byte(tt.data), "timestamps.yaml")
+			}
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to mention %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+
+			var order []string
+			var offsets []int
+			for _, event := range tr.Events {
+				order = append(order, event.Message)
+				offsets = append(offsets, event.TimeOffset)
+			}
+			if !reflect.DeepEqual(order, tt.order) || !reflect.DeepEqual(offsets, tt.offsets) {
+				t.Errorf("events %q at %v, want %q at %v", order, offsets, tt.order, tt.offsets)
+			}
+			if tr.Incident.DurationSeconds != tt.duration {
+				t.Errorf("duration %d, want %d", tr.Incident.DurationSeconds, tt.duration)
+			}
+		})
+	}
+}
+
+func TestEventLevels(t *testing.T) {
+	tests := []struct {
+		name    string
+		infer   bool // -infer-levels
+		level   string
+		message string
+		want    string
+		err     string
+	}{
+		{"default", false, "", "ERROR: upstream timeout", levelInfo, ""},
+		{"explicit", false, levelWarn, "disk 85% full", levelWarn, ""},
+		{"inferred error", true, "", "ERROR: upstream timeout", levelError, ""},
+		{"inferred critical", true, "", "CRITICAL db primary down", levelError, ""},
+		{"inferred bracketed warning", true, "", "[WARN] disk 85% full", levelWarn, ""},
+		{"inferred after an emoji", true, "", "🔥 FATAL: out of memory", levelError, ""},
+		{"prefix only at the start", true, "", "no ERROR here", levelInfo, ""},
+		{"part of a longer word", true, "", "ERRORS dropped to zero", levelInfo, ""},
+		{"explicit beats inferred", true, levelInfo, "ERROR: retried fine", levelInfo, ""},
+		{"unknown level", false, "debug", "noise", "", `unknown level "debug"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := inferLevels
+			inferLevels = tt.infer
+			t.Cleanup(func() { inferLevels = previous })
+
+			data, err := json.Marshal(IncidentTranscript{Events: []Event{{Channel: "metrics", Message: tt.message, Level: tt.level}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr, err := parseTranscript(data, "levels.json")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to mention %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			// The level reaches JSON stream frames and search results alike
+			event := tr.Events[0]
+			if got := newEventFrame(event, time.Time{}).Level; got != tt.want {
+				t.Errorf("frame level %q, want %q", got, tt.want)
+			}
+			if got := searchEvents(tr, tt.message, "", 1).Results[0].Level; got != tt.want {
+				t.Errorf("search level %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventSpeakers(t *testing.T) {
+	tests := []struct {
+		name    string
+		infer   bool // -infer-speakers
+		channel string
+		speaker string
+		message string
+		want    string // speaker after parsing
+		text    string // message after parsing
+		err     string
+	}{
+		{"default", false, "team", "", "[Alice] Rolling back", "", "[Alice] Rolling back", ""},
+		{"explicit", false, "team", "Bob", "Rolling back", "Bob", "Rolling back", ""},
+		{"inferred", true, "team", "", "[Alice] Rolling back", "Alice", "Rolling back", ""},
+		{"inferred name with spaces", true, "team", "", "[Priya S]  Paging on-call", "Priya S", "Paging on-call", ""},
+		{"metrics channel kept", true, "metrics", "", "[db-1] CPU 92%", "", "[db-1] CPU 92%", ""},
+		{"bracketed level kept", true, "team", "", "[WARN] disk 85% full", "", "[WARN] disk 85% full", ""},
+		{"prefix only at the start", true, "team", "", "paged [Alice] twice", "", "paged [Alice] twice", ""},
+		{"explicit beats inferred", true, "team", "Bob", "[Alice] Rolling back", "Bob", "[Alice] Rolling back", ""},
+		{"multi-line speaker", false, "team", "Alice\nBob", "Rolling back", "", "", "speaker must be a single line"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := inferSpeakers
+			inferSpeakers = tt.infer
+			t.Cleanup(func() { inferSpeakers = previous })
+
+			data, err := json.Marshal(IncidentTranscript{Events: []Event{{Channel: tt.channel, Speaker: tt.speaker, Message: tt.message}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr, err := parseTranscript(data, "speakers.json")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to mention %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			event := tr.Events[0]
+			if event.Speaker != tt.want || event.Message != tt.text {
+				t.Fatalf("speaker %q message %q, want %q and %q", event.Speaker, event.Message, tt.want, tt.text)
+			}
+
+			// The speaker reaches JSON frames, text streams and Slack alike
+			if got := newEventFrame(event, time.Time{}).Speaker; got != tt.want {
+				t.Errorf("frame speaker %q, want %q", got, tt.want)
+			}
+			wantText, wantSlack := tt.text, ""
+			if tt.want != "" {
+				wantText = "[" + tt.want + "] " + tt.text
+				wantSlack = " · 🗣 *" + tt.want + "*"
+			}
+			if got := event.displayText(); got != wantText {
+				t.Errorf("display text %q, want %q", got, wantText)
+			}
+			if got := slackSpeaker(event); got != wantSlack {
+				t.Errorf("Slack context %q, want %q", got, wantSlack)
+			}
+		})
+	}
+}
+
+func TestTimeScale(t *testing.T) {
+	tests := []struct {
+		name     string
+		factor   float64
+		offsets  []int // the fixture's 0, 1, 3, 10, 20 once scaled
+		duration int
+	}{
+		{"unchanged", 1, []int{0, 1, 3, 10, 20}, 20},
+		{"stretched", 2, []int{0, 2, 6, 20, 40}, 40},
+		{"halved, rounding half away from zero", 0.5, []int{0, 1, 2, 5, 10}, 10},
+		{"a third, rounded to whole seconds", 1.0 / 3, []int{0, 0, 1, 3, 7}, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(fixtureTranscript())
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "fixture.json")
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			previousFile, previousScale := transcriptFile, timeScale
+			transcriptFile, timeScale = path, tt.factor
+			t.Cleanup(func() { transcriptFile, timeScale = previousFile, previousScale })
+
+			// Scaling happens once, at load
+			tr, err := prepareTranscript()
+			if err != nil {
+				t.Fatalf("prepare transcript: %v", err)
+			}
+			var offsets []int
+			for _, event := range tr.Events {
+				offsets = append(offsets, event.TimeOffset)
+			}
+			if !reflect.DeepEqual(offsets, tt.offsets) {
+				t.Errorf("offsets %v, want %v", offsets, tt.offsets)
+			}
+			if tr.Incident.DurationSeconds != tt.duration {
+				t.Errorf("duration %d, want %d", tr.Incident.DurationSeconds, tt.duration)
+			}
+		})
+	}
+}
+
+func TestDelayAfter(t *testing.T) {
+	// delay_after counts from the previous event on the same channel, which
+	// may itself use either style; metrics interleave without affecting team
+	data := []byte(`{"incident":{"title":"Mixed timing","duration_seconds":60},"events":[
+		{"time_offset":0,"channel":"team","message":"Paging on-call"},
+		{"delay_after":5,"channel":"team","message":"Acked"},
+		{"time_offset":3,"channel":"metrics","message":"CPU 92%"},
+		{"time_offset":20,"channel":"team","message":"Rolling back"},
+		{"delay_after":10,"time_offset":1,"channel":"team","message":"Resolved"},
+		{"delay_after":4,"channel":"metrics","message":"CPU 40%"}
+	]}`)
+
+	tests := []struct {
+		name  string
+		speed float64
+		want  []timedEvent
+	}{
+		{"real time", 1, []timedEvent{
+			{"Paging on-call", 0},
+			{"CPU 92%", 3 * time.Second},
+			{"Acked", 5 * time.Second},
+			{"CPU 40%", 7 * time.Second},
+			{"Rolling back", 20 * time.Second},
+			{"Resolved", 30 * time.Second},
+		}},
+		{"double speed", 2, []timedEvent{
+			{"Paging on-call", 0},
+			{"CPU 92%", 1500 * time.Millisecond},
+			{"Acked", 2500 * time.Millisecond},
+			{"CPU 40%", 3500 * time.Millisecond},
+			{"Rolling back", 10 * time.Second},
+			{"Resolved", 15 * time.Second},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := parseTranscript(data, "mixed.json")
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			clock := useFakeClock(t)
+			rp := newReplay(tr.Events, newSpeedControl(tt.speed), false)
+			t.Cleanup(rp.wait)
+			sub, _ := rp.subscribeWithBacklog([]string{"team", "metrics"})
+
+			if got := driveTimed(t, clock, sub); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTranscriptURL(t *testing.T) {
+	// Object storage stand-in serving the testdata fixtures
+	store := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	t.Cleanup(store.Close)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	tests := []struct {
+		name   string
+		source string
+		err    string // substring of the load error, empty when it loads
+		hidden string // must not appear in the error
+		events int
+	}{
+		{"JSON by URL", store.URL + "/checkout_outage.json", "", "", 6},
+		// The format comes from the path, not the signed query string
+		{"signed YAML URL", store.URL + "/checkout_outage.yaml?X-Amz-Signature=s3cr3t&format=.json", "", "", 6},
+		{"missing object", store.URL + "/gone.json?X-Amz-Signature=s3cr3t", "unexpected status 404 Not Found", "s3cr3t", 0},
+		{"fetched but invalid", store.URL + "/negative_offset.json", "time_offset -5 is negative", "", 0},
+		{"host unreachable", down.URL + "/checkout_outage.json?X-Amz-Signature=s3cr3t", "failed to fetch transcript", "s3cr3t", 0},
+		{"plain path still read from disk", filepath.Join("testdata", "checkout_outage.yaml"), "", "", 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := transcriptFile
+			transcriptFile = tt.source
+			t.Cleanup(func() { transcriptFile = previous })
+
+			tr, err := prepareTranscript()
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("load %s: %v", tt.source, err)
+				}
+				if len(tr.Events) != tt.events {
+					t.Errorf("loaded %d events, want %d", len(tr.Events), tt.events)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("%s loaded, want an error mentioning %q", tt.source, tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error %q, want it to mention %q", err, tt.err)
+			}
+			if tt.hidden != "" && strings.Contains(err.Error(), tt.hidden) {
+				t.Errorf("error %q leaks the URL's query string", err)
+			}
+		})
+	}
+}
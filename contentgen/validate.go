@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Load the transcript, and any transcripts directory, exactly as serving
+// would, then print a report of each one's channels. Nothing is served or
+// published, so CI can gate transcript changes on the result.
+func runValidate(w io.Writer, dir string) error {
+	if err := loadTranscript(); err != nil {
+		return err
+	}
+	source := transcriptFile
+	switch {
+	case len(mergeSources) > 0:
+		source = "merged transcript"
+	case source == "":
+		source = "embedded transcript"
+	case isTranscriptURL(source):
+		source = redactURL(source)
+	}
+	writeTranscriptReport(w, source, transcript)
+
+	if dir != "" {
+		loaded, err := loadIncidentsDir(dir)
+		if err != nil {
+			return err
+		}
+		ids := make([]string, 0, len(loaded))
+		for id := range loaded {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			writeTranscriptReport(w, id, loaded[id].Transcript)
+		}
+	}
+	return nil
+}
+
+// Print a transcript's title, length and per-channel event counts, noting
+// events that fall past the declared duration
+func writeTranscriptReport(w io.Writer, source string, t *IncidentTranscript) {
+	fmt.Fprintf(w, "✅ %s: %q\n", source, t.Incident.Title)
+	fmt.Fprintf(w, "   %d events over %s\n", len(t.Events), formatOffset(t.Incident.DurationSeconds))
+
+	counts := make(map[string]int)
+	late := 0
+	for _, event := range t.Events {
+		counts[event.Channel]++
+		if event.TimeOffset > t.Incident.DurationSeconds {
+			late++
+		}
+	}
+	channels := make([]string, 0, len(counts))
+	for channel := range counts {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	for _, channel := range channels {
+		fmt.Fprintf(w, "   %-12s %d events\n", channel, counts[channel])
+	}
+	if late > 0 {
+		fmt.Fprintf(w, "   ⚠️  %d events are past duration_seconds %d\n", late, t.Incident.DurationSeconds)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunValidate(t *testing.T) {
+	// A transcripts directory holding one good scenario
+	dir := t.TempDir()
+	data, err := os.ReadFile("testdata/checkout_outage.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "checkout.yaml"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	report := `✅ testdata/checkout_outage.json: "Checkout outage"
+   6 events over T+00:02:00
+   metrics      2 events
+   team         4 events
+`
+	tests := []struct {
+		name   string
+		file   string
+		dir    string
+		report string // printed on success
+		err    string // substring of the error on failure
+	}{
+		{"good fixture", "testdata/checkout_outage.json", "", report, ""},
+		{"with a transcripts directory", "testdata/checkout_outage.json", dir, report + strings.Replace(report, "testdata/checkout_outage.json", "checkout", 1), ""},
+		{"decreasing offsets", "testdata/decreasing_offsets.yaml", "", "", "offsets must not decrease within a channel"},
+		{"negative offset", "testdata/negative_offset.json", "", "", "time_offset -5 is negative"},
+		{"missing file", "testdata/nope.json", "", "", "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slack := useFakeSlack(t)
+			previousFile, previousTranscript := transcriptFile, transcript
+			transcriptFile = tt.file
+			t.Cleanup(func() { transcriptFile, transcript = previousFile, previousTranscript })
+
+			var out strings.Builder
+			err := runValidate(&out, tt.dir)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want it to mention %q", err, tt.err)
+				}
+			} else if err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			if out.String() != tt.report {
+				t.Errorf("report\n%s\nwant\n%s", out.String(), tt.report)
+			}
+			if posts := slack.received(); len(posts) != 0 {
+				t.Errorf("validating posted %d messages to Slack", len(posts))
+			}
+		})
+	}
+}
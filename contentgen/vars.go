@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Parse incident template variables given as "Service=checkout,Region=us-east-1"
+func parseTemplateVars(value string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid variable %q, expected key=value", pair)
+		}
+		vars[name] = strings.TrimSpace(val)
+	}
+	return vars, nil
+}
+
+// Render one templated string. Text without template actions is returned as is.
+func renderTemplate(name, text string, vars map[string]string, strict bool) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	// Strict mode fails on undefined variables instead of rendering them empty
+	missingKey := "missingkey=zero"
+	if strict {
+		missingKey = "missingkey=error"
+	}
+	tmpl, err := template.New(name).Option(missingKey).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return out.String(), nil
+}
+
+// Default -title-format: the author's title followed by today's date
+const defaultTitleFormat = "{{.Title}} - {{.Date}}"
+
+// Rewrite an incident title through a format that may reference the
+// original {{.Title}} and the current {{.Date}}
+func stampTitle(title, format string, now time.Time) (string, error) {
+	vars := map[string]string{"Title": title, "Date": now.Format("Jan 2, 2006")}
+	stamped, err := renderTemplate("title format", format, vars, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to stamp title: %w", err)
+	}
+	return stamped, nil
+}
+
+// Substitute template variables into the incident title, description and
+// every event message, reporting every template that fails
+func renderTranscript(t *IncidentTranscript, vars map[string]string, strict bool) error {
+	var errs []error
+	render := func(name string, text *string) {
+		rendered, err := renderTemplate(name, *text, vars, strict)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		*text = rendered
+	}
+
+	render("title", &t.Incident.Title)
+	render("description", &t.Incident.Description)
+	for i := range t.Events {
+		render(fmt.Sprintf("event %d", i), &t.Events[i].Message)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to render transcript templates: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Events waiting for delivery before new ones are dropped
+const webhookQueueSize = 256
+
+// Body POSTed to a channel's webhook for every event
+type webhookPayload struct {
+	Channel    string `json:"channel"`
+	Message    string `json:"message"`
+	TimeOffset int    `json:"time_offset"`
+	Timestamp  string `json:"timestamp"`
+}
+
+// Failed webhook delivery, retryable when the failure was transient
+type WebhookError struct {
+	RetryAfter time.Duration
+	Err        error
+	transient  bool
+}
+
+func (e *WebhookError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *WebhookError) Unwrap() error {
+	return e.Err
+}
+
+// Report whether another attempt could succeed
+func (e *WebhookError) Retryable() bool {
+	return e.transient
+}
+
+// Delay the receiver asked for before the next attempt
+func (e *WebhookError) Backoff() time.Duration {
+	return e.RetryAfter
+}
+
+// Generic outbound webhook sink: POSTs each event on a mapped channel to its
+// URL. Deliveries happen in order on a background worker so a slow or failing
+// receiver never stalls the replay.
+type WebhookSink struct {
+	URLs       map[string]string // transcript channel -> webhook URL
+	HTTPClient *http.Client
+	queue      chan webhookPayload
+}
+
+// Create a webhook sink and start its delivery worker; nil when nothing is mapped
+func NewWebhookSink(urls map[string]string) *WebhookSink {
+	if len(urls) == 0 {
+		return nil
+	}
+	sink := &WebhookSink{
+		URLs:       urls,
+		HTTPClient: outboundHTTPClient,
+		queue:      make(chan webhookPayload, webhookQueueSize),
+	}
+	go sink.run()
+	return sink
+}
+
+// Load the channel-to-URL map from a JSON file, or else from the
+// WEBHOOK_MAP environment variable as JSON
+func loadWebhookMap(path string) (map[string]string, error) {
+	data := []byte(os.Getenv("WEBHOOK_MAP"))
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read webhook map: %w", err)
+		}
+	}
+	urls := make(map[string]string)
+	if len(bytes.TrimSpace(data)) == 0 {
+		return urls, nil
+	}
+	if err := json.Unmarshal(data, &urls); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook map JSON: %w", err)
+	}
+	for channel, url := range urls {
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return nil, fmt.Errorf("invalid webhook URL for channel %q: %q", channel, url)
+		}
+	}
+	return urls, nil
+}
+
+// Queue an event for delivery if its channel has a webhook
+func (s *WebhookSink) Publish(event Event) {
+	if s == nil {
+		return
+	}
+	if _, ok := s.URLs[event.Channel]; !ok {
+		return
+	}
+
+	payload := webhookPayload{
+		Channel:    event.Channel,
+		Message:    event.Message,
+		TimeOffset: event.TimeOffset,
+		Timestamp:  wallClock.Now().Format(time.RFC3339),
+	}
+	select {
+	case s.queue <- payload:
+	default:
+		webhookDeliveryCounter.WithLabelValues("dropped").Inc()
+		slog.Warn("⚠️  Webhook queue full, dropping event", "channel", event.Channel, "offset", event.TimeOffset)
+	}
+}
+
+// Deliver queued events one at a time, retrying transient failures
+func (s *WebhookSink) run() {
+	for payload := range s.queue {
+		url := s.URLs[payload.Channel]
+		body, err := json.Marshal(payload)
+		if err != nil {
+			slog.Warn("⚠️  Failed to marshal webhook payload", "channel", payload.Channel, "err", err)
+			continue
+		}
+
+		err = withRetry("webhook", func(ctx context.Context) error {
+			return postWebhook(ctx, s.HTTPClient, url, body)
+		})
+		if err != nil {
+			webhookDeliveryCounter.WithLabelValues("failure").Inc()
+			slog.Warn("⚠️  Failed to deliver webhook", "channel", payload.Channel, "offset", payload.TimeOffset, "err", err)
+			continue
+		}
+		webhookDeliveryCounter.WithLabelValues("success").Inc()
+		slog.Debug("Delivered webhook", "channel", payload.Channel, "offset", payload.TimeOffset)
+	}
+}
+
+// Make a single JSON webhook POST
+func postWebhook(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return &WebhookError{Err: fmt.Errorf("failed to send request: %w", err), transient: true}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		retryAfter := time.Second
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return &WebhookError{RetryAfter: retryAfter, Err: fmt.Errorf("webhook rate limited (retry after %s)", retryAfter), transient: true}
+	case resp.StatusCode >= 500:
+		return &WebhookError{Err: fmt.Errorf("webhook server error: HTTP %d", resp.StatusCode), transient: true}
+	case resp.StatusCode >= 300:
+		return &WebhookError{Err: fmt.Errorf("webhook rejected event: HTTP %d", resp.StatusCode)}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Receiver capturing every webhook payload; responses come from status in
+// call order, then 200
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	payloads []webhookPayload
+	status   []int
+	received chan struct{}
+}
+
+func newWebhookReceiver(t *testing.T, status ...int) *webhookReceiver {
+	t.Helper()
+	rcv := &webhookReceiver{status: status, received: make(chan struct{}, 64)}
+	rcv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("webhook Content-Type %q, want application/json", got)
+		}
+		rcv.mu.Lock()
+		if len(rcv.status) > 0 {
+			status := rcv.status[0]
+			rcv.status = rcv.status[1:]
+			rcv.mu.Unlock()
+			w.WriteHeader(status)
+			return
+		}
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("webhook body: %v", err)
+		}
+		rcv.payloads = append(rcv.payloads, payload)
+		rcv.mu.Unlock()
+		rcv.received <- struct{}{}
+	}))
+	t.Cleanup(rcv.Close)
+	return rcv
+}
+
+// Wait for n payloads, failing the test after a few seconds
+func (rcv *webhookReceiver) wait(t *testing.T, n int) []webhookPayload {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-rcv.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("webhook received %d payloads, want %d", i, n)
+		}
+	}
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return append([]webhookPayload(nil), rcv.payloads...)
+}
+
+func TestWebhookSinkPostsMappedChannels(t *testing.T) {
+	clock := useFakeClock(t)
+	rcv := newWebhookReceiver(t)
+	sink := NewWebhookSink(map[string]string{"team": rcv.URL + "/hooks/team"})
+	sink.HTTPClient = rcv.Client()
+
+	for _, event := range fixtureTranscript().Events {
+		sink.Publish(event)
+	}
+
+	stamp := clock.Now().Format(time.RFC3339)
+	want := []webhookPayload{
+		{Channel: "team", Message: "Paging on-call", TimeOffset: 0, Timestamp: stamp},
+		{Channel: "team", Message: "Rolling back", TimeOffset: 10, Timestamp: stamp},
+		{Channel: "team", Message: "Resolved", TimeOffset: 20, Timestamp: stamp},
+	}
+	if got := rcv.wait(t, len(want)); !reflect.DeepEqual(got, want) {
+		t.Errorf("payloads %+v, want %+v", got, want)
+	}
+}
+
+func TestWebhookSinkRetriesTransientFailures(t *testing.T) {
+	clock := useFakeClock(t)
+	rcv := newWebhookReceiver(t, http.StatusServiceUnavailable)
+	sink := NewWebhookSink(map[string]string{"team": rcv.URL})
+	sink.HTTPClient = rcv.Client()
+
+	sink.Publish(Event{TimeOffset: 0, Channel: "team", Message: "Paging on-call"})
+	clock.step(t) // the backoff after the 503
+	if got := rcv.wait(t, 1); len(got) != 1 || got[0].Message != "Paging on-call" {
+		t.Errorf("payloads %+v, want the event delivered once after the retry", got)
+	}
+}
+
+func TestNilWebhookSink(t *testing.T) {
+	if sink := NewWebhookSink(nil); sink != nil {
+		t.Fatalf("NewWebhookSink(nil) = %v, want nil", sink)
+	}
+	var sink *WebhookSink
+	sink.Publish(Event{Channel: "team", Message: "ignored"})
+}
+
+func TestLoadWebhookMap(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name string
+		env  string
+		path string
+		want map[string]string
+		err  bool
+	}{
+		{"nothing configured", "", "", map[string]string{}, false},
+		{"environment", `{"team":"https://hooks.example.com/t"}`, "", map[string]string{"team": "https://hooks.example.com/t"}, false},
+		{"file over environment", `{"team":"https://env.example.com"}`, write("map.json", `{"metrics":"http://localhost:9000/m"}`), map[string]string{"metrics": "http://localhost:9000/m"}, false},
+		{"not JSON", "team=https://hooks.example.com", "", nil, true},
+		{"not a URL", `{"team":"hooks.example.com/t"}`, "", nil, true},
+		{"missing file", "", filepath.Join(dir, "absent.json"), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WEBHOOK_MAP", tt.env)
+			got, err := loadWebhookMap(tt.path)
+			if (err != nil) != tt.err {
+				t.Fatalf("error %v, want error %v", err, tt.err)
+			}
+			if !tt.err && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("map %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
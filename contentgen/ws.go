@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+// Time allowed for writing a single WebSocket frame
+const wsWriteTimeout = 5 * time.Second
+
+// Stream one transcript channel from the shared replay over a WebSocket
+func wsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if !acquireStreamSlot(w, r) {
+		return
+	}
+	defer releaseStreamSlot()
+
+	channel := r.PathValue("channel")
+	inc := primaryIncident()
+
+	// Count the viewer for as long as the handler runs, whatever path it returns by
+	clients := inc.Replay.connect(channel)
+	defer func() {
+		clients := inc.Replay.disconnect(channel)
+		slog.Info("👥 Viewer left WebSocket stream", "channel", channel, "clients", clients)
+	}()
+
+	opts, err := parseStreamOptions(r, inc.Transcript.Incident)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Frames are always JSON here, so progress comes without ?format=json
+	opts.progress = progressInterval > 0
+
+	// Accept the same origins as the CORS policy; others must be same-origin
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: corsAllowed(r.Header.Get("Origin"))})
+	if err != nil {
+		slog.Warn("⚠️  WebSocket upgrade failed", "channel", channel, "remote_addr", r.RemoteAddr, "err", err)
+		return
+	}
+	defer conn.CloseNow()
+
+	// Clients only listen, so the read loop just notices when they go away;
+	// abandoned connections also end on their own after the time limit
+	ctx, cancel := streamContext(conn.CloseRead(r.Context()))
+	defer cancel()
+	slog.Info("Client connected to WebSocket stream", "channel", channel, "remote_addr", r.RemoteAddr, "clients", clients)
+
+	// Set the scene before anything else when asked to
+	if opts.intro {
+		writeCtx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+		err := wsjson.Write(writeCtx, conn, newIntroFrame(inc.Transcript.Incident))
+		cancel()
+		if err != nil {
+			slog.Info("Client disconnected from WebSocket stream", "channel", channel, "remote_addr", r.RemoteAddr)
+			return
+		}
+	}
+
+	// Early viewers wait for a scheduled start together
+	if wallClock.Now().Before(replayStartAt) {
+		writeCtx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+		err := wsjson.Write(writeCtx, conn, markerFrame{Event: "scheduled", Channel: channel, Message: replayStartAt.In(timestampLocation).Format(time.RFC3339)})
+		cancel()
+		if err != nil || !waitForStart(ctx) {
+			slog.Info("Client disconnected from WebSocket stream", "channel", channel, "remote_addr", r.RemoteAddr)
+			return
+		}
+	}
+
+	// Say so when the channel has nothing to replay, rather than completing silently
+	if len(channelEvents(inc.Transcript, channel)) == 0 {
+		slog.Info("ℹ️  No events for channel", "channel", channel)
+		writeCtx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+		err := wsjson.Write(writeCtx, conn, markerFrame{Event: "empty", Channel: channel})
+		cancel()
+		if err != nil {
+			slog.Info("Client disconnected from WebSocket stream", "channel", channel, "remote_addr", r.RemoteAddr)
+			return
+		}
+	}
+
+	err = feedChannel(ctx, inc, []string{channel}, opts, func(msg replayMessage) error {
+		var frame interface{}
+		switch msg.Kind {
+		case messageComplete:
+			frame = markerFrame{Event: lifecycleComplete, Channel: channel}
+		case messageChannelComplete:
+			frame = markerFrame{Event: lifecycleChannelComplete, Channel: msg.Event.Channel}
+		case messageRestarted:
+			frame = markerFrame{Event: lifecycleRestarted, Channel: channel}
+		case messagePaused:
+			frame = markerFrame{Event: lifecyclePaused, Channel: channel}
+		case messageResumed:
+			frame = markerFrame{Event: lifecycleResumed, Channel: channel}
+		case messageSeeked:
+			offset := msg.Event.TimeOffset
+			frame = markerFrame{Event: "seeked", Channel: channel, Offset: &offset}
+		case messageGapBegan:
+			start := telemetryGap.Start
+			frame = markerFrame{Event: "telemetry_gap_start", Channel: channel, Offset: &start}
+		case messageGapEnded:
+			end := telemetryGap.End
+			frame = markerFrame{Event: "telemetry_gap_end", Channel: channel, Offset: &end}
+		case messageProgress:
+			frame = msg.Progress
+		case messageQuietSkip:
+			frame = markerFrame{Event: "quiet_skipped", Channel: channel, Message: strconv.Itoa(msg.Skipped)}
+		case messageAnnotated:
+			offset := msg.Mark.Offset
+			frame = markerFrame{Event: "annotation", Channel: channel, Offset: &offset, Message: msg.Mark.Label, Note: msg.Mark.Note}
+		case messageChatFailed:
+			offset := msg.Event.TimeOffset
+			frame = markerFrame{Event: "chat_failed", Channel: msg.Event.Channel, Offset: &offset, Message: msg.Event.Message, Note: msg.Chat.outcome()}
+		case messageBackfill:
+			backfill := newEventFrame(msg.Event, wallClock.Now())
+			backfill.Catchup = true
+			frame = backfill
+		default:
+			frame = newLiveEventFrame(msg, wallClock.Now())
+		}
+
+		writeCtx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+		defer cancel()
+		if err := wsjson.Write(writeCtx, conn, frame); err != nil {
+			return err
+		}
+		if msg.Kind == messageComplete && opts.close {
+			if opts.grace && waitUntil(ctx, wallClock.Now().Add(completionGrace)) != nil {
+				return nil
+			}
+			return errStreamComplete
+		}
+		return nil
+	}, nil)
+
+	if errors.Is(err, errSlowClient) {
+		slog.Warn("⚠️  Dropped slow client from WebSocket stream", "channel", channel, "remote_addr", r.RemoteAddr)
+		conn.Close(websocket.StatusPolicyViolation, "client fell too far behind")
+		return
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		slog.Info("⏲ Stream time limit reached", "channel", channel, "remote_addr", r.RemoteAddr, "limit", maxStreamDuration)
+		conn.Close(websocket.StatusNormalClosure, "stream time limit reached")
+		return
+	}
+	if err == nil || errors.Is(err, errStreamComplete) {
+		conn.Close(websocket.StatusNormalClosure, "")
+	}
+	slog.Info("Client disconnected from WebSocket stream", "channel", channel, "remote_addr", r.RemoteAddr)
+}